@@ -0,0 +1,703 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: api/cashback/v1/cashback.proto
+
+package cashbackv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CashbackRequest struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	TuronUserId    int64                  `protobuf:"varint,1,opt,name=turon_user_id,json=turonUserId,proto3" json:"turon_user_id,omitempty"`
+	CashbackAmount float64                `protobuf:"fixed64,2,opt,name=cashback_amount,json=cashbackAmount,proto3" json:"cashback_amount,omitempty"`
+	// Ignored: the server records the caller's address.
+	HostIp string `protobuf:"bytes,3,opt,name=host_ip,json=hostIp,proto3" json:"host_ip,omitempty"`
+	Reason string `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	// A repeated request with the same key posts nothing and succeeds again.
+	IdempotencyKey string `protobuf:"bytes,5,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *CashbackRequest) Reset() {
+	*x = CashbackRequest{}
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CashbackRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CashbackRequest) ProtoMessage() {}
+
+func (x *CashbackRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CashbackRequest.ProtoReflect.Descriptor instead.
+func (*CashbackRequest) Descriptor() ([]byte, []int) {
+	return file_api_cashback_v1_cashback_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CashbackRequest) GetTuronUserId() int64 {
+	if x != nil {
+		return x.TuronUserId
+	}
+	return 0
+}
+
+func (x *CashbackRequest) GetCashbackAmount() float64 {
+	if x != nil {
+		return x.CashbackAmount
+	}
+	return 0
+}
+
+func (x *CashbackRequest) GetHostIp() string {
+	if x != nil {
+		return x.HostIp
+	}
+	return ""
+}
+
+func (x *CashbackRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *CashbackRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type CashbackResponse struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Message string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// Set when idempotency_key was used before and nothing new was posted.
+	Replayed      bool `protobuf:"varint,2,opt,name=replayed,proto3" json:"replayed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CashbackResponse) Reset() {
+	*x = CashbackResponse{}
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CashbackResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CashbackResponse) ProtoMessage() {}
+
+func (x *CashbackResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CashbackResponse.ProtoReflect.Descriptor instead.
+func (*CashbackResponse) Descriptor() ([]byte, []int) {
+	return file_api_cashback_v1_cashback_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CashbackResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CashbackResponse) GetReplayed() bool {
+	if x != nil {
+		return x.Replayed
+	}
+	return false
+}
+
+type GetBalanceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	TuronUserId   int64                  `protobuf:"varint,1,opt,name=turon_user_id,json=turonUserId,proto3" json:"turon_user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalanceRequest) Reset() {
+	*x = GetBalanceRequest{}
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalanceRequest) ProtoMessage() {}
+
+func (x *GetBalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalanceRequest.ProtoReflect.Descriptor instead.
+func (*GetBalanceRequest) Descriptor() ([]byte, []int) {
+	return file_api_cashback_v1_cashback_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetBalanceRequest) GetTuronUserId() int64 {
+	if x != nil {
+		return x.TuronUserId
+	}
+	return 0
+}
+
+type Balance struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	TuronUserId    int64                  `protobuf:"varint,2,opt,name=turon_user_id,json=turonUserId,proto3" json:"turon_user_id,omitempty"`
+	CashbackAmount float64                `protobuf:"fixed64,3,opt,name=cashback_amount,json=cashbackAmount,proto3" json:"cashback_amount,omitempty"`
+	// One of none, active, frozen or closed.
+	Status          string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	FreezeCredits   bool                   `protobuf:"varint,5,opt,name=freeze_credits,json=freezeCredits,proto3" json:"freeze_credits,omitempty"`
+	StatusReason    string                 `protobuf:"bytes,6,opt,name=status_reason,json=statusReason,proto3" json:"status_reason,omitempty"`
+	StatusChangedAt *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=status_changed_at,json=statusChangedAt,proto3" json:"status_changed_at,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Balance) Reset() {
+	*x = Balance{}
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Balance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Balance) ProtoMessage() {}
+
+func (x *Balance) ProtoReflect() protoreflect.Message {
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Balance.ProtoReflect.Descriptor instead.
+func (*Balance) Descriptor() ([]byte, []int) {
+	return file_api_cashback_v1_cashback_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Balance) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Balance) GetTuronUserId() int64 {
+	if x != nil {
+		return x.TuronUserId
+	}
+	return 0
+}
+
+func (x *Balance) GetCashbackAmount() float64 {
+	if x != nil {
+		return x.CashbackAmount
+	}
+	return 0
+}
+
+func (x *Balance) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Balance) GetFreezeCredits() bool {
+	if x != nil {
+		return x.FreezeCredits
+	}
+	return false
+}
+
+func (x *Balance) GetStatusReason() string {
+	if x != nil {
+		return x.StatusReason
+	}
+	return ""
+}
+
+func (x *Balance) GetStatusChangedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StatusChangedAt
+	}
+	return nil
+}
+
+func (x *Balance) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Balance) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// ListHistoryRequest takes the filters of GET /cashback/{id}/history.
+type ListHistoryRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	TuronUserId int64                  `protobuf:"varint,1,opt,name=turon_user_id,json=turonUserId,proto3" json:"turon_user_id,omitempty"`
+	// RFC 3339 timestamp or YYYY-MM-DD date.
+	FromDate string `protobuf:"bytes,2,opt,name=from_date,json=fromDate,proto3" json:"from_date,omitempty"`
+	// RFC 3339 timestamp or YYYY-MM-DD date, inclusive.
+	ToDate string `protobuf:"bytes,3,opt,name=to_date,json=toDate,proto3" json:"to_date,omitempty"`
+	// IANA time zone for date-only bounds.
+	Tz         string   `protobuf:"bytes,4,opt,name=tz,proto3" json:"tz,omitempty"`
+	Operations []string `protobuf:"bytes,5,rep,name=operations,proto3" json:"operations,omitempty"`
+	Sources    []string `protobuf:"bytes,6,rep,name=sources,proto3" json:"sources,omitempty"`
+	Reason     string   `protobuf:"bytes,7,opt,name=reason,proto3" json:"reason,omitempty"`
+	MinAmount  *float64 `protobuf:"fixed64,8,opt,name=min_amount,json=minAmount,proto3,oneof" json:"min_amount,omitempty"`
+	MaxAmount  *float64 `protobuf:"fixed64,9,opt,name=max_amount,json=maxAmount,proto3,oneof" json:"max_amount,omitempty"`
+	// created_at or amount.
+	SortBy string `protobuf:"bytes,10,opt,name=sort_by,json=sortBy,proto3" json:"sort_by,omitempty"`
+	// asc or desc.
+	SortOrder     string `protobuf:"bytes,11,opt,name=sort_order,json=sortOrder,proto3" json:"sort_order,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListHistoryRequest) Reset() {
+	*x = ListHistoryRequest{}
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListHistoryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListHistoryRequest) ProtoMessage() {}
+
+func (x *ListHistoryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListHistoryRequest.ProtoReflect.Descriptor instead.
+func (*ListHistoryRequest) Descriptor() ([]byte, []int) {
+	return file_api_cashback_v1_cashback_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListHistoryRequest) GetTuronUserId() int64 {
+	if x != nil {
+		return x.TuronUserId
+	}
+	return 0
+}
+
+func (x *ListHistoryRequest) GetFromDate() string {
+	if x != nil {
+		return x.FromDate
+	}
+	return ""
+}
+
+func (x *ListHistoryRequest) GetToDate() string {
+	if x != nil {
+		return x.ToDate
+	}
+	return ""
+}
+
+func (x *ListHistoryRequest) GetTz() string {
+	if x != nil {
+		return x.Tz
+	}
+	return ""
+}
+
+func (x *ListHistoryRequest) GetOperations() []string {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+func (x *ListHistoryRequest) GetSources() []string {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *ListHistoryRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *ListHistoryRequest) GetMinAmount() float64 {
+	if x != nil && x.MinAmount != nil {
+		return *x.MinAmount
+	}
+	return 0
+}
+
+func (x *ListHistoryRequest) GetMaxAmount() float64 {
+	if x != nil && x.MaxAmount != nil {
+		return *x.MaxAmount
+	}
+	return 0
+}
+
+func (x *ListHistoryRequest) GetSortBy() string {
+	if x != nil {
+		return x.SortBy
+	}
+	return ""
+}
+
+func (x *ListHistoryRequest) GetSortOrder() string {
+	if x != nil {
+		return x.SortOrder
+	}
+	return ""
+}
+
+type HistoryEntry struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Id             int64                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	CashbackId     int64                  `protobuf:"varint,2,opt,name=cashback_id,json=cashbackId,proto3" json:"cashback_id,omitempty"`
+	TuronUserId    int64                  `protobuf:"varint,3,opt,name=turon_user_id,json=turonUserId,proto3" json:"turon_user_id,omitempty"`
+	SourceSlug     string                 `protobuf:"bytes,4,opt,name=source_slug,json=sourceSlug,proto3" json:"source_slug,omitempty"`
+	Operation      string                 `protobuf:"bytes,5,opt,name=operation,proto3" json:"operation,omitempty"`
+	Reason         string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"`
+	CashbackAmount float64                `protobuf:"fixed64,7,opt,name=cashback_amount,json=cashbackAmount,proto3" json:"cashback_amount,omitempty"`
+	// Signed amount; unset on legacy entries.
+	Amount        *float64               `protobuf:"fixed64,8,opt,name=amount,proto3,oneof" json:"amount,omitempty"`
+	BalanceAfter  *float64               `protobuf:"fixed64,9,opt,name=balance_after,json=balanceAfter,proto3,oneof" json:"balance_after,omitempty"`
+	HostIp        string                 `protobuf:"bytes,10,opt,name=host_ip,json=hostIp,proto3" json:"host_ip,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HistoryEntry) Reset() {
+	*x = HistoryEntry{}
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HistoryEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HistoryEntry) ProtoMessage() {}
+
+func (x *HistoryEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_api_cashback_v1_cashback_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HistoryEntry.ProtoReflect.Descriptor instead.
+func (*HistoryEntry) Descriptor() ([]byte, []int) {
+	return file_api_cashback_v1_cashback_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *HistoryEntry) GetId() int64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetCashbackId() int64 {
+	if x != nil {
+		return x.CashbackId
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetTuronUserId() int64 {
+	if x != nil {
+		return x.TuronUserId
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetSourceSlug() string {
+	if x != nil {
+		return x.SourceSlug
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetCashbackAmount() float64 {
+	if x != nil {
+		return x.CashbackAmount
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetAmount() float64 {
+	if x != nil && x.Amount != nil {
+		return *x.Amount
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetBalanceAfter() float64 {
+	if x != nil && x.BalanceAfter != nil {
+		return *x.BalanceAfter
+	}
+	return 0
+}
+
+func (x *HistoryEntry) GetHostIp() string {
+	if x != nil {
+		return x.HostIp
+	}
+	return ""
+}
+
+func (x *HistoryEntry) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+var File_api_cashback_v1_cashback_proto protoreflect.FileDescriptor
+
+const file_api_cashback_v1_cashback_proto_rawDesc = "" +
+	"\n" +
+	"\x1eapi/cashback/v1/cashback.proto\x12\vcashback.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xb8\x01\n" +
+	"\x0fCashbackRequest\x12\"\n" +
+	"\rturon_user_id\x18\x01 \x01(\x03R\vturonUserId\x12'\n" +
+	"\x0fcashback_amount\x18\x02 \x01(\x01R\x0ecashbackAmount\x12\x17\n" +
+	"\ahost_ip\x18\x03 \x01(\tR\x06hostIp\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12'\n" +
+	"\x0fidempotency_key\x18\x05 \x01(\tR\x0eidempotencyKey\"H\n" +
+	"\x10CashbackResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x1a\n" +
+	"\breplayed\x18\x02 \x01(\bR\breplayed\"7\n" +
+	"\x11GetBalanceRequest\x12\"\n" +
+	"\rturon_user_id\x18\x01 \x01(\x03R\vturonUserId\"\x88\x03\n" +
+	"\aBalance\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\"\n" +
+	"\rturon_user_id\x18\x02 \x01(\x03R\vturonUserId\x12'\n" +
+	"\x0fcashback_amount\x18\x03 \x01(\x01R\x0ecashbackAmount\x12\x16\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12%\n" +
+	"\x0efreeze_credits\x18\x05 \x01(\bR\rfreezeCredits\x12#\n" +
+	"\rstatus_reason\x18\x06 \x01(\tR\fstatusReason\x12F\n" +
+	"\x11status_changed_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\x0fstatusChangedAt\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xee\x02\n" +
+	"\x12ListHistoryRequest\x12\"\n" +
+	"\rturon_user_id\x18\x01 \x01(\x03R\vturonUserId\x12\x1b\n" +
+	"\tfrom_date\x18\x02 \x01(\tR\bfromDate\x12\x17\n" +
+	"\ato_date\x18\x03 \x01(\tR\x06toDate\x12\x0e\n" +
+	"\x02tz\x18\x04 \x01(\tR\x02tz\x12\x1e\n" +
+	"\n" +
+	"operations\x18\x05 \x03(\tR\n" +
+	"operations\x12\x18\n" +
+	"\asources\x18\x06 \x03(\tR\asources\x12\x16\n" +
+	"\x06reason\x18\a \x01(\tR\x06reason\x12\"\n" +
+	"\n" +
+	"min_amount\x18\b \x01(\x01H\x00R\tminAmount\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"max_amount\x18\t \x01(\x01H\x01R\tmaxAmount\x88\x01\x01\x12\x17\n" +
+	"\asort_by\x18\n" +
+	" \x01(\tR\x06sortBy\x12\x1d\n" +
+	"\n" +
+	"sort_order\x18\v \x01(\tR\tsortOrderB\r\n" +
+	"\v_min_amountB\r\n" +
+	"\v_max_amount\"\x9b\x03\n" +
+	"\fHistoryEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x03R\x02id\x12\x1f\n" +
+	"\vcashback_id\x18\x02 \x01(\x03R\n" +
+	"cashbackId\x12\"\n" +
+	"\rturon_user_id\x18\x03 \x01(\x03R\vturonUserId\x12\x1f\n" +
+	"\vsource_slug\x18\x04 \x01(\tR\n" +
+	"sourceSlug\x12\x1c\n" +
+	"\toperation\x18\x05 \x01(\tR\toperation\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x12'\n" +
+	"\x0fcashback_amount\x18\a \x01(\x01R\x0ecashbackAmount\x12\x1b\n" +
+	"\x06amount\x18\b \x01(\x01H\x00R\x06amount\x88\x01\x01\x12(\n" +
+	"\rbalance_after\x18\t \x01(\x01H\x01R\fbalanceAfter\x88\x01\x01\x12\x17\n" +
+	"\ahost_ip\x18\n" +
+	" \x01(\tR\x06hostIp\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAtB\t\n" +
+	"\a_amountB\x10\n" +
+	"\x0e_balance_after2\xc4\x02\n" +
+	"\x0fCashbackService\x12O\n" +
+	"\x10IncreaseCashback\x12\x1c.cashback.v1.CashbackRequest\x1a\x1d.cashback.v1.CashbackResponse\x12O\n" +
+	"\x10DecreaseCashback\x12\x1c.cashback.v1.CashbackRequest\x1a\x1d.cashback.v1.CashbackResponse\x12B\n" +
+	"\n" +
+	"GetBalance\x12\x1e.cashback.v1.GetBalanceRequest\x1a\x14.cashback.v1.Balance\x12K\n" +
+	"\vListHistory\x12\x1f.cashback.v1.ListHistoryRequest\x1a\x19.cashback.v1.HistoryEntry0\x01B*Z(cashback-serv/api/cashback/v1;cashbackv1b\x06proto3"
+
+var (
+	file_api_cashback_v1_cashback_proto_rawDescOnce sync.Once
+	file_api_cashback_v1_cashback_proto_rawDescData []byte
+)
+
+func file_api_cashback_v1_cashback_proto_rawDescGZIP() []byte {
+	file_api_cashback_v1_cashback_proto_rawDescOnce.Do(func() {
+		file_api_cashback_v1_cashback_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_cashback_v1_cashback_proto_rawDesc), len(file_api_cashback_v1_cashback_proto_rawDesc)))
+	})
+	return file_api_cashback_v1_cashback_proto_rawDescData
+}
+
+var file_api_cashback_v1_cashback_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_api_cashback_v1_cashback_proto_goTypes = []any{
+	(*CashbackRequest)(nil),       // 0: cashback.v1.CashbackRequest
+	(*CashbackResponse)(nil),      // 1: cashback.v1.CashbackResponse
+	(*GetBalanceRequest)(nil),     // 2: cashback.v1.GetBalanceRequest
+	(*Balance)(nil),               // 3: cashback.v1.Balance
+	(*ListHistoryRequest)(nil),    // 4: cashback.v1.ListHistoryRequest
+	(*HistoryEntry)(nil),          // 5: cashback.v1.HistoryEntry
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_api_cashback_v1_cashback_proto_depIdxs = []int32{
+	6, // 0: cashback.v1.Balance.status_changed_at:type_name -> google.protobuf.Timestamp
+	6, // 1: cashback.v1.Balance.created_at:type_name -> google.protobuf.Timestamp
+	6, // 2: cashback.v1.Balance.updated_at:type_name -> google.protobuf.Timestamp
+	6, // 3: cashback.v1.HistoryEntry.created_at:type_name -> google.protobuf.Timestamp
+	0, // 4: cashback.v1.CashbackService.IncreaseCashback:input_type -> cashback.v1.CashbackRequest
+	0, // 5: cashback.v1.CashbackService.DecreaseCashback:input_type -> cashback.v1.CashbackRequest
+	2, // 6: cashback.v1.CashbackService.GetBalance:input_type -> cashback.v1.GetBalanceRequest
+	4, // 7: cashback.v1.CashbackService.ListHistory:input_type -> cashback.v1.ListHistoryRequest
+	1, // 8: cashback.v1.CashbackService.IncreaseCashback:output_type -> cashback.v1.CashbackResponse
+	1, // 9: cashback.v1.CashbackService.DecreaseCashback:output_type -> cashback.v1.CashbackResponse
+	3, // 10: cashback.v1.CashbackService.GetBalance:output_type -> cashback.v1.Balance
+	5, // 11: cashback.v1.CashbackService.ListHistory:output_type -> cashback.v1.HistoryEntry
+	8, // [8:12] is the sub-list for method output_type
+	4, // [4:8] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_api_cashback_v1_cashback_proto_init() }
+func file_api_cashback_v1_cashback_proto_init() {
+	if File_api_cashback_v1_cashback_proto != nil {
+		return
+	}
+	file_api_cashback_v1_cashback_proto_msgTypes[4].OneofWrappers = []any{}
+	file_api_cashback_v1_cashback_proto_msgTypes[5].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_cashback_v1_cashback_proto_rawDesc), len(file_api_cashback_v1_cashback_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_cashback_v1_cashback_proto_goTypes,
+		DependencyIndexes: file_api_cashback_v1_cashback_proto_depIdxs,
+		MessageInfos:      file_api_cashback_v1_cashback_proto_msgTypes,
+	}.Build()
+	File_api_cashback_v1_cashback_proto = out.File
+	file_api_cashback_v1_cashback_proto_goTypes = nil
+	file_api_cashback_v1_cashback_proto_depIdxs = nil
+}
@@ -0,0 +1,251 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: api/cashback/v1/cashback.proto
+
+package cashbackv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CashbackService_IncreaseCashback_FullMethodName = "/cashback.v1.CashbackService/IncreaseCashback"
+	CashbackService_DecreaseCashback_FullMethodName = "/cashback.v1.CashbackService/DecreaseCashback"
+	CashbackService_GetBalance_FullMethodName       = "/cashback.v1.CashbackService/GetBalance"
+	CashbackService_ListHistory_FullMethodName      = "/cashback.v1.CashbackService/ListHistory"
+)
+
+// CashbackServiceClient is the client API for CashbackService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CashbackService is the gRPC counterpart of the /cashback REST endpoints.
+// Errors use the status codes matching the REST answers: INVALID_ARGUMENT
+// for 400, NOT_FOUND for 404, FAILED_PRECONDITION for 409 and INTERNAL for
+// 500.
+type CashbackServiceClient interface {
+	IncreaseCashback(ctx context.Context, in *CashbackRequest, opts ...grpc.CallOption) (*CashbackResponse, error)
+	DecreaseCashback(ctx context.Context, in *CashbackRequest, opts ...grpc.CallOption) (*CashbackResponse, error)
+	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*Balance, error)
+	// ListHistory streams the user's matching history entries.
+	ListHistory(ctx context.Context, in *ListHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[HistoryEntry], error)
+}
+
+type cashbackServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCashbackServiceClient(cc grpc.ClientConnInterface) CashbackServiceClient {
+	return &cashbackServiceClient{cc}
+}
+
+func (c *cashbackServiceClient) IncreaseCashback(ctx context.Context, in *CashbackRequest, opts ...grpc.CallOption) (*CashbackResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CashbackResponse)
+	err := c.cc.Invoke(ctx, CashbackService_IncreaseCashback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cashbackServiceClient) DecreaseCashback(ctx context.Context, in *CashbackRequest, opts ...grpc.CallOption) (*CashbackResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CashbackResponse)
+	err := c.cc.Invoke(ctx, CashbackService_DecreaseCashback_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cashbackServiceClient) GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*Balance, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Balance)
+	err := c.cc.Invoke(ctx, CashbackService_GetBalance_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cashbackServiceClient) ListHistory(ctx context.Context, in *ListHistoryRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[HistoryEntry], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CashbackService_ServiceDesc.Streams[0], CashbackService_ListHistory_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListHistoryRequest, HistoryEntry]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CashbackService_ListHistoryClient = grpc.ServerStreamingClient[HistoryEntry]
+
+// CashbackServiceServer is the server API for CashbackService service.
+// All implementations must embed UnimplementedCashbackServiceServer
+// for forward compatibility.
+//
+// CashbackService is the gRPC counterpart of the /cashback REST endpoints.
+// Errors use the status codes matching the REST answers: INVALID_ARGUMENT
+// for 400, NOT_FOUND for 404, FAILED_PRECONDITION for 409 and INTERNAL for
+// 500.
+type CashbackServiceServer interface {
+	IncreaseCashback(context.Context, *CashbackRequest) (*CashbackResponse, error)
+	DecreaseCashback(context.Context, *CashbackRequest) (*CashbackResponse, error)
+	GetBalance(context.Context, *GetBalanceRequest) (*Balance, error)
+	// ListHistory streams the user's matching history entries.
+	ListHistory(*ListHistoryRequest, grpc.ServerStreamingServer[HistoryEntry]) error
+	mustEmbedUnimplementedCashbackServiceServer()
+}
+
+// UnimplementedCashbackServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCashbackServiceServer struct{}
+
+func (UnimplementedCashbackServiceServer) IncreaseCashback(context.Context, *CashbackRequest) (*CashbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IncreaseCashback not implemented")
+}
+func (UnimplementedCashbackServiceServer) DecreaseCashback(context.Context, *CashbackRequest) (*CashbackResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DecreaseCashback not implemented")
+}
+func (UnimplementedCashbackServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*Balance, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedCashbackServiceServer) ListHistory(*ListHistoryRequest, grpc.ServerStreamingServer[HistoryEntry]) error {
+	return status.Errorf(codes.Unimplemented, "method ListHistory not implemented")
+}
+func (UnimplementedCashbackServiceServer) mustEmbedUnimplementedCashbackServiceServer() {}
+func (UnimplementedCashbackServiceServer) testEmbeddedByValue()                         {}
+
+// UnsafeCashbackServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CashbackServiceServer will
+// result in compilation errors.
+type UnsafeCashbackServiceServer interface {
+	mustEmbedUnimplementedCashbackServiceServer()
+}
+
+func RegisterCashbackServiceServer(s grpc.ServiceRegistrar, srv CashbackServiceServer) {
+	// If the following call pancis, it indicates UnimplementedCashbackServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CashbackService_ServiceDesc, srv)
+}
+
+func _CashbackService_IncreaseCashback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CashbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CashbackServiceServer).IncreaseCashback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CashbackService_IncreaseCashback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CashbackServiceServer).IncreaseCashback(ctx, req.(*CashbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CashbackService_DecreaseCashback_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CashbackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CashbackServiceServer).DecreaseCashback(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CashbackService_DecreaseCashback_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CashbackServiceServer).DecreaseCashback(ctx, req.(*CashbackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CashbackService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CashbackServiceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CashbackService_GetBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CashbackServiceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CashbackService_ListHistory_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListHistoryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CashbackServiceServer).ListHistory(m, &grpc.GenericServerStream[ListHistoryRequest, HistoryEntry]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CashbackService_ListHistoryServer = grpc.ServerStreamingServer[HistoryEntry]
+
+// CashbackService_ServiceDesc is the grpc.ServiceDesc for CashbackService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CashbackService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "cashback.v1.CashbackService",
+	HandlerType: (*CashbackServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IncreaseCashback",
+			Handler:    _CashbackService_IncreaseCashback_Handler,
+		},
+		{
+			MethodName: "DecreaseCashback",
+			Handler:    _CashbackService_DecreaseCashback_Handler,
+		},
+		{
+			MethodName: "GetBalance",
+			Handler:    _CashbackService_GetBalance_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListHistory",
+			Handler:       _CashbackService_ListHistory_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "api/cashback/v1/cashback.proto",
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"cashback-serv/config"
+	"cashback-serv/internal/repository"
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "time/tzdata"
+)
+
+const usage = `usage: cashbackctl <command> [flags]
+
+commands:
+  rebuild-balances   recompute every cached cashback balance from the ledger
+  reconcile          compare stored balances with history and write a report
+                     flags: -from <turon_user_id> -to <turon_user_id> -out <dir> -repair
+  snapshot           store a balance snapshot of every wallet
+                     flags: -as-of <timestamp> -tz <zone>
+  export-balances    write every user's balance at a point in time as CSV
+                     flags: -as-of <timestamp> -tz <zone> -out <file>
+  statements         pre-generate monthly statements for every active user
+                     flags: -month <YYYY-MM> -tz <zone>`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
+	db, err := sql.Open("postgres", cfg.GetDSN())
+	if err != nil {
+		log.Fatalf("Connection error with database: %v", err)
+	}
+	defer db.Close()
+
+	cashbackRepo := repository.NewCashbackRepository(db)
+	sourceRepo := repository.NewSourceRepository(db)
+	sourceService := service.NewSourceService(sourceRepo)
+	cashbackService := service.NewCashbackService(cashbackRepo, sourceService)
+	cashbackService.SetTimeZone(cfg.TimeZone)
+
+	switch os.Args[1] {
+	case "rebuild-balances":
+		changed, err := cashbackService.RebuildBalances()
+		if err != nil {
+			log.Fatalf("Rebuild failed: %v", err)
+		}
+		fmt.Printf("rebuilt balances: %d changed\n", changed)
+	case "reconcile":
+		runReconcile(cashbackService, os.Args[2:])
+	case "snapshot":
+		runSnapshot(cashbackService, os.Args[2:])
+	case "export-balances":
+		runExportBalances(cashbackService, os.Args[2:])
+	case "statements":
+		runStatements(cashbackService, os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runReconcile(cashbackService *service.CashbackService, args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	from := flags.Int64("from", 0, "first turon_user_id of the range (0 = unbounded)")
+	to := flags.Int64("to", 0, "last turon_user_id of the range (0 = unbounded)")
+	outDir := flags.String("out", ".", "directory for the JSON and CSV reports")
+	repair := flags.Bool("repair", false, "write adjustment entries for every discrepancy")
+	flags.Parse(args)
+
+	report, err := cashbackService.Reconcile(*from, *to, *repair, "")
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	base := filepath.Join(*outDir, "reconciliation-"+report.StartedAt.Format("20060102-150405"))
+	if err := writeReconciliationReport(base, report); err != nil {
+		log.Fatalf("Writing report failed: %v", err)
+	}
+
+	fmt.Printf("scanned %d accounts, %d discrepancies, report: %s.{json,csv}\n",
+		report.Scanned, len(report.Discrepancies), base)
+}
+
+func writeReconciliationReport(base string, report *models.ReconciliationReport) error {
+	jsonFile, err := os.Create(base + ".json")
+	if err != nil {
+		return err
+	}
+	defer jsonFile.Close()
+
+	encoder := json.NewEncoder(jsonFile)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+
+	csvFile, err := os.Create(base + ".csv")
+	if err != nil {
+		return err
+	}
+	defer csvFile.Close()
+
+	return service.WriteReconciliationCSV(csvFile, report)
+}
+
+func runSnapshot(cashbackService *service.CashbackService, args []string) {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	asOfParam := flags.String("as-of", "", "snapshot instant (RFC 3339 or YYYY-MM-DD, default now)")
+	tz := flags.String("tz", "", "time zone for a YYYY-MM-DD -as-of (default TIMEZONE)")
+	flags.Parse(args)
+
+	asOf := time.Now()
+	if *asOfParam != "" {
+		var err error
+		if asOf, err = cashbackService.ParseAsOf(*asOfParam, *tz); err != nil {
+			log.Fatalf("Invalid -as-of: %v", err)
+		}
+	}
+
+	created, err := cashbackService.TakeBalanceSnapshots(asOf)
+	if err != nil {
+		log.Fatalf("Snapshot failed: %v", err)
+	}
+	fmt.Printf("snapshot as of %s: %d balances stored\n", asOf.Format(time.RFC3339), created)
+}
+
+func runExportBalances(cashbackService *service.CashbackService, args []string) {
+	flags := flag.NewFlagSet("export-balances", flag.ExitOnError)
+	asOfParam := flags.String("as-of", "", "balance instant (RFC 3339 or YYYY-MM-DD)")
+	tz := flags.String("tz", "", "time zone for a YYYY-MM-DD -as-of (default TIMEZONE)")
+	out := flags.String("out", "", "output file (default stdout)")
+	flags.Parse(args)
+
+	if *asOfParam == "" {
+		log.Fatalf("-as-of must be provided")
+	}
+	asOf, err := cashbackService.ParseAsOf(*asOfParam, *tz)
+	if err != nil {
+		log.Fatalf("Invalid -as-of: %v", err)
+	}
+
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			log.Fatalf("Creating output failed: %v", err)
+		}
+		defer w.Close()
+	}
+
+	if err := cashbackService.ExportBalancesAsOf(w, asOf); err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+}
+
+func runStatements(cashbackService *service.CashbackService, args []string) {
+	flags := flag.NewFlagSet("statements", flag.ExitOnError)
+	month := flags.String("month", "", "statement month, YYYY-MM (default the previous month)")
+	tz := flags.String("tz", "", "time zone of the month boundaries (default TIMEZONE)")
+	flags.Parse(args)
+
+	if *month == "" {
+		var err error
+		if *month, err = cashbackService.PreviousStatementPeriod(*tz); err != nil {
+			log.Fatalf("Invalid -tz: %v", err)
+		}
+	}
+
+	generated, err := cashbackService.GenerateStatements(*month, *tz)
+	if err != nil {
+		log.Fatalf("Statement generation failed: %v", err)
+	}
+	fmt.Printf("statements for %s: %d generated\n", *month, generated)
+}
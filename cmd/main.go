@@ -2,12 +2,26 @@ package main
 
 import (
 	"cashback-serv/config"
+	"cashback-serv/internal/grpcserver"
 	"cashback-serv/internal/handler"
+	"cashback-serv/internal/logging"
+	"cashback-serv/internal/metrics"
 	"cashback-serv/internal/repository"
 	"cashback-serv/internal/service"
+	"cashback-serv/internal/tracing"
+	"cashback-serv/migrations"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata"
 
 	_ "cashback-serv/docs"
 
@@ -15,6 +29,7 @@ import (
 	_ "github.com/lib/pq"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"google.golang.org/grpc"
 )
 
 // @title Cashback Service API
@@ -22,36 +37,173 @@ import (
 // @description CASHBACK SERVICE API
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey OperatorToken
+// @in header
+// @name Authorization
+// @description "Bearer <token>" of the operator, from ADMIN_OPERATOR_TOKENS
 func main() {
 	gin.SetMode(gin.ReleaseMode)
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Configuration error: %v", err)
+		fatal("Configuration error", err)
 	}
 
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fatal("Configuration error", err)
+	}
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.SampleRatio)
+	if err != nil {
+		fatal("Configuration error", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := sql.Open("postgres", cfg.GetDSN())
 	if err != nil {
-		log.Fatalf("Connection error with database: %v", err)
+		fatal("Connection error with database", err)
 	}
 	defer db.Close()
+	metrics.RegisterDB(db)
+
+	// sql.Open does not connect. An unreachable database is not fatal, since
+	// it may come up later, but /readyz fails until it does.
+	pingCtx, cancelPing := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := db.PingContext(pingCtx); err != nil {
+		slog.Warn("Database is unreachable", "error", err)
+	}
+	cancelPing()
+
+	migrationVersion, err := migrations.Latest()
+	if err != nil {
+		fatal("Migrations error", err)
+	}
 
 	cashbackRepo := repository.NewCashbackRepository(db)
 	sourceRepo := repository.NewSourceRepository(db)
 	sourceService := service.NewSourceService(sourceRepo)
 
 	cashbackService := service.NewCashbackService(cashbackRepo, sourceService)
+	cashbackService.SetAdjustmentApprovalThreshold(cfg.Adjustment.ApprovalThreshold)
+	cashbackService.SetTimeZone(cfg.TimeZone)
+	cashbackService.StartBalanceSnapshots(cfg.Snapshot.Interval)
+	cashbackService.StartOperationCleanup(cfg.Operation.Retention)
+	if err := cashbackService.ResumeBatches(); err != nil {
+		slog.Warn("Failed to resume unfinished batches", "error", err)
+	}
+
+	cashbackStream := service.NewCashbackStream()
+	repository.ListenCashbackUpdates(cfg.GetDSN(), cashbackStream.Notify, cashbackStream.NotifyAll)
+	cashbackService.SetStream(cashbackStream)
+
+	webhookRepo := repository.NewWebhookRepository(db)
+	webhookService := service.NewWebhookService(webhookRepo, sourceRepo)
+	webhookService.SetMaxAttempts(cfg.Webhook.MaxAttempts)
+	webhookService.StartDispatcher(cfg.Webhook.PollInterval)
+	webhookService.StartCleanup(cfg.Webhook.Retention)
+
+	healthService := service.NewHealthService(repository.NewHealthRepository(db), cashbackService, migrationVersion)
+	healthService.SetWorkerStallTimeout(cfg.Server.WorkerStallTimeout)
 
 	cashbackHandler := handler.NewCashbackHandler(cashbackService)
+	adminHandler := handler.NewAdminHandler(cashbackService)
+	webhookHandler := handler.NewWebhookHandler(webhookService)
+	healthHandler := handler.NewHealthHandler(healthService)
 
-	router := gin.Default()
+	router := gin.New()
+	router.Use(handler.RequestID(), tracing.Middleware(), handler.AccessLog(), handler.Recovery(), metrics.Middleware())
+	if len(cfg.Server.SigningSecrets) > 0 {
+		router.Use(handler.VerifySignature(cfg.Server.SigningSecrets, "/swagger/*any", "/metrics", "/healthz", "/readyz",
+			// EventSource cannot set the signature headers; the stream
+			// checks a stream token instead.
+			"/cashback/:turon_user_id/stream", "/v1/cashback/:turon_user_id/stream"))
+		cashbackHandler.SetStreamSecrets(cfg.Server.SigningSecrets)
+	}
+	if len(cfg.Admin.OperatorTokens) == 0 {
+		slog.Warn("ADMIN_OPERATOR_TOKENS is empty: the admin API rejects every request")
+	}
+	router.Use(handler.AuthenticateOperator(cfg.Admin.OperatorTokens, "/admin/"))
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	cashbackHandler.RegisterRoutes(router)
+	adminHandler.RegisterRoutes(router)
+	webhookHandler.RegisterRoutes(router)
+	healthHandler.RegisterRoutes(router)
+
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Port != 0 {
+		if len(cfg.GRPC.AuthTokens) == 0 {
+			fatal("gRPC is enabled without authentication", errors.New("GRPC_PORT is set but GRPC_AUTH_TOKENS is empty"))
+		}
+
+		grpcAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.GRPC.Port)
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			fatal("gRPC listen error", err)
+		}
 
-	serverAddr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
-	if err := router.Run(serverAddr); err != nil {
-		log.Fatalf("Something went wrogn: %v", err)
+		grpcServer = grpcserver.New(cashbackService, cfg.GRPC.AuthTokens)
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				fatal("gRPC server error", err)
+			}
+		}()
 	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		Handler: router,
+	}
+	server.RegisterOnShutdown(cashbackHandler.CloseStreams)
+	go func() {
+		slog.Info("HTTP server listening", "addr", server.Addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("Something went wrogn", err)
+		}
+	}()
+
+	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-signals.Done()
+	stop()
+
+	// Fail readiness first and keep serving for a while, so load balancers
+	// stop routing here before connections are refused.
+	slog.Info("Shutting down", "delay", cfg.Server.ShutdownDelay)
+	healthService.SetShuttingDown()
+	time.Sleep(cfg.Server.ShutdownDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		defer func() {
+			select {
+			case <-stopped:
+			case <-ctx.Done():
+				grpcServer.Stop()
+			}
+		}()
+	}
+
+	// Balance streams are ended by CloseStreams once shutdown begins.
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Warn("HTTP server did not shut down cleanly", "error", err)
+		server.Close()
+	}
+	slog.Info("HTTP server stopped")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
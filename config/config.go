@@ -4,14 +4,26 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DB     DBConfig
-	Server ServerConfig
-	Env    string
+	DB         DBConfig
+	Server     ServerConfig
+	GRPC       GRPCConfig
+	Snapshot   SnapshotConfig
+	Adjustment AdjustmentConfig
+	Admin      AdminConfig
+	Operation  OperationConfig
+	Webhook    WebhookConfig
+	Log        LogConfig
+	Tracing    TracingConfig
+	// TimeZone resolves date-only filters when the client sends no tz.
+	TimeZone *time.Location
+	Env      string
 }
 
 type DBConfig struct {
@@ -25,6 +37,69 @@ type DBConfig struct {
 type ServerConfig struct {
 	Port int
 	Host string
+	// SigningSecrets, when set, require every API request to be signed
+	// with one of them.
+	SigningSecrets []string
+	// ShutdownDelay is how long /readyz fails before the server stops
+	// accepting connections, so load balancers can take it out first.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout bounds the wait for in-flight requests to finish.
+	ShutdownTimeout time.Duration
+	// WorkerStallTimeout is how long the queue worker may spend on one
+	// operation before /readyz fails.
+	WorkerStallTimeout time.Duration
+}
+
+// GRPCConfig configures the gRPC API, served on Server.Host. Port 0, the
+// default, turns it off; serving it requires AuthTokens.
+type GRPCConfig struct {
+	Port       int
+	AuthTokens []string
+}
+
+type SnapshotConfig struct {
+	Interval time.Duration
+}
+
+type AdjustmentConfig struct {
+	ApprovalThreshold float64
+}
+
+// AdminConfig authenticates the operators of the admin API.
+type AdminConfig struct {
+	// OperatorTokens maps each bearer token to the operator it identifies.
+	// Without any, the admin API rejects every request.
+	OperatorTokens map[string]string
+}
+
+type OperationConfig struct {
+	// Retention is how long finished async operation records are kept.
+	Retention time.Duration
+}
+
+type WebhookConfig struct {
+	// PollInterval is how often the dispatcher looks for due deliveries.
+	PollInterval time.Duration
+	// MaxAttempts failed attempts move a delivery to the dead-letter list.
+	MaxAttempts int
+	// Retention is how long delivered events are kept.
+	Retention time.Duration
+}
+
+type LogConfig struct {
+	// Level is debug, info, warn or error.
+	Level string
+	// Format is json or text.
+	Format string
+}
+
+// TracingConfig selects where spans go: none, stdout or otlp. The OTLP
+// endpoint comes from the standard OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	Exporter string
+	// SampleRatio of new traces is recorded; traces started by a caller
+	// follow the caller's decision.
+	SampleRatio float64
 }
 
 func (c *Config) GetDSN() string {
@@ -38,9 +113,9 @@ func (c *Config) GetDSN() string {
 }
 
 func LoadConfig() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
-		fmt.Errorf("error loading .env file: %w", err)
-		}
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error loading .env file: %w", err)
+	}
 
 	dbPort, err := strconv.Atoi(getEnv("DB_PORT", "5432"))
 	if err != nil {
@@ -52,6 +127,71 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("invalid SERVER_PORT: %w", err)
 	}
 
+	shutdownDelay, err := time.ParseDuration(getEnv("SHUTDOWN_DELAY", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_DELAY: %w", err)
+	}
+
+	shutdownTimeout, err := time.ParseDuration(getEnv("SHUTDOWN_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %w", err)
+	}
+
+	workerStallTimeout, err := time.ParseDuration(getEnv("WORKER_STALL_TIMEOUT", "30s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WORKER_STALL_TIMEOUT: %w", err)
+	}
+
+	grpcPort, err := strconv.Atoi(getEnv("GRPC_PORT", "0"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid GRPC_PORT: %w", err)
+	}
+
+	snapshotInterval, err := time.ParseDuration(getEnv("BALANCE_SNAPSHOT_INTERVAL", "24h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid BALANCE_SNAPSHOT_INTERVAL: %w", err)
+	}
+
+	approvalThreshold, err := strconv.ParseFloat(getEnv("ADJUSTMENT_APPROVAL_THRESHOLD", "1000"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ADJUSTMENT_APPROVAL_THRESHOLD: %w", err)
+	}
+
+	operatorTokens, err := getEnvOperatorTokens("ADMIN_OPERATOR_TOKENS")
+	if err != nil {
+		return nil, err
+	}
+
+	operationRetention, err := time.ParseDuration(getEnv("OPERATION_RETENTION", "168h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid OPERATION_RETENTION: %w", err)
+	}
+
+	webhookPollInterval, err := time.ParseDuration(getEnv("WEBHOOK_POLL_INTERVAL", "5s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_POLL_INTERVAL: %w", err)
+	}
+
+	webhookMaxAttempts, err := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "10"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: %w", err)
+	}
+
+	webhookRetention, err := time.ParseDuration(getEnv("WEBHOOK_RETENTION", "168h"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_RETENTION: %w", err)
+	}
+
+	tracingSampleRatio, err := strconv.ParseFloat(getEnv("TRACING_SAMPLE_RATIO", "1"), 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRACING_SAMPLE_RATIO: %w", err)
+	}
+
+	timeZone, err := time.LoadLocation(getEnv("TIMEZONE", "Asia/Tashkent"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TIMEZONE: %w", err)
+	}
+
 	config := &Config{
 		DB: DBConfig{
 			Name:     getEnv("DB_NAME", "postgres"),
@@ -61,10 +201,44 @@ func LoadConfig() (*Config, error) {
 			Port:     dbPort,
 		},
 		Server: ServerConfig{
-			Port: serverPort,
-			Host: getEnv("SERVER_HOST", "localhost"),
+			Port:               serverPort,
+			Host:               getEnv("SERVER_HOST", "localhost"),
+			SigningSecrets:     getEnvList("API_SIGNING_SECRETS"),
+			ShutdownDelay:      shutdownDelay,
+			ShutdownTimeout:    shutdownTimeout,
+			WorkerStallTimeout: workerStallTimeout,
+		},
+		GRPC: GRPCConfig{
+			Port:       grpcPort,
+			AuthTokens: getEnvList("GRPC_AUTH_TOKENS"),
+		},
+		Snapshot: SnapshotConfig{
+			Interval: snapshotInterval,
+		},
+		Adjustment: AdjustmentConfig{
+			ApprovalThreshold: approvalThreshold,
+		},
+		Admin: AdminConfig{
+			OperatorTokens: operatorTokens,
+		},
+		Operation: OperationConfig{
+			Retention: operationRetention,
+		},
+		Webhook: WebhookConfig{
+			PollInterval: webhookPollInterval,
+			MaxAttempts:  webhookMaxAttempts,
+			Retention:    webhookRetention,
+		},
+		Log: LogConfig{
+			Level:  getEnv("LOG_LEVEL", "info"),
+			Format: getEnv("LOG_FORMAT", "json"),
+		},
+		Tracing: TracingConfig{
+			Exporter:    getEnv("TRACING_EXPORTER", "none"),
+			SampleRatio: tracingSampleRatio,
 		},
-		Env: getEnv("ENV", "development"),
+		TimeZone: timeZone,
+		Env:      getEnv("ENV", "development"),
 	}
 
 	return config, nil
@@ -77,3 +251,31 @@ func getEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// getEnvList splits a comma-separated variable, skipping empty items.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvOperatorTokens reads a comma-separated list of operator=token pairs.
+func getEnvOperatorTokens(key string) (map[string]string, error) {
+	tokens := map[string]string{}
+	for _, pair := range getEnvList(key) {
+		operator, token, ok := strings.Cut(pair, "=")
+		operator, token = strings.TrimSpace(operator), strings.TrimSpace(token)
+		if !ok || operator == "" || token == "" {
+			return nil, fmt.Errorf("invalid %s: entries must be operator=token", key)
+		}
+		if _, exists := tokens[token]; exists {
+			return nil, fmt.Errorf("invalid %s: token of %s is not unique", key, operator)
+		}
+		tokens[token] = operator
+	}
+	return tokens, nil
+}
@@ -5,4 +5,103 @@ const(
 	 Decrease     	= "decrease"
 	 SourceTuron    = "turon"
 	 SourceCinerama = "cinerama"
-)
\ No newline at end of file
+	 Reconcile      = "reconcile"
+	 Adjust         = "adjust"
+	 Close          = "close"
+)
+
+const (
+	AccountKindUser   = "user"
+	AccountKindSource = "source"
+	AccountKindSystem = "system"
+
+	SystemAccountOpening    = "opening"
+	SystemAccountExpiry     = "expiry"
+	SystemAccountReversal   = "reversal"
+	SystemAccountAdjustment = "adjustment"
+	SystemAccountPayout     = "payout"
+	SystemAccountForfeiture = "forfeiture"
+)
+
+// Operations recorded on cashback_history rows. Credit and debit are the
+// regular movements; the rest name system-initiated kinds.
+const (
+	OperationCredit     = "credit"
+	OperationDebit      = "debit"
+	OperationOpening    = "opening"
+	OperationAdjustment = "adjustment"
+	OperationExpiry     = "expiry"
+	OperationPayout     = "payout"
+	OperationForfeiture = "forfeiture"
+	OperationLegacy     = "legacy"
+
+	ReasonReconciliation = "reconciliation"
+)
+
+const (
+	AdjustmentStatusPendingApproval = "pending_approval"
+	AdjustmentStatusApproved        = "approved"
+	AdjustmentStatusPosted          = "posted"
+	AdjustmentStatusRejected        = "rejected"
+	AdjustmentStatusFailed          = "failed"
+
+	AdjustmentReasonCorrection   = "correction"
+	AdjustmentReasonGoodwill     = "goodwill"
+	AdjustmentReasonCompensation = "compensation"
+	AdjustmentReasonFraud        = "fraud"
+	AdjustmentReasonOther        = "other"
+)
+
+const (
+	BatchStatusProcessing = "processing"
+	BatchStatusCompleted  = "completed"
+
+	BatchItemStatusPending   = "pending"
+	BatchItemStatusSucceeded = "succeeded"
+	BatchItemStatusFailed    = "failed"
+)
+
+const (
+	OperationStatusPending   = "pending"
+	OperationStatusSucceeded = "succeeded"
+	OperationStatusFailed    = "failed"
+)
+
+// Account statuses. AccountStatusNone is never stored: it describes a user
+// who has no wallet yet.
+const (
+	AccountStatusNone   = "none"
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// How the remaining balance of a closed account is settled.
+const (
+	ClosureDispositionPayout  = "payout"
+	ClosureDispositionForfeit = "forfeit"
+)
+
+// Webhook event types.
+const (
+	EventCashbackCredited      = "cashback.credited"
+	EventCashbackDebited       = "cashback.debited"
+	EventCashbackDebitRejected = "cashback.debit_rejected"
+	EventSourceCreated         = "source.created"
+	EventAccountClosed         = "account.closed"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead"
+)
+
+// Health check and readiness statuses.
+const (
+	HealthStatusOK     = "ok"
+	HealthStatusFailed = "failed"
+
+	ReadinessReady    = "ready"
+	ReadinessNotReady = "not_ready"
+)
@@ -15,33 +15,114 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/cashback/decrease": {
-            "post": {
-                "description": "Cashback amount decrease of the user",
-                "consumes": [
-                    "application/json"
+        "/admin/adjustments": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
                 ],
+                "description": "List manual adjustments, optionally by status",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "cashback"
+                    "admin"
                 ],
-                "summary": "Cashback amount decrease",
+                "summary": "List manual adjustments",
                 "parameters": [
                     {
-                        "description": "Cashback amount decrease ",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                        "enum": [
+                            "pending_approval",
+                            "approved",
+                            "posted",
+                            "rejected",
+                            "failed"
+                        ],
+                        "type": "string",
+                        "description": "Adjustment status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data: array of adjustments, pagination: pagination info",
                         "schema": {
-                            "$ref": "#/definitions/models.CashbackRequest"
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
+                }
+            }
+        },
+        "/admin/adjustments/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a manual adjustment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Adjustment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackAdjustment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -49,8 +130,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -70,9 +160,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/cashback/increase": {
+        "/admin/adjustments/{id}/approve": {
             "post": {
-                "description": "Increase cashback of the user",
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Approve and post a pending adjustment. The approving operator, identified by their token, must differ from the requesting one",
                 "consumes": [
                     "application/json"
                 ],
@@ -80,23 +175,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cashback"
+                    "admin"
                 ],
-                "summary": "Cashback increase",
+                "summary": "Approve a manual adjustment",
                 "parameters": [
                     {
-                        "description": "Cashback increase",
+                        "type": "integer",
+                        "description": "Adjustment ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Approval",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.CashbackRequest"
+                            "$ref": "#/definitions/models.AdjustmentDecision"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackAdjustment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -104,8 +212,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -113,6 +230,21 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackAdjustment"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -125,9 +257,13 @@ const docTemplate = `{
                 }
             }
         },
-        "/cashback/{turon_user_id}": {
-            "get": {
-                "description": "Cashback amount of the user",
+        "/admin/adjustments/{id}/reject": {
+            "post": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
                 "consumes": [
                     "application/json"
                 ],
@@ -135,23 +271,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "cashback"
+                    "admin"
                 ],
-                "summary": "GET Cashback",
+                "summary": "Reject a manual adjustment",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "Turon User ID",
-                        "name": "turon_user_id",
+                        "description": "Adjustment ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Rejection",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AdjustmentDecision"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Cashback"
+                            "$ref": "#/definitions/models.CashbackAdjustment"
                         }
                     },
                     "400": {
@@ -163,6 +308,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -172,6 +326,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -184,64 +347,61 @@ const docTemplate = `{
                 }
             }
         },
-        "/cashback/{turon_user_id}/history": {
+        "/admin/analytics/active-users": {
             "get": {
-                "description": "Get cashback history with optional date filtering and pagination",
-                "consumes": [
-                    "application/json"
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
                 ],
+                "description": "Number of wallets with at least one entry in each period",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "cashback"
+                    "analytics"
                 ],
-                "summary": "CashbackHistory of the user",
+                "summary": "Active users",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Turon User ID",
-                        "name": "turon_user_id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "string",
-                        "format": "date",
                         "example": "2024-03-01",
-                        "description": "Start date",
+                        "description": "Start: RFC 3339 timestamp or YYYY-MM-DD date",
                         "name": "from_date",
-                        "in": "query"
+                        "in": "query",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "format": "date",
-                        "example": "2024-03-20",
-                        "description": "End date",
+                        "example": "2024-03-31",
+                        "description": "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)",
                         "name": "to_date",
-                        "in": "query"
+                        "in": "query",
+                        "required": true
                     },
                     {
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 1,
-                        "description": "Page number",
-                        "name": "page",
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone of the dates and periods (default server TIMEZONE). Zones whose offset is not a whole number of hours are rejected",
+                        "name": "tz",
                         "in": "query"
                     },
                     {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Items per page",
-                        "name": "page_size",
+                        "enum": [
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Period length",
+                        "name": "interval",
                         "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "data: array of cashback history, pagination: pagination info",
+                        "description": "data: array of models.ActiveUsersPoint",
                         "schema": {
                             "type": "object",
                             "additionalProperties": true
@@ -256,6 +416,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -267,54 +436,3892 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "models.Cashback": {
-            "type": "object",
-            "properties": {
-                "cashback_amount": {
-                    "type": "number",
-                    "example": 100.5
-                },
-                "created_at": {
-                    "type": "string",
-                    "example": "2024-03-20T10:00:00Z"
-                },
-                "deleted_at": {
-                    "type": "string",
-                    "example": "null"
-                },
-                "id": {
-                    "type": "integer",
-                    "example": 1
-                },
-                "turon_user_id": {
-                    "type": "integer",
-                    "example": 123
-                },
-                "updated_at": {
-                    "type": "string",
-                    "example": "2024-03-20T10:00:00Z"
-                }
-            }
         },
-        "models.CashbackRequest": {
-            "type": "object",
-            "properties": {
-                "cashback_amount": {
-                    "type": "number"
-                },
-                "host_ip": {
-                    "type": "string"
-                },
-                "turon_user_id": {
-                    "type": "integer"
-                },
-                "type": {
-                    "type": "string"
-                }
-            }
+        "/admin/analytics/liability": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Cashback outstanding across all wallets at the end of each period",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Outstanding cashback liability",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2024-01-01",
+                        "description": "Start: RFC 3339 timestamp or YYYY-MM-DD date",
+                        "name": "from_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-31",
+                        "description": "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)",
+                        "name": "to_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone of the dates and periods (default server TIMEZONE). Zones whose offset is not a whole number of hours are rejected",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Period length",
+                        "name": "interval",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data: array of models.LiabilityPoint",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/analytics/top-earners": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Users with the most cashback credited in the range",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Top earners",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2024-03-01",
+                        "description": "Start: RFC 3339 timestamp or YYYY-MM-DD date",
+                        "name": "from_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-31",
+                        "description": "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)",
+                        "name": "to_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone of the dates (default server TIMEZONE). Zones whose offset is not a whole number of hours are rejected",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 1000,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of users",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data: array of models.TopEarner",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/analytics/totals": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Credited, debited and net cashback per day, week or month, optionally split by source or operation. Served from hourly rollups, so bounds apply at hour granularity",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "analytics"
+                ],
+                "summary": "Cashback totals",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2024-03-01",
+                        "description": "Start: RFC 3339 timestamp or YYYY-MM-DD date",
+                        "name": "from_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-31",
+                        "description": "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)",
+                        "name": "to_date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone of the dates and periods (default server TIMEZONE). Zones whose offset is not a whole number of hours are rejected",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "day",
+                            "week",
+                            "month"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Period length",
+                        "name": "interval",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "source",
+                            "operation"
+                        ],
+                        "type": "string",
+                        "description": "Split each period",
+                        "name": "group_by",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data: array of models.AnalyticsTotal",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cashback/balances/export": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Stream every user's cashback balance at as_of as CSV, for month-end closing",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export balances as of a point in time",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "2024-03-31",
+                        "description": "Point in time (RFC 3339, or YYYY-MM-DD for the end of that day)",
+                        "name": "as_of",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone for a date-only as_of (default server TIMEZONE)",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cashback/{turon_user_id}/adjust": {
+            "post": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Post a signed manual adjustment through the cashback queue. Adjustments above the approval threshold are stored as pending_approval (202) until a second operator approves them",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Manual balance adjustment",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Adjustment",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AdjustmentRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackAdjustment"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackAdjustment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackAdjustment"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cashback/{turon_user_id}/close": {
+            "post": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Pay out or forfeit the remaining balance with a history entry, then close and soft-delete the account. A closed account accepts no further operations",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Close account",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason and disposition",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CloseAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountClosure"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cashback/{turon_user_id}/freeze": {
+            "post": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Stop all debits on the account while it is investigated; with block_credits, credits are rejected too",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Freeze account",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Cashback"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/cashback/{turon_user_id}/unfreeze": {
+            "post": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Return a frozen account to active",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Unfreeze account",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Reason; block_credits is ignored",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AccountStatusRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Cashback"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/history/export": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Stream every user's cashback history over a date range as CSV or XLSX. Accepts the same filters as the history endpoint; from_date and to_date are required",
+                "produces": [
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Export cashback history of all users",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-01",
+                        "description": "Start: RFC 3339 timestamp or YYYY-MM-DD date",
+                        "name": "from_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-20",
+                        "description": "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)",
+                        "name": "to_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone for date-only bounds (default server TIMEZONE)",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "credit",
+                                "debit",
+                                "opening",
+                                "adjustment",
+                                "expiry",
+                                "payout",
+                                "forfeiture",
+                                "legacy"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Operation types",
+                        "name": "operation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Source slugs",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Minimum amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Maximum amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Free-text search in the reason",
+                        "name": "reason",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "amount"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Sort field",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "sort_order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliation": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Compare stored balances with the signed sum of history postings",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Balance reconciliation report",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "First turon user ID of the range",
+                        "name": "from_turon_user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Last turon user ID of the range",
+                        "name": "to_turon_user_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Report format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReconciliationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reconciliation/repair": {
+            "post": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Reconcile balances and write an adjustment history entry for every discrepancy",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Repair balances",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "First turon user ID of the range",
+                        "name": "from_turon_user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Last turon user ID of the range",
+                        "name": "to_turon_user_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Report format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ReconciliationReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List webhook subscriptions",
+                "responses": {
+                    "200": {
+                        "description": "data: array of subscriptions",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Register a URL for cashback.credited, cashback.debited, cashback.debit_rejected, source.created and account.closed events, of one source or of all sources when source is empty. Deliveries are signed with the returned secret: X-Cashback-Signature is sha256= followed by the hex HMAC-SHA256 of X-Cashback-Timestamp, a dot and the body. The secret is not shown again",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Subscribe to webhook events",
+                "parameters": [
+                    {
+                        "description": "Subscription",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.WebhookSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Deliveries that exhausted their attempts or whose subscription was deleted, most recent first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "List dead-lettered webhook deliveries",
+                "parameters": [
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data: array of deliveries, pagination: pagination info",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/deliveries/{id}/redeliver": {
+            "post": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Queue a delivery again with a fresh attempt budget",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver a webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "OperatorToken": []
+                    }
+                ],
+                "description": "Stop delivering to the subscription; its pending deliveries move to the dead-letter list",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete a webhook subscription",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/balances": {
+            "post": {
+                "description": "Balances of many users in one call. Users without a cashback wallet are listed in missing",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Batch balance lookup",
+                "parameters": [
+                    {
+                        "description": "Users to look up, at most 500",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BalanceLookupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.BalanceLookupResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/bulk": {
+            "post": {
+                "description": "Credit many users in one batch from a JSON array or a CSV file (header: turon_user_id,cashback_amount[,reason]), sent as the body or as the multipart field \"file\". All rows are validated and stored, then posted in the background; poll the Location for the outcome. Resubmitting the same batch_id resumes the batch: posted rows are skipped and the others retried",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Bulk cashback credit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "partner-2024-03",
+                        "description": "Client batch ID; generated when omitted",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Rows, when sending JSON",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BulkCreditRow"
+                            }
+                        }
+                    },
+                    {
+                        "type": "file",
+                        "description": "CSV file, when uploading",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackBatch"
+                        }
+                    },
+                    "400": {
+                        "description": "error, and rows: array of models.BulkRowError for row-level problems",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Too many batches pending; retry later",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/bulk/{batch_id}": {
+            "get": {
+                "description": "Status of a bulk credit batch and the outcome of every row",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Bulk cashback credit report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "batch_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackBatch"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/decrease": {
+            "post": {
+                "description": "Cashback amount decrease of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Cashback amount decrease",
+                "parameters": [
+                    {
+                        "description": "Cashback amount decrease ",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client key; a repeated request with the same key posts nothing and succeeds again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Accept the operation and process it in the background; poll /operations/{id} for the outcome",
+                        "name": "async",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Account frozen or closed, insufficient cashback, or Idempotency-Key reused for a different request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Too many async operations pending; retry later",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/increase": {
+            "post": {
+                "description": "Increase cashback of the user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Cashback increase",
+                "parameters": [
+                    {
+                        "description": "Cashback increase",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client key; a repeated request with the same key posts nothing and succeeds again",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Accept the operation and process it in the background; poll /operations/{id} for the outcome",
+                        "name": "async",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Account frozen or closed, or Idempotency-Key reused for a different request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Too many async operations pending; retry later",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/{turon_user_id}": {
+            "get": {
+                "description": "Cashback amount and account status of the user. A user who never earned cashback gets a zero balance with status none; 404 is only returned for an erased account. With as_of, returns models.CashbackBalance computed from history up to that instant",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "GET Cashback",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-31T23:59:59Z",
+                        "description": "Point in time (RFC 3339, or YYYY-MM-DD for the end of that day)",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone for a date-only as_of (default server TIMEZONE)",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Cashback"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/{turon_user_id}/history": {
+            "get": {
+                "description": "Get cashback history with optional filtering, sorting and pagination",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "CashbackHistory of the user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-01",
+                        "description": "Start: RFC 3339 timestamp or YYYY-MM-DD date",
+                        "name": "from_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-20",
+                        "description": "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)",
+                        "name": "to_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone for date-only bounds (default server TIMEZONE)",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "credit",
+                                "debit",
+                                "opening",
+                                "adjustment",
+                                "expiry",
+                                "payout",
+                                "forfeiture",
+                                "legacy"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Operation types",
+                        "name": "operation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Source slugs",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Minimum amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Maximum amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Free-text search in the reason",
+                        "name": "reason",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "amount"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Sort field",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "sort_order",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "offset",
+                            "cursor"
+                        ],
+                        "type": "string",
+                        "default": "offset",
+                        "description": "Pagination mode",
+                        "name": "pagination",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from next_cursor or prev_cursor; implies cursor mode",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count matching rows (default true in offset mode, false in cursor mode)",
+                        "name": "include_total",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (offset mode)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data: array of models.CashbackHistoryV1, pagination: pagination info",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/{turon_user_id}/history/export": {
+            "get": {
+                "description": "Stream the user's cashback history as CSV or XLSX. Accepts the same filters as the history endpoint",
+                "produces": [
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Export cashback history of the user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "default": "csv",
+                        "description": "File format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-01",
+                        "description": "Start: RFC 3339 timestamp or YYYY-MM-DD date",
+                        "name": "from_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-20",
+                        "description": "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)",
+                        "name": "to_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone for date-only bounds (default server TIMEZONE)",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "credit",
+                                "debit",
+                                "opening",
+                                "adjustment",
+                                "expiry",
+                                "payout",
+                                "forfeiture",
+                                "legacy"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Operation types",
+                        "name": "operation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Source slugs",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Minimum amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Maximum amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Free-text search in the reason",
+                        "name": "reason",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "amount"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Sort field",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "sort_order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/{turon_user_id}/statements/{period}": {
+            "get": {
+                "description": "Statement of the user for one month: opening balance, every credit, debit, expiry and adjustment with its source and reason, and closing balance. Returns HTML when format=html or the client accepts text/html",
+                "produces": [
+                    "application/json",
+                    "text/html"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Monthly cashback statement",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03",
+                        "description": "Month, YYYY-MM",
+                        "name": "period",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone of the month boundaries (default server TIMEZONE)",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "html"
+                        ],
+                        "type": "string",
+                        "default": "json",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Statement"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/cashback/{turon_user_id}/stream": {
+            "get": {
+                "description": "Server-Sent Events stream of the user's cashback. A balance event with the current balance is sent on connect and after every change, preceded by one history event per new history entry. Event IDs are history IDs: reconnecting with Last-Event-ID (or last_event_id) replays the entries missed since then",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Stream balance updates",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Same as the Last-Event-ID header, for clients that cannot set it",
+                        "name": "last_event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Stream token of the user; required when API requests are signed",
+                        "name": "token",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Unix time the stream token expires at, at most an hour ahead",
+                        "name": "expires",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "event: history (models.CashbackHistoryV1) and event: balance (models.Cashback)",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Missing, expired or invalid stream token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Answers as long as the process is serving HTTP. Dependencies are checked by /readyz",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operations/{id}": {
+            "get": {
+                "description": "Status of an increase or decrease submitted with async=true: pending, succeeded with the resulting history ID, or failed with the error. Finished operations are kept for the configured retention period",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cashback"
+                ],
+                "summary": "Async operation status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Operation"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks database connectivity, the migration version, queue saturation and that the queue worker is making progress. Fails once graceful shutdown has begun",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Readiness"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/models.Readiness"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/cashback/balances": {
+            "post": {
+                "description": "Balances of many users in one call. Users without a cashback wallet are listed in missing",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Batch balance lookup",
+                "parameters": [
+                    {
+                        "description": "Users to look up, at most 500",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.BalanceLookupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.BalanceLookupResult"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/cashback/bulk": {
+            "post": {
+                "description": "Credit many users in one batch, as /cashback/bulk: rows are stored and posted in the background. Row-level problems come back as validation_failed with the rows in error.details",
+                "consumes": [
+                    "application/json",
+                    "text/csv",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Bulk cashback credit",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "example": "partner-2024-03",
+                        "description": "Client batch ID; generated when omitted",
+                        "name": "batch_id",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Rows, when sending JSON",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BulkCreditRow"
+                            }
+                        }
+                    },
+                    {
+                        "type": "file",
+                        "description": "CSV file, when uploading",
+                        "name": "file",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.CashbackBatch"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "error": {
+                                            "allOf": [
+                                                {
+                                                    "$ref": "#/definitions/models.APIError"
+                                                },
+                                                {
+                                                    "type": "object",
+                                                    "properties": {
+                                                        "details": {
+                                                            "type": "array",
+                                                            "items": {
+                                                                "$ref": "#/definitions/models.BulkRowError"
+                                                            }
+                                                        }
+                                                    }
+                                                }
+                                            ]
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "503": {
+                        "description": "unavailable: too many batches pending; retry later",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/cashback/bulk/{batch_id}": {
+            "get": {
+                "description": "Status of a bulk credit batch and the outcome of every row",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Bulk cashback credit report",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Batch ID",
+                        "name": "batch_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.CashbackBatch"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/cashback/decrease": {
+            "post": {
+                "description": "Decrease cashback of the user. Returns the resulting balance and history entry; with async=true, the pending operation",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Cashback decrease",
+                "parameters": [
+                    {
+                        "description": "Cashback decrease",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client key; a repeated request with the same key posts nothing and returns the original history entry with replayed=true",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Accept the operation and process it in the background; poll /v2/operations/{id} for the outcome",
+                        "name": "async",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.CashbackMutation"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Operation"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "409": {
+                        "description": "account_frozen, account_closed, insufficient_cashback or idempotency_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "503": {
+                        "description": "unavailable: too many async operations pending; retry later",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/cashback/increase": {
+            "post": {
+                "description": "Increase cashback of the user. Returns the resulting balance and history entry; with async=true, the pending operation",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Cashback increase",
+                "parameters": [
+                    {
+                        "description": "Cashback increase",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.CashbackRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client key; a repeated request with the same key posts nothing and returns the original history entry with replayed=true",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Accept the operation and process it in the background; poll /v2/operations/{id} for the outcome",
+                        "name": "async",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.CashbackMutation"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Operation"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "409": {
+                        "description": "account_frozen, account_closed or idempotency_conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "503": {
+                        "description": "unavailable: too many async operations pending; retry later",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/cashback/{turon_user_id}": {
+            "get": {
+                "description": "Cashback amount and account status of the user; with as_of, the balance at that instant",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Get cashback",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-31T23:59:59Z",
+                        "description": "Point in time (RFC 3339, or YYYY-MM-DD for the end of that day)",
+                        "name": "as_of",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone for a date-only as_of (default server TIMEZONE)",
+                        "name": "tz",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Cashback"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "404": {
+                        "description": "account_erased",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/cashback/{turon_user_id}/history": {
+            "get": {
+                "description": "History with the filters, sorting and pagination of /cashback/{turon_user_id}/history",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Cashback history of the user",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Turon User ID",
+                        "name": "turon_user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-01",
+                        "description": "Start: RFC 3339 timestamp or YYYY-MM-DD date",
+                        "name": "from_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "2024-03-20",
+                        "description": "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)",
+                        "name": "to_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "example": "Asia/Tashkent",
+                        "description": "IANA time zone for date-only bounds (default server TIMEZONE)",
+                        "name": "tz",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "enum": [
+                                "credit",
+                                "debit",
+                                "opening",
+                                "adjustment",
+                                "expiry",
+                                "payout",
+                                "forfeiture",
+                                "legacy"
+                            ],
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Operation types",
+                        "name": "operation",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Source slugs",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Minimum amount",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "number",
+                        "description": "Maximum amount",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Free-text search in the reason",
+                        "name": "reason",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "amount"
+                        ],
+                        "type": "string",
+                        "default": "created_at",
+                        "description": "Sort field",
+                        "name": "sort_by",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "default": "desc",
+                        "description": "Sort direction",
+                        "name": "sort_order",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "offset",
+                            "cursor"
+                        ],
+                        "type": "string",
+                        "default": "offset",
+                        "description": "Pagination mode",
+                        "name": "pagination",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from next_cursor or prev_cursor; implies cursor mode",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Count matching rows (default true in offset mode, false in cursor mode)",
+                        "name": "include_total",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 1,
+                        "description": "Page number (offset mode)",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Items per page",
+                        "name": "page_size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.HistoryPage"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/operations/{id}": {
+            "get": {
+                "description": "Status of an increase or decrease submitted with async=true",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "v2"
+                ],
+                "summary": "Async operation status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Operation ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/models.Envelope"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/models.Operation"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "models.APIError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "invalid_argument"
+                },
+                "details": {
+                    "type": "object"
+                },
+                "message": {
+                    "type": "string",
+                    "example": "turon_user_id must be positive"
+                }
+            }
+        },
+        "models.AccountClosure": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/models.Cashback"
+                },
+                "settlement": {
+                    "$ref": "#/definitions/models.CashbackHistory"
+                }
+            }
+        },
+        "models.AccountStatusRequest": {
+            "type": "object",
+            "properties": {
+                "block_credits": {
+                    "description": "BlockCredits makes a freeze reject credits too; debits are always\nrejected.",
+                    "type": "boolean",
+                    "example": false
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "fraud investigation #881"
+                }
+            }
+        },
+        "models.AdjustmentDecision": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string",
+                    "example": "Checked against order log"
+                }
+            }
+        },
+        "models.AdjustmentRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": -25
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "Duplicate credit for order 1042"
+                },
+                "reason_code": {
+                    "type": "string",
+                    "example": "correction"
+                }
+            }
+        },
+        "models.BalanceDiscrepancy": {
+            "type": "object",
+            "properties": {
+                "adjustment_history_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "cashback_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "difference": {
+                    "type": "number",
+                    "example": 10
+                },
+                "recomputed_balance": {
+                    "type": "number",
+                    "example": 90.5
+                },
+                "repair_error": {
+                    "type": "string",
+                    "example": ""
+                },
+                "repaired": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "stored_balance": {
+                    "type": "number",
+                    "example": 100.5
+                },
+                "turon_user_id": {
+                    "type": "integer",
+                    "example": 123
+                }
+            }
+        },
+        "models.BalanceLookupRequest": {
+            "type": "object",
+            "properties": {
+                "turon_user_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        123,
+                        456
+                    ]
+                }
+            }
+        },
+        "models.BalanceLookupResult": {
+            "type": "object",
+            "properties": {
+                "balances": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Cashback"
+                    }
+                },
+                "missing": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        456
+                    ]
+                }
+            }
+        },
+        "models.BatchItem": {
+            "type": "object",
+            "properties": {
+                "cashback_amount": {
+                    "type": "number",
+                    "example": 25
+                },
+                "error": {
+                    "type": "string",
+                    "example": "source not found"
+                },
+                "history_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "partner_payout"
+                },
+                "row": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "status": {
+                    "type": "string",
+                    "example": "succeeded"
+                },
+                "turon_user_id": {
+                    "type": "integer",
+                    "example": 123
+                }
+            }
+        },
+        "models.BulkCreditRow": {
+            "type": "object",
+            "properties": {
+                "cashback_amount": {
+                    "type": "number",
+                    "example": 25
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "partner_payout"
+                },
+                "turon_user_id": {
+                    "type": "integer",
+                    "example": 123
+                }
+            }
+        },
+        "models.BulkRowError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "cashback_amount must be positive"
+                },
+                "row": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "models.Cashback": {
+            "type": "object",
+            "properties": {
+                "cashback_amount": {
+                    "type": "number",
+                    "example": 100.5
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "example": "null"
+                },
+                "freeze_credits": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "none",
+                        "active",
+                        "frozen",
+                        "closed"
+                    ],
+                    "example": "active"
+                },
+                "status_changed_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                },
+                "status_reason": {
+                    "type": "string",
+                    "example": "fraud investigation"
+                },
+                "turon_user_id": {
+                    "type": "integer",
+                    "example": 123
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                }
+            }
+        },
+        "models.CashbackAdjustment": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": -25
+                },
+                "approved_by": {
+                    "type": "string",
+                    "example": "support.bob"
+                },
+                "comment": {
+                    "type": "string",
+                    "example": "Duplicate credit for order 1042"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                },
+                "decided_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:05:00Z"
+                },
+                "decision_comment": {
+                    "type": "string",
+                    "example": "Checked against order log"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "insufficient cashback amount"
+                },
+                "history_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "host_ip": {
+                    "type": "string",
+                    "example": "192.168.1.1"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "reason_code": {
+                    "type": "string",
+                    "example": "correction"
+                },
+                "rejected_by": {
+                    "type": "string",
+                    "example": "support.bob"
+                },
+                "requested_by": {
+                    "type": "string",
+                    "example": "support.alice"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "posted"
+                },
+                "turon_user_id": {
+                    "type": "integer",
+                    "example": 123
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                }
+            }
+        },
+        "models.CashbackBatch": {
+            "type": "object",
+            "properties": {
+                "batch_id": {
+                    "type": "string",
+                    "example": "partner-2024-03"
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                },
+                "failed": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BatchItem"
+                    }
+                },
+                "pending": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "status": {
+                    "type": "string",
+                    "example": "completed"
+                },
+                "succeeded": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:02:00Z"
+                }
+            }
+        },
+        "models.CashbackHistory": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": -50.25
+                },
+                "balance_after": {
+                    "type": "number",
+                    "example": 100.5
+                },
+                "cashback_amount": {
+                    "type": "number",
+                    "example": 50.25
+                },
+                "cashback_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                },
+                "deleted_at": {
+                    "type": "string",
+                    "example": "null"
+                },
+                "host_ip": {
+                    "type": "string",
+                    "example": "192.168.1.1"
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "credit"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "purchase"
+                },
+                "source_slug": {
+                    "type": "string",
+                    "example": "turon"
+                },
+                "turon_user_id": {
+                    "type": "integer",
+                    "example": 123
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                }
+            }
+        },
+        "models.CashbackMutation": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "$ref": "#/definitions/models.Cashback"
+                },
+                "history": {
+                    "$ref": "#/definitions/models.CashbackHistory"
+                },
+                "replayed": {
+                    "description": "Replayed is set when the request repeated an Idempotency-Key and\nnothing new was posted.",
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.CashbackRequest": {
+            "type": "object",
+            "properties": {
+                "cashback_amount": {
+                    "type": "number"
+                },
+                "host_ip": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "turon_user_id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "Deprecated: use Reason. Still accepted when Reason is empty.",
+                    "type": "string"
+                }
+            }
+        },
+        "models.CloseAccountRequest": {
+            "type": "object",
+            "properties": {
+                "disposition": {
+                    "type": "string",
+                    "enum": [
+                        "payout",
+                        "forfeit"
+                    ],
+                    "example": "payout"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "customer request"
+                }
+            }
+        },
+        "models.Envelope": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "object"
+                },
+                "error": {
+                    "$ref": "#/definitions/models.APIError"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "4f1c2a9e0b7d4e3f8a6b5c4d3e2f1a0b"
+                }
+            }
+        },
+        "models.HealthCheck": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "error": {
+                    "type": "string",
+                    "example": "database is unreachable"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "ok",
+                        "failed"
+                    ],
+                    "example": "ok"
+                }
+            }
+        },
+        "models.HistoryPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.CashbackHistory"
+                    }
+                },
+                "pagination": {
+                    "type": "object"
+                }
+            }
+        },
+        "models.Operation": {
+            "type": "object",
+            "properties": {
+                "cashback_amount": {
+                    "type": "number",
+                    "example": 25
+                },
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                },
+                "error": {
+                    "type": "string",
+                    "example": "insufficient cashback amount"
+                },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:01Z"
+                },
+                "history_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0f8fad5b-d9cb-469f-a165-70867728950e"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "succeeded"
+                },
+                "turon_user_id": {
+                    "type": "integer",
+                    "example": 123
+                },
+                "type": {
+                    "type": "string",
+                    "example": "increase"
+                },
+                "updated_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:01Z"
+                }
+            }
+        },
+        "models.Readiness": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.HealthCheck"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "ready",
+                        "not_ready"
+                    ],
+                    "example": "ready"
+                }
+            }
+        },
+        "models.ReconciliationReport": {
+            "type": "object",
+            "properties": {
+                "discrepancies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.BalanceDiscrepancy"
+                    }
+                },
+                "finished_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:05Z"
+                },
+                "from_turon_user_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "repair": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "scanned": {
+                    "type": "integer",
+                    "example": 1000
+                },
+                "started_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                },
+                "to_turon_user_id": {
+                    "type": "integer",
+                    "example": 1000
+                }
+            }
+        },
+        "models.Statement": {
+            "type": "object",
+            "properties": {
+                "cashback_id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "closing_balance": {
+                    "type": "number",
+                    "example": 130
+                },
+                "generated_at": {
+                    "type": "string",
+                    "example": "2024-04-01T00:05:00+05:00"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.StatementLine"
+                    }
+                },
+                "opening_balance": {
+                    "type": "number",
+                    "example": 100
+                },
+                "period": {
+                    "type": "string",
+                    "example": "2024-03"
+                },
+                "period_end": {
+                    "type": "string",
+                    "example": "2024-04-01T00:00:00+05:00"
+                },
+                "period_start": {
+                    "type": "string",
+                    "example": "2024-03-01T00:00:00+05:00"
+                },
+                "time_zone": {
+                    "type": "string",
+                    "example": "Asia/Tashkent"
+                },
+                "total_adjusted": {
+                    "type": "number",
+                    "example": 0
+                },
+                "total_credited": {
+                    "type": "number",
+                    "example": 50
+                },
+                "total_debited": {
+                    "type": "number",
+                    "example": 20
+                },
+                "total_expired": {
+                    "type": "number",
+                    "example": 0
+                },
+                "turon_user_id": {
+                    "type": "integer",
+                    "example": 123
+                }
+            }
+        },
+        "models.StatementLine": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 50
+                },
+                "balance_after": {
+                    "type": "number",
+                    "example": 150
+                },
+                "date": {
+                    "type": "string",
+                    "example": "2024-03-05T14:20:00+05:00"
+                },
+                "history_id": {
+                    "type": "integer",
+                    "example": 42
+                },
+                "operation": {
+                    "type": "string",
+                    "example": "credit"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "purchase"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "turon"
+                }
+            }
+        },
+        "models.WebhookSubscription": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string",
+                    "example": "2024-03-20T10:00:00Z"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "cashback.credited",
+                        "cashback.debited"
+                    ]
+                },
+                "id": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "secret": {
+                    "description": "Secret signs the deliveries. It is only returned when the subscription\nis created.",
+                    "type": "string",
+                    "example": "whsec_6f1c..."
+                },
+                "source": {
+                    "type": "string",
+                    "example": "turon"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://partner.example.com/hooks/cashback"
+                }
+            }
+        },
+        "models.WebhookSubscriptionRequest": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "cashback.credited",
+                        "cashback.debited"
+                    ]
+                },
+                "source": {
+                    "type": "string",
+                    "example": "turon"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://partner.example.com/hooks/cashback"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "OperatorToken": {
+            "description": "\"Bearer \u003ctoken\u003e\" of the operator, from ADMIN_OPERATOR_TOKENS",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
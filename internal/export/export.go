@@ -0,0 +1,99 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// RowWriter writes a table one row at a time, so exports never hold more
+// than the current row in memory. Values may be string, int64, float64,
+// *float64, time.Time or nil.
+type RowWriter interface {
+	WriteRow(values []interface{}) error
+	Close() error
+}
+
+func NewRowWriter(format string, w io.Writer) (RowWriter, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w, "Sheet1")
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+func NewCSVWriter(w io.Writer) RowWriter {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	c.record = c.record[:0]
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			c.record = append(c.record, escapeFormula(s))
+			continue
+		}
+		c.record = append(c.record, formatValue(value))
+	}
+	return c.writer.Write(c.record)
+}
+
+// escapeFormula quotes text that a spreadsheet opening the CSV would
+// otherwise evaluate as a formula. Only text values are escaped; numbers
+// keep their sign.
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', 2, 64)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
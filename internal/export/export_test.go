@@ -0,0 +1,149 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"cashback-serv/internal/export"
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCSVFormulaEscaping(t *testing.T) {
+	amount := -7.5
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{name: "equals", value: "=HYPERLINK(\"http://evil\")", want: "'=HYPERLINK(\"http://evil\")"},
+		{name: "plus", value: "+1+1", want: "'+1+1"},
+		{name: "minus", value: "-1+1", want: "'-1+1"},
+		{name: "at", value: "@SUM(A1:A2)", want: "'@SUM(A1:A2)"},
+		{name: "tab", value: "\t=1", want: "'\t=1"},
+		{name: "carriage return", value: "\r=1", want: "'\r=1"},
+		{name: "inner equals", value: "a=b", want: "a=b"},
+		{name: "plain", value: "purchase", want: "purchase"},
+		{name: "empty", value: "", want: ""},
+		{name: "negative number", value: -12.5, want: "-12.50"},
+		{name: "negative pointer", value: &amount, want: "-7.50"},
+		{name: "int", value: int64(-3), want: "-3"},
+		{name: "nil", value: nil, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := export.NewCSVWriter(&buf)
+			// The second column keeps a row of one empty value from being
+			// read as a blank line.
+			if err := w.WriteRow([]interface{}{tt.value, "end"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			record, err := csv.NewReader(&buf).Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record[0] != tt.want {
+				t.Errorf("got %q, want %q", record[0], tt.want)
+			}
+		})
+	}
+}
+
+// sheet is the part of a worksheet the tests read.
+type sheet struct {
+	Rows []struct {
+		Cells []struct {
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := export.NewRowWriter(export.FormatXLSX, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	amount := 12.25
+	rows := [][]interface{}{
+		{"id", "reason", "amount", "balance_after", "created_at"},
+		{int64(1), "=1+1", -4.5, &amount, time.Date(2024, 3, 20, 10, 0, 0, 0, time.UTC)},
+		{int64(2), "<a & b>", 0.1, (*float64)(nil), nil},
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string]*zip.File{}
+	for _, f := range archive.File {
+		parts[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if parts[name] == nil {
+			t.Errorf("part %s is missing", name)
+		}
+	}
+	if t.Failed() {
+		return
+	}
+
+	f, err := parts["xl/worksheets/sheet1.xml"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got sheet
+	if err := xml.Unmarshal(body, &got); err != nil {
+		t.Fatalf("sheet is not valid XML: %v", err)
+	}
+
+	// Inline strings are never evaluated, so "=1+1" stays unescaped.
+	want := [][]string{
+		{"id", "reason", "amount", "balance_after", "created_at"},
+		{"1", "=1+1", "-4.5", "12.25", "2024-03-20T10:00:00Z"},
+		{"2", "<a & b>", "0.1", "", ""},
+	}
+	if len(got.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(got.Rows), len(want))
+	}
+	for i, row := range got.Rows {
+		if len(row.Cells) != len(want[i]) {
+			t.Fatalf("row %d: got %d cells, want %d", i+1, len(row.Cells), len(want[i]))
+		}
+		for j, cell := range row.Cells {
+			value := cell.Value
+			if cell.Type == "inlineStr" {
+				value = cell.Inline
+			}
+			if value != want[i][j] {
+				t.Errorf("row %d cell %d: got %q, want %q", i+1, j+1, value, want[i][j])
+			}
+		}
+	}
+	if got.Rows[1].Cells[2].Type != "" {
+		t.Errorf("amount is stored as %q, want a number", got.Rows[1].Cells[2].Type)
+	}
+}
@@ -0,0 +1,147 @@
+package grpcserver
+
+import (
+	cashbackv1 "cashback-serv/api/cashback/v1"
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"context"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements cashbackv1.CashbackServiceServer on top of the same
+// CashbackService the REST handlers use.
+type Server struct {
+	cashbackv1.UnimplementedCashbackServiceServer
+
+	service *service.CashbackService
+}
+
+// New returns a gRPC server with the cashback service registered. Calls must
+// carry one of tokens as a bearer token; with no tokens, authentication is
+// off.
+func New(cashbackService *service.CashbackService, tokens []string) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			unaryRequestID,
+			unaryAuth(tokens),
+			unaryErrors,
+		),
+		grpc.ChainStreamInterceptor(
+			streamRequestID,
+			streamAuth(tokens),
+			streamErrors,
+		),
+	)
+	cashbackv1.RegisterCashbackServiceServer(server, &Server{service: cashbackService})
+	return server
+}
+
+func (s *Server) IncreaseCashback(ctx context.Context, req *cashbackv1.CashbackRequest) (*cashbackv1.CashbackResponse, error) {
+	mutation, err := s.service.IncreaseCashback(ctx, cashbackRequest(ctx, req))
+	if err != nil {
+		return nil, err
+	}
+	return &cashbackv1.CashbackResponse{Message: "Cashback successfully increased", Replayed: mutation.Replayed}, nil
+}
+
+func (s *Server) DecreaseCashback(ctx context.Context, req *cashbackv1.CashbackRequest) (*cashbackv1.CashbackResponse, error) {
+	mutation, err := s.service.DecreaseCashback(ctx, cashbackRequest(ctx, req))
+	if err != nil {
+		return nil, err
+	}
+	return &cashbackv1.CashbackResponse{Message: "Cashback successfully decreased", Replayed: mutation.Replayed}, nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, req *cashbackv1.GetBalanceRequest) (*cashbackv1.Balance, error) {
+	cashback, err := s.service.GetCashbackByUserID(ctx, req.GetTuronUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &cashbackv1.Balance{
+		Id:              cashback.ID,
+		TuronUserId:     cashback.TuronUserID,
+		CashbackAmount:  cashback.CashbackAmount,
+		Status:          cashback.Status,
+		FreezeCredits:   cashback.FreezeCredits,
+		StatusChangedAt: timestamp(cashback.StatusChangedAt),
+		CreatedAt:       timestamp(&cashback.CreatedAt),
+		UpdatedAt:       timestamp(&cashback.UpdatedAt),
+	}
+	if cashback.StatusReason != nil {
+		balance.StatusReason = *cashback.StatusReason
+	}
+	return balance, nil
+}
+
+func (s *Server) ListHistory(req *cashbackv1.ListHistoryRequest, stream grpc.ServerStreamingServer[cashbackv1.HistoryEntry]) error {
+	filter := &models.HistoryFilter{
+		FromDate:   req.GetFromDate(),
+		ToDate:     req.GetToDate(),
+		TimeZone:   req.GetTz(),
+		Operations: req.GetOperations(),
+		Sources:    req.GetSources(),
+		Reason:     req.GetReason(),
+		MinAmount:  req.MinAmount,
+		MaxAmount:  req.MaxAmount,
+		SortBy:     req.GetSortBy(),
+		SortOrder:  req.GetSortOrder(),
+	}
+
+	ctx := stream.Context()
+	return s.service.StreamCashbackHistory(ctx, req.GetTuronUserId(), filter, func(h *models.CashbackHistory) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return stream.Send(&cashbackv1.HistoryEntry{
+			Id:             h.ID,
+			CashbackId:     h.CashbackID,
+			TuronUserId:    h.TuronUserID,
+			SourceSlug:     h.SourceSlug,
+			Operation:      h.Operation,
+			Reason:         h.Reason,
+			CashbackAmount: h.CashbackAmount,
+			Amount:         h.Amount,
+			BalanceAfter:   h.BalanceAfter,
+			HostIp:         h.HostIP,
+			CreatedAt:      timestamppb.New(h.CreatedAt),
+		})
+	})
+}
+
+// cashbackRequest converts req. The host recorded is the caller's address,
+// not the host_ip the client claims.
+func cashbackRequest(ctx context.Context, req *cashbackv1.CashbackRequest) *models.CashbackRequest {
+	return &models.CashbackRequest{
+		TuronUserID:    req.GetTuronUserId(),
+		CashbackAmount: req.GetCashbackAmount(),
+		HostIP:         peerIP(ctx),
+		Reason:         req.GetReason(),
+		IdempotencyKey: req.GetIdempotencyKey(),
+	}
+}
+
+// peerIP returns the IP address of the caller, or "" when it is unknown.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
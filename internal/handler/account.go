@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// @Summary Freeze account
+// @Description Stop all debits on the account while it is investigated; with block_credits, credits are rejected too
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param turon_user_id path int true "Turon User ID"
+// @Param request body models.AccountStatusRequest true "Reason"
+// @Success 200 {object} models.Cashback
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/cashback/{turon_user_id}/freeze [post]
+func (h *AdminHandler) FreezeAccount(c *gin.Context) {
+	h.changeAccountStatus(c, h.service.FreezeAccount)
+}
+
+// @Summary Unfreeze account
+// @Description Return a frozen account to active
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param turon_user_id path int true "Turon User ID"
+// @Param request body models.AccountStatusRequest true "Reason; block_credits is ignored"
+// @Success 200 {object} models.Cashback
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/cashback/{turon_user_id}/unfreeze [post]
+func (h *AdminHandler) UnfreezeAccount(c *gin.Context) {
+	h.changeAccountStatus(c, h.service.UnfreezeAccount)
+}
+
+func (h *AdminHandler) changeAccountStatus(c *gin.Context, change func(int64, *models.AccountStatusRequest) (*models.Cashback, error)) {
+	turonUserID, err := strconv.ParseInt(c.Param("turon_user_id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid turon_user_id format"), http.StatusBadRequest)
+		return
+	}
+
+	var req models.AccountStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+	req.Operator = operator(c)
+
+	cashback, err := change(turonUserID, &req)
+	if err != nil {
+		h.handleError(c, err, accountErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, cashback)
+}
+
+// @Summary Close account
+// @Description Pay out or forfeit the remaining balance with a history entry, then close and soft-delete the account. A closed account accepts no further operations
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param turon_user_id path int true "Turon User ID"
+// @Param request body models.CloseAccountRequest true "Reason and disposition"
+// @Success 200 {object} models.AccountClosure
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/cashback/{turon_user_id}/close [post]
+func (h *AdminHandler) CloseAccount(c *gin.Context) {
+	turonUserID, err := strconv.ParseInt(c.Param("turon_user_id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid turon_user_id format"), http.StatusBadRequest)
+		return
+	}
+
+	var req models.CloseAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+	req.Operator = operator(c)
+
+	closure, err := h.service.CloseAccount(c.Request.Context(), turonUserID, &req, c.ClientIP())
+	if err != nil {
+		h.handleError(c, err, accountErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, closure)
+}
+
+func accountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAccountNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidTransition):
+		return http.StatusConflict
+	default:
+		return serviceErrorStatus(err)
+	}
+}
@@ -0,0 +1,377 @@
+package handler
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type AdminHandler struct {
+	service *service.CashbackService
+}
+
+func NewAdminHandler(service *service.CashbackService) *AdminHandler {
+	return &AdminHandler{service: service}
+}
+
+func (h *AdminHandler) RegisterRoutes(router *gin.Engine) {
+	admin := router.Group("/admin")
+	{
+		admin.GET("/reconciliation", h.GetReconciliationReport)
+		admin.POST("/reconciliation/repair", h.RepairBalances)
+		admin.GET("/cashback/balances/export", h.ExportBalancesAsOf)
+		admin.GET("/history/export", h.ExportAllHistory)
+		admin.POST("/cashback/:turon_user_id/adjust", h.AdjustCashback)
+		admin.POST("/cashback/:turon_user_id/freeze", h.FreezeAccount)
+		admin.POST("/cashback/:turon_user_id/unfreeze", h.UnfreezeAccount)
+		admin.POST("/cashback/:turon_user_id/close", h.CloseAccount)
+		admin.GET("/adjustments", h.ListAdjustments)
+		admin.GET("/adjustments/:id", h.GetAdjustment)
+		admin.POST("/adjustments/:id/approve", h.ApproveAdjustment)
+		admin.POST("/adjustments/:id/reject", h.RejectAdjustment)
+		admin.GET("/analytics/totals", h.GetAnalyticsTotals)
+		admin.GET("/analytics/liability", h.GetLiability)
+		admin.GET("/analytics/top-earners", h.GetTopEarners)
+		admin.GET("/analytics/active-users", h.GetActiveUsers)
+	}
+}
+
+func (h *AdminHandler) handleError(c *gin.Context, err error, status int) {
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// @Summary Balance reconciliation report
+// @Description Compare stored balances with the signed sum of history postings
+// @Tags admin
+// @Produce json
+// @Produce text/csv
+// @Param from_turon_user_id query int false "First turon user ID of the range"
+// @Param to_turon_user_id query int false "Last turon user ID of the range"
+// @Param format query string false "Report format" Enums(json, csv) default(json)
+// @Success 200 {object} models.ReconciliationReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/reconciliation [get]
+func (h *AdminHandler) GetReconciliationReport(c *gin.Context) {
+	h.reconcile(c, false)
+}
+
+// @Summary Repair balances
+// @Description Reconcile balances and write an adjustment history entry for every discrepancy
+// @Tags admin
+// @Produce json
+// @Produce text/csv
+// @Param from_turon_user_id query int false "First turon user ID of the range"
+// @Param to_turon_user_id query int false "Last turon user ID of the range"
+// @Param format query string false "Report format" Enums(json, csv) default(json)
+// @Success 200 {object} models.ReconciliationReport
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/reconciliation/repair [post]
+func (h *AdminHandler) RepairBalances(c *gin.Context) {
+	h.reconcile(c, true)
+}
+
+func (h *AdminHandler) reconcile(c *gin.Context, repair bool) {
+	fromTuronUserID, err := strconv.ParseInt(c.DefaultQuery("from_turon_user_id", "0"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid from_turon_user_id format"), http.StatusBadRequest)
+		return
+	}
+
+	toTuronUserID, err := strconv.ParseInt(c.DefaultQuery("to_turon_user_id", "0"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid to_turon_user_id format"), http.StatusBadRequest)
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		h.handleError(c, errors.New("format must be json or csv"), http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service.Reconcile(fromTuronUserID, toTuronUserID, repair, c.ClientIP())
+	if err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
+		return
+	}
+
+	if format == "csv" {
+		filename := fmt.Sprintf("reconciliation-%s.csv", report.StartedAt.Format("20060102-150405"))
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+		if err := service.WriteReconciliationCSV(c.Writer, report); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Export balances as of a point in time
+// @Description Stream every user's cashback balance at as_of as CSV, for month-end closing
+// @Tags admin
+// @Produce text/csv
+// @Param as_of query string true "Point in time (RFC 3339, or YYYY-MM-DD for the end of that day)" example(2024-03-31)
+// @Param tz query string false "IANA time zone for a date-only as_of (default server TIMEZONE)" example(Asia/Tashkent)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/cashback/balances/export [get]
+func (h *AdminHandler) ExportBalancesAsOf(c *gin.Context) {
+	asOfParam := c.Query("as_of")
+	if asOfParam == "" {
+		h.handleError(c, errors.New("as_of must be provided"), http.StatusBadRequest)
+		return
+	}
+
+	asOf, err := h.service.ParseAsOf(asOfParam, c.Query("tz"))
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("balances-%s.csv", asOf.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Header("Content-Type", "text/csv")
+	c.Status(http.StatusOK)
+	if err := h.service.ExportBalancesAsOf(c.Writer, asOf); err != nil {
+		c.Error(err)
+	}
+}
+
+// @Summary Manual balance adjustment
+// @Description Post a signed manual adjustment through the cashback queue. Adjustments above the approval threshold are stored as pending_approval (202) until a second operator approves them
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param turon_user_id path int true "Turon User ID"
+// @Param request body models.AdjustmentRequest true "Adjustment"
+// @Success 200 {object} models.CashbackAdjustment
+// @Success 202 {object} models.CashbackAdjustment
+// @Failure 400 {object} map[string]string
+// @Failure 422 {object} models.CashbackAdjustment
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/cashback/{turon_user_id}/adjust [post]
+func (h *AdminHandler) AdjustCashback(c *gin.Context) {
+	turonUserID, err := strconv.ParseInt(c.Param("turon_user_id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid turon_user_id format"), http.StatusBadRequest)
+		return
+	}
+
+	var req models.AdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+	req.Operator = operator(c)
+
+	adjustment, err := h.service.RequestAdjustment(turonUserID, &req, c.ClientIP())
+	if err != nil {
+		h.handleError(c, err, adjustmentErrorStatus(err))
+		return
+	}
+
+	h.respondAdjustment(c, adjustment)
+}
+
+// @Summary List manual adjustments
+// @Description List manual adjustments, optionally by status
+// @Tags admin
+// @Produce json
+// @Param status query string false "Adjustment status" Enums(pending_approval, approved, posted, rejected, failed)
+// @Param page query int false "Page number" default(1) minimum(1)
+// @Param page_size query int false "Items per page" default(10) minimum(1) maximum(100)
+// @Success 200 {object} map[string]interface{} "data: array of adjustments, pagination: pagination info"
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/adjustments [get]
+func (h *AdminHandler) ListAdjustments(c *gin.Context) {
+	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 64)
+
+	pagination := &models.Pagination{
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	adjustments, err := h.service.ListAdjustments(c.Query("status"), pagination)
+	if err != nil {
+		h.handleError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       adjustments,
+		"pagination": pagination,
+	})
+}
+
+// @Summary Get a manual adjustment
+// @Tags admin
+// @Produce json
+// @Param id path int true "Adjustment ID"
+// @Success 200 {object} models.CashbackAdjustment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/adjustments/{id} [get]
+func (h *AdminHandler) GetAdjustment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid adjustment id format"), http.StatusBadRequest)
+		return
+	}
+
+	adjustment, err := h.service.GetAdjustment(id)
+	if err != nil {
+		h.handleError(c, err, adjustmentErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustment)
+}
+
+// @Summary Approve a manual adjustment
+// @Description Approve and post a pending adjustment. The approving operator, identified by their token, must differ from the requesting one
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Adjustment ID"
+// @Param request body models.AdjustmentDecision true "Approval"
+// @Success 200 {object} models.CashbackAdjustment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} models.CashbackAdjustment
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/adjustments/{id}/approve [post]
+func (h *AdminHandler) ApproveAdjustment(c *gin.Context) {
+	h.decideAdjustment(c, h.service.ApproveAdjustment)
+}
+
+// @Summary Reject a manual adjustment
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Adjustment ID"
+// @Param request body models.AdjustmentDecision true "Rejection"
+// @Success 200 {object} models.CashbackAdjustment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/adjustments/{id}/reject [post]
+func (h *AdminHandler) RejectAdjustment(c *gin.Context) {
+	h.decideAdjustment(c, h.service.RejectAdjustment)
+}
+
+func (h *AdminHandler) decideAdjustment(c *gin.Context, decide func(int64, *models.AdjustmentDecision) (*models.CashbackAdjustment, error)) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid adjustment id format"), http.StatusBadRequest)
+		return
+	}
+
+	var decision models.AdjustmentDecision
+	if err := c.ShouldBindJSON(&decision); err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+	decision.Operator = operator(c)
+
+	adjustment, err := decide(id, &decision)
+	if err != nil {
+		h.handleError(c, err, adjustmentErrorStatus(err))
+		return
+	}
+
+	h.respondAdjustment(c, adjustment)
+}
+
+func (h *AdminHandler) respondAdjustment(c *gin.Context, adjustment *models.CashbackAdjustment) {
+	switch adjustment.Status {
+	case constants.AdjustmentStatusPendingApproval:
+		c.JSON(http.StatusAccepted, adjustment)
+	case constants.AdjustmentStatusFailed:
+		c.JSON(http.StatusUnprocessableEntity, adjustment)
+	default:
+		c.JSON(http.StatusOK, adjustment)
+	}
+}
+
+func adjustmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidAdjustment):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAdjustmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrAdjustmentNotPending), errors.Is(err, service.ErrSelfApproval):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// @Summary Export cashback history of all users
+// @Description Stream every user's cashback history over a date range as CSV or XLSX. Accepts the same filters as the history endpoint; from_date and to_date are required
+// @Tags admin
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "File format" Enums(csv, xlsx) default(csv)
+// @Param from_date query string false "Start: RFC 3339 timestamp or YYYY-MM-DD date" example(2024-03-01)
+// @Param to_date query string false "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)" example(2024-03-20)
+// @Param tz query string false "IANA time zone for date-only bounds (default server TIMEZONE)" example(Asia/Tashkent)
+// @Param operation query []string false "Operation types" collectionFormat(multi) Enums(credit, debit, opening, adjustment, expiry, payout, forfeiture, legacy)
+// @Param source query []string false "Source slugs" collectionFormat(multi)
+// @Param min_amount query number false "Minimum amount" minimum(0)
+// @Param max_amount query number false "Maximum amount" minimum(0)
+// @Param reason query string false "Free-text search in the reason"
+// @Param sort_by query string false "Sort field" Enums(created_at, amount) default(created_at)
+// @Param sort_order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/history/export [get]
+func (h *AdminHandler) ExportAllHistory(c *gin.Context) {
+	filter, err := historyFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	historyExport, err := h.service.NewBulkHistoryExport(filter, c.Query("format"))
+	if err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
+		return
+	}
+
+	streamHistoryExport(c, historyExport)
+}
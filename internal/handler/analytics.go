@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"cashback-serv/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// @Summary Cashback totals
+// @Description Credited, debited and net cashback per day, week or month, optionally split by source or operation. Served from hourly rollups, so bounds apply at hour granularity
+// @Tags analytics
+// @Produce json
+// @Param from_date query string true "Start: RFC 3339 timestamp or YYYY-MM-DD date" example(2024-03-01)
+// @Param to_date query string true "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)" example(2024-03-31)
+// @Param tz query string false "IANA time zone of the dates and periods (default server TIMEZONE). Zones whose offset is not a whole number of hours are rejected" example(Asia/Tashkent)
+// @Param interval query string false "Period length" Enums(day, week, month) default(day)
+// @Param group_by query string false "Split each period" Enums(source, operation)
+// @Success 200 {object} map[string]interface{} "data: array of models.AnalyticsTotal"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/analytics/totals [get]
+func (h *AdminHandler) GetAnalyticsTotals(c *gin.Context) {
+	filter, err := analyticsFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	totals, err := h.service.GetAnalyticsTotals(filter)
+	if err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": totals})
+}
+
+// @Summary Outstanding cashback liability
+// @Description Cashback outstanding across all wallets at the end of each period
+// @Tags analytics
+// @Produce json
+// @Param from_date query string true "Start: RFC 3339 timestamp or YYYY-MM-DD date" example(2024-01-01)
+// @Param to_date query string true "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)" example(2024-03-31)
+// @Param tz query string false "IANA time zone of the dates and periods (default server TIMEZONE). Zones whose offset is not a whole number of hours are rejected" example(Asia/Tashkent)
+// @Param interval query string false "Period length" Enums(day, week, month) default(day)
+// @Success 200 {object} map[string]interface{} "data: array of models.LiabilityPoint"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/analytics/liability [get]
+func (h *AdminHandler) GetLiability(c *gin.Context) {
+	filter, err := analyticsFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	points, err := h.service.GetLiability(filter)
+	if err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": points})
+}
+
+// @Summary Top earners
+// @Description Users with the most cashback credited in the range
+// @Tags analytics
+// @Produce json
+// @Param from_date query string true "Start: RFC 3339 timestamp or YYYY-MM-DD date" example(2024-03-01)
+// @Param to_date query string true "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)" example(2024-03-31)
+// @Param tz query string false "IANA time zone of the dates (default server TIMEZONE). Zones whose offset is not a whole number of hours are rejected" example(Asia/Tashkent)
+// @Param limit query int false "Number of users" default(10) minimum(1) maximum(1000)
+// @Success 200 {object} map[string]interface{} "data: array of models.TopEarner"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/analytics/top-earners [get]
+func (h *AdminHandler) GetTopEarners(c *gin.Context) {
+	filter, err := analyticsFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	earners, err := h.service.GetTopEarners(filter)
+	if err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": earners})
+}
+
+// @Summary Active users
+// @Description Number of wallets with at least one entry in each period
+// @Tags analytics
+// @Produce json
+// @Param from_date query string true "Start: RFC 3339 timestamp or YYYY-MM-DD date" example(2024-03-01)
+// @Param to_date query string true "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)" example(2024-03-31)
+// @Param tz query string false "IANA time zone of the dates and periods (default server TIMEZONE). Zones whose offset is not a whole number of hours are rejected" example(Asia/Tashkent)
+// @Param interval query string false "Period length" Enums(day, week, month) default(day)
+// @Success 200 {object} map[string]interface{} "data: array of models.ActiveUsersPoint"
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/analytics/active-users [get]
+func (h *AdminHandler) GetActiveUsers(c *gin.Context) {
+	filter, err := analyticsFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	points, err := h.service.GetActiveUsers(filter)
+	if err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": points})
+}
+
+func analyticsFilterFromQuery(c *gin.Context) (*models.AnalyticsFilter, error) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		return nil, errors.New("invalid limit format")
+	}
+
+	return &models.AnalyticsFilter{
+		FromDate: c.Query("from_date"),
+		ToDate:   c.Query("to_date"),
+		TimeZone: c.Query("tz"),
+		Interval: c.Query("interval"),
+		GroupBy:  c.Query("group_by"),
+		Limit:    limit,
+	}, nil
+}
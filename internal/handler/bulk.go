@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// @Summary Bulk cashback credit
+// @Description Credit many users in one batch from a JSON array or a CSV file (header: turon_user_id,cashback_amount[,reason]), sent as the body or as the multipart field "file". All rows are validated and stored, then posted in the background; poll the Location for the outcome. Resubmitting the same batch_id resumes the batch: posted rows are skipped and the others retried
+// @Tags cashback
+// @Accept json
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Param batch_id query string false "Client batch ID; generated when omitted" example(partner-2024-03)
+// @Param request body []models.BulkCreditRow false "Rows, when sending JSON"
+// @Param file formData file false "CSV file, when uploading"
+// @Success 202 {object} models.CashbackBatch
+// @Failure 400 {object} map[string]interface{} "error, and rows: array of models.BulkRowError for row-level problems"
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string "Too many batches pending; retry later"
+// @Router /cashback/bulk [post]
+func (h *CashbackHandler) BulkCredit(c *gin.Context) {
+	rows, err := bulkRowsFromRequest(c)
+	if err != nil {
+		// Anything that goes wrong reading the upload is the client's.
+		var validationErr *service.BulkValidationError
+		if errors.As(err, &validationErr) {
+			h.handleBulkError(c, err)
+		} else {
+			h.handleError(c, err, http.StatusBadRequest)
+		}
+		return
+	}
+
+	batch, err := h.service.BulkCredit(c.Request.Context(), c.Query("batch_id"), rows, c.ClientIP())
+	if err != nil {
+		h.handleBulkError(c, err)
+		return
+	}
+
+	c.Header("Location", "/cashback/bulk/"+url.PathEscape(batch.ID))
+	c.JSON(http.StatusAccepted, batch)
+}
+
+// @Summary Bulk cashback credit report
+// @Description Status of a bulk credit batch and the outcome of every row
+// @Tags cashback
+// @Produce json
+// @Param batch_id path string true "Batch ID"
+// @Success 200 {object} models.CashbackBatch
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cashback/bulk/{batch_id} [get]
+func (h *CashbackHandler) GetBatch(c *gin.Context) {
+	batch, err := h.service.GetBatch(c.Request.Context(), c.Param("batch_id"))
+	if err != nil {
+		h.handleBulkError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, batch)
+}
+
+func bulkRowsFromRequest(c *gin.Context) ([]models.BulkCreditRow, error) {
+	contentType := c.ContentType()
+	switch {
+	case contentType == gin.MIMEMultipartPOSTForm:
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("file must be provided")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		return service.ParseBulkCSV(file)
+	case strings.HasSuffix(contentType, "/csv"):
+		return service.ParseBulkCSV(c.Request.Body)
+	default:
+		var rows []models.BulkCreditRow
+		if err := c.ShouldBindJSON(&rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+}
+
+func (h *CashbackHandler) handleBulkError(c *gin.Context, err error) {
+	var validationErr *service.BulkValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "rows": validationErr.Rows})
+	case errors.Is(err, service.ErrBatchNotFound):
+		h.handleError(c, err, http.StatusNotFound)
+	case errors.Is(err, service.ErrBatchConflict):
+		h.handleError(c, err, http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidArgument):
+		h.handleError(c, err, http.StatusBadRequest)
+	case errors.Is(err, service.ErrTooManyOperations):
+		h.handleError(c, err, http.StatusServiceUnavailable)
+	default:
+		h.handleError(c, err, http.StatusInternalServerError)
+	}
+}
@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"bytes"
+	constants "cashback-serv/const"
 	"cashback-serv/internal/service"
 	"cashback-serv/models"
+	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
@@ -18,20 +23,54 @@ import (
 
 type CashbackHandler struct {
 	service *service.CashbackService
+	// streams is cancelled by CloseStreams to end every open balance stream.
+	streams      context.Context
+	closeStreams context.CancelFunc
+	// streamSecrets, when set, require a stream token to open a stream.
+	streamSecrets []string
 }
 
 func NewCashbackHandler(service *service.CashbackService) *CashbackHandler {
-	return &CashbackHandler{service: service}
+	streams, closeStreams := context.WithCancel(context.Background())
+	return &CashbackHandler{service: service, streams: streams, closeStreams: closeStreams}
 }
 
+// SetStreamSecrets requires balance streams to be opened with a stream token
+// signed with one of secrets (see package signature).
+func (h *CashbackHandler) SetStreamSecrets(secrets []string) {
+	h.streamSecrets = secrets
+}
+
+// CloseStreams ends every open balance stream, so a graceful shutdown does
+// not wait on them. Clients reconnect with Last-Event-ID and miss nothing.
+func (h *CashbackHandler) CloseStreams() {
+	h.closeStreams()
+}
+
+// RegisterRoutes serves the original routes both unprefixed and under /v1,
+// and the enveloped API under /v2.
 func (h *CashbackHandler) RegisterRoutes(router *gin.Engine) {
+	h.registerV1(router.Group(""))
+	h.registerV1(router.Group("/v1"))
+	h.registerV2(router.Group("/v2"))
+}
+
+func (h *CashbackHandler) registerV1(router *gin.RouterGroup) {
 	cashback := router.Group("/cashback")
 	{
 		cashback.POST("/increase", h.IncreaseCashback)
 		cashback.POST("/decrease", h.DecreaseCashback)
+		cashback.POST("/bulk", h.BulkCredit)
+		cashback.POST("/balances", h.GetBalances)
+		cashback.GET("/bulk/:batch_id", h.GetBatch)
 		cashback.GET("/:turon_user_id", h.GetCashback)
 		cashback.GET("/:turon_user_id/history", h.GetCashbackHistory)
+		cashback.GET("/:turon_user_id/history/export", h.ExportCashbackHistory)
+		cashback.GET("/:turon_user_id/statements/:period", h.GetStatement)
+		cashback.GET("/:turon_user_id/stream", h.StreamCashback)
 	}
+
+	router.GET("/operations/:id", h.GetOperation)
 }
 
 func (h *CashbackHandler) handleError(c *gin.Context, err error, status int) {
@@ -44,9 +83,14 @@ func (h *CashbackHandler) handleError(c *gin.Context, err error, status int) {
 // @Accept json
 // @Produce json
 // @Param request body models.CashbackRequest true "Cashback increase"
+// @Param Idempotency-Key header string false "Client key; a repeated request with the same key posts nothing and succeeds again"
+// @Param async query bool false "Accept the operation and process it in the background; poll /operations/{id} for the outcome"
 // @Success 200 {object} map[string]string
+// @Success 202 {object} models.Operation
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string "Account frozen or closed, or Idempotency-Key reused for a different request"
 // @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string "Too many async operations pending; retry later"
 // @Router /cashback/increase [post]
 func (h *CashbackHandler) IncreaseCashback(c *gin.Context) {
 	var req models.CashbackRequest
@@ -54,9 +98,20 @@ func (h *CashbackHandler) IncreaseCashback(c *gin.Context) {
 		h.handleError(c, err, http.StatusBadRequest)
 		return
 	}
+	req.IdempotencyKey = c.GetHeader(IdempotencyKeyHeader)
 
-	if err := h.service.IncreaseCashback(&req); err != nil {
-		h.handleError(c, err, http.StatusInternalServerError)
+	async, err := strconv.ParseBool(c.DefaultQuery("async", "false"))
+	if err != nil {
+		h.handleError(c, errors.New("invalid async format"), http.StatusBadRequest)
+		return
+	}
+	if async {
+		h.submitOperation(c, constants.Increase, &req)
+		return
+	}
+
+	if _, err := h.service.IncreaseCashback(c.Request.Context(), &req); err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
 		return
 	}
 
@@ -69,9 +124,14 @@ func (h *CashbackHandler) IncreaseCashback(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body models.CashbackRequest true "Cashback amount decrease "
+// @Param Idempotency-Key header string false "Client key; a repeated request with the same key posts nothing and succeeds again"
+// @Param async query bool false "Accept the operation and process it in the background; poll /operations/{id} for the outcome"
 // @Success 200 {object} map[string]string
+// @Success 202 {object} models.Operation
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string "Account frozen or closed, insufficient cashback, or Idempotency-Key reused for a different request"
 // @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string "Too many async operations pending; retry later"
 // @Router /cashback/decrease [post]
 func (h *CashbackHandler) DecreaseCashback(c *gin.Context) {
 	var req models.CashbackRequest
@@ -79,21 +139,98 @@ func (h *CashbackHandler) DecreaseCashback(c *gin.Context) {
 		h.handleError(c, err, http.StatusBadRequest)
 		return
 	}
+	req.IdempotencyKey = c.GetHeader(IdempotencyKeyHeader)
+
+	async, err := strconv.ParseBool(c.DefaultQuery("async", "false"))
+	if err != nil {
+		h.handleError(c, errors.New("invalid async format"), http.StatusBadRequest)
+		return
+	}
+	if async {
+		h.submitOperation(c, constants.Decrease, &req)
+		return
+	}
 
-	if err := h.service.DecreaseCashback(&req); err != nil {
-		h.handleError(c, err, http.StatusInternalServerError)
+	if _, err := h.service.DecreaseCashback(c.Request.Context(), &req); err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Cashback successfully decreased"})
 }
 
+func (h *CashbackHandler) submitOperation(c *gin.Context, opType string, req *models.CashbackRequest) {
+	operation, err := h.service.SubmitCashback(c.Request.Context(), opType, req)
+	if err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
+		return
+	}
+
+	c.Header("Location", "/operations/"+operation.ID)
+	c.JSON(http.StatusAccepted, operation)
+}
+
+// @Summary Async operation status
+// @Description Status of an increase or decrease submitted with async=true: pending, succeeded with the resulting history ID, or failed with the error. Finished operations are kept for the configured retention period
+// @Tags cashback
+// @Produce json
+// @Param id path string true "Operation ID"
+// @Success 200 {object} models.Operation
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /operations/{id} [get]
+func (h *CashbackHandler) GetOperation(c *gin.Context) {
+	operation, err := h.service.GetOperation(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrOperationNotFound) {
+			h.handleError(c, err, http.StatusNotFound)
+			return
+		}
+		h.handleError(c, errors.New("failed to get operation"), http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, operation)
+}
+
+// @Summary Batch balance lookup
+// @Description Balances of many users in one call. Users without a cashback wallet are listed in missing
+// @Tags cashback
+// @Accept json
+// @Produce json
+// @Param request body models.BalanceLookupRequest true "Users to look up, at most 500"
+// @Success 200 {object} models.BalanceLookupResult
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cashback/balances [post]
+func (h *CashbackHandler) GetBalances(c *gin.Context) {
+	var req models.BalanceLookupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.GetCashbacksByUserIDs(c.Request.Context(), req.TuronUserIDs)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArgument) {
+			h.handleError(c, err, http.StatusBadRequest)
+			return
+		}
+		h.handleError(c, errors.New("failed to get cashback data"), http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
 // @Summary GET Cashback
-// @Description Cashback amount of the user
+// @Description Cashback amount and account status of the user. A user who never earned cashback gets a zero balance with status none; 404 is only returned for an erased account. With as_of, returns models.CashbackBalance computed from history up to that instant
 // @Tags cashback
 // @Accept json
 // @Produce json
 // @Param turon_user_id path int true "Turon User ID"
+// @Param as_of query string false "Point in time (RFC 3339, or YYYY-MM-DD for the end of that day)" example(2024-03-31T23:59:59Z)
+// @Param tz query string false "IANA time zone for a date-only as_of (default server TIMEZONE)" example(Asia/Tashkent)
 // @Success 200 {object} models.Cashback
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
@@ -105,31 +242,72 @@ func (h *CashbackHandler) GetCashback(c *gin.Context) {
 		h.handleError(c, errors.New("invalid turon_user_id format"), http.StatusBadRequest)
 		return
 	}
-	cashback, err := h.service.GetCashbackByUserID(turonUserID)
-	if err != nil {
-		h.handleError(c, errors.New("failed to get cashback data"), http.StatusInternalServerError)
+
+	if asOfParam := c.Query("as_of"); asOfParam != "" {
+		h.getCashbackAsOf(c, turonUserID, asOfParam)
 		return
 	}
 
-	if cashback == nil {
-		h.handleError(c, errors.New("Cashback not found"), http.StatusNotFound)
+	cashback, err := h.service.GetCashbackByUserID(c.Request.Context(), turonUserID)
+	if err != nil {
+		h.handleAccountError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, cashback)
 }
 
+func (h *CashbackHandler) getCashbackAsOf(c *gin.Context, turonUserID int64, asOfParam string) {
+	asOf, err := h.service.ParseAsOf(asOfParam, c.Query("tz"))
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	balance, err := h.service.GetCashbackBalanceAsOf(c.Request.Context(), turonUserID, asOf)
+	if err != nil {
+		h.handleAccountError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, balance)
+}
+
+// handleAccountError answers 400 for an invalid user ID and 404 for an
+// erased account. A user without a wallet is not an error.
+func (h *CashbackHandler) handleAccountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		h.handleError(c, err, http.StatusBadRequest)
+	case errors.Is(err, service.ErrAccountErased):
+		h.handleError(c, err, http.StatusNotFound)
+	default:
+		h.handleError(c, errors.New("failed to get cashback data"), http.StatusInternalServerError)
+	}
+}
+
 // @Summary CashbackHistory of the user
-// @Description Get cashback history with optional date filtering and pagination
+// @Description Get cashback history with optional filtering, sorting and pagination
 // @Tags cashback
 // @Accept json
 // @Produce json
 // @Param turon_user_id path int true "Turon User ID"
-// @Param from_date query string false "Start date" format(date) example(2024-03-01)
-// @Param to_date query string false "End date" format(date) example(2024-03-20)
-// @Param page query int false "Page number" default(1) minimum(1)
+// @Param from_date query string false "Start: RFC 3339 timestamp or YYYY-MM-DD date" example(2024-03-01)
+// @Param to_date query string false "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)" example(2024-03-20)
+// @Param tz query string false "IANA time zone for date-only bounds (default server TIMEZONE)" example(Asia/Tashkent)
+// @Param operation query []string false "Operation types" collectionFormat(multi) Enums(credit, debit, opening, adjustment, expiry, payout, forfeiture, legacy)
+// @Param source query []string false "Source slugs" collectionFormat(multi)
+// @Param min_amount query number false "Minimum amount" minimum(0)
+// @Param max_amount query number false "Maximum amount" minimum(0)
+// @Param reason query string false "Free-text search in the reason"
+// @Param sort_by query string false "Sort field" Enums(created_at, amount) default(created_at)
+// @Param sort_order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Param pagination query string false "Pagination mode" Enums(offset, cursor) default(offset)
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor; implies cursor mode"
+// @Param include_total query bool false "Count matching rows (default true in offset mode, false in cursor mode)"
+// @Param page query int false "Page number (offset mode)" default(1) minimum(1)
 // @Param page_size query int false "Items per page" default(10) minimum(1) maximum(100)
-// @Success 200 {object} map[string]interface{} "data: array of cashback history, pagination: pagination info"
+// @Success 200 {object} map[string]interface{} "data: array of models.CashbackHistoryV1, pagination: pagination info"
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /cashback/{turon_user_id}/history [get]
@@ -139,26 +317,222 @@ func (h *CashbackHandler) GetCashbackHistory(c *gin.Context) {
 		h.handleError(c, errors.New("invalid user id format"), http.StatusBadRequest)
 		return
 	}
+	if turonUserID <= 0 {
+		h.handleError(c, errors.New("turon_user_id must be positive"), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := historyFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 64)
 
-	fromDate := c.Query("from_date")
-	toDate := c.Query("to_date")
+	cursor := c.Query("cursor")
+	if cursor != "" || c.Query("pagination") == "cursor" {
+		includeTotal, _ := strconv.ParseBool(c.DefaultQuery("include_total", "false"))
+		page := &models.CursorPagination{
+			Cursor:       cursor,
+			PageSize:     pageSize,
+			IncludeTotal: includeTotal,
+		}
+
+		history, err := h.service.GetCashbackHistoryByCursor(c.Request.Context(), turonUserID, filter, page)
+		if err != nil {
+			h.handleError(c, err, serviceErrorStatus(err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"data":       models.NewCashbackHistoryV1(history),
+			"pagination": page,
+		})
+		return
+	}
 
 	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
-	pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 64)
+	includeTotal, _ := strconv.ParseBool(c.DefaultQuery("include_total", "true"))
 
 	pagination := &models.Pagination{
-		Page:     page,
-		PageSize: pageSize,
+		Page:      page,
+		PageSize:  pageSize,
+		SkipTotal: !includeTotal,
 	}
 
-	history, err := h.service.GetCashbackHistoryByUserID(turonUserID, fromDate, toDate, pagination)
+	history, err := h.service.GetCashbackHistoryByUserID(c.Request.Context(), turonUserID, filter, pagination)
 	if err != nil {
-		h.handleError(c, err, http.StatusInternalServerError)
+		h.handleError(c, err, serviceErrorStatus(err))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":       history,
+		"data":       models.NewCashbackHistoryV1(history),
 		"pagination": pagination,
 	})
 }
+
+// @Summary Export cashback history of the user
+// @Description Stream the user's cashback history as CSV or XLSX. Accepts the same filters as the history endpoint
+// @Tags cashback
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param turon_user_id path int true "Turon User ID"
+// @Param format query string false "File format" Enums(csv, xlsx) default(csv)
+// @Param from_date query string false "Start: RFC 3339 timestamp or YYYY-MM-DD date" example(2024-03-01)
+// @Param to_date query string false "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)" example(2024-03-20)
+// @Param tz query string false "IANA time zone for date-only bounds (default server TIMEZONE)" example(Asia/Tashkent)
+// @Param operation query []string false "Operation types" collectionFormat(multi) Enums(credit, debit, opening, adjustment, expiry, payout, forfeiture, legacy)
+// @Param source query []string false "Source slugs" collectionFormat(multi)
+// @Param min_amount query number false "Minimum amount" minimum(0)
+// @Param max_amount query number false "Maximum amount" minimum(0)
+// @Param reason query string false "Free-text search in the reason"
+// @Param sort_by query string false "Sort field" Enums(created_at, amount) default(created_at)
+// @Param sort_order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cashback/{turon_user_id}/history/export [get]
+func (h *CashbackHandler) ExportCashbackHistory(c *gin.Context) {
+	turonUserID, err := strconv.ParseInt(c.Param("turon_user_id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid user id format"), http.StatusBadRequest)
+		return
+	}
+	if turonUserID <= 0 {
+		h.handleError(c, errors.New("turon_user_id must be positive"), http.StatusBadRequest)
+		return
+	}
+
+	filter, err := historyFilterFromQuery(c)
+	if err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	historyExport, err := h.service.NewHistoryExport(turonUserID, filter, c.Query("format"))
+	if err != nil {
+		h.handleError(c, err, serviceErrorStatus(err))
+		return
+	}
+
+	streamHistoryExport(c, historyExport)
+}
+
+// @Summary Monthly cashback statement
+// @Description Statement of the user for one month: opening balance, every credit, debit, expiry and adjustment with its source and reason, and closing balance. Returns HTML when format=html or the client accepts text/html
+// @Tags cashback
+// @Produce json,html
+// @Param turon_user_id path int true "Turon User ID"
+// @Param period path string true "Month, YYYY-MM" example(2024-03)
+// @Param tz query string false "IANA time zone of the month boundaries (default server TIMEZONE)" example(Asia/Tashkent)
+// @Param format query string false "Response format" Enums(json, html) default(json)
+// @Success 200 {object} models.Statement
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /cashback/{turon_user_id}/statements/{period} [get]
+func (h *CashbackHandler) GetStatement(c *gin.Context) {
+	turonUserID, err := strconv.ParseInt(c.Param("turon_user_id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid turon_user_id format"), http.StatusBadRequest)
+		return
+	}
+
+	statement, err := h.service.GetStatement(c.Request.Context(), turonUserID, c.Param("period"), c.Query("tz"))
+	if err != nil {
+		h.handleAccountError(c, err)
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" && c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		format = "html"
+	}
+	if format != "html" {
+		c.JSON(http.StatusOK, statement)
+		return
+	}
+
+	var page bytes.Buffer
+	if err := service.RenderStatementHTML(&page, statement); err != nil {
+		h.handleError(c, errors.New("failed to render statement"), http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
+
+// streamHistoryExport writes the export as an attachment. Once streaming has
+// started the status can no longer change, so late errors are only recorded.
+func streamHistoryExport(c *gin.Context, historyExport *service.HistoryExport) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", historyExport.Filename()))
+	c.Header("Content-Type", historyExport.ContentType())
+	c.Status(http.StatusOK)
+	if err := historyExport.Stream(c.Request.Context(), c.Writer); err != nil {
+		c.Error(err)
+	}
+}
+
+func historyFilterFromQuery(c *gin.Context) (*models.HistoryFilter, error) {
+	filter := &models.HistoryFilter{
+		FromDate:   c.Query("from_date"),
+		ToDate:     c.Query("to_date"),
+		TimeZone:   c.Query("tz"),
+		Operations: queryList(c, "operation"),
+		Sources:    queryList(c, "source"),
+		Reason:     strings.TrimSpace(c.Query("reason")),
+		SortBy:     c.Query("sort_by"),
+		SortOrder:  strings.ToLower(c.Query("sort_order")),
+	}
+
+	var err error
+	if filter.MinAmount, err = queryFloat(c, "min_amount"); err != nil {
+		return nil, err
+	}
+	if filter.MaxAmount, err = queryFloat(c, "max_amount"); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// queryList accepts both repeated (?k=a&k=b) and comma-separated (?k=a,b)
+// query values.
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func queryFloat(c *gin.Context, key string) (*float64, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s format", key)
+	}
+	return &value, nil
+}
+
+func serviceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAccountFrozen), errors.Is(err, service.ErrAccountClosed), errors.Is(err, service.ErrIdempotencyConflict):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInsufficientCashback), errors.Is(err, service.ErrNoCashback):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrTooManyOperations):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
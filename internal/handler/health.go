@@ -0,0 +1,49 @@
+package handler
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandler struct {
+	service *service.HealthService
+}
+
+func NewHealthHandler(service *service.HealthService) *HealthHandler {
+	return &HealthHandler{service: service}
+}
+
+func (h *HealthHandler) RegisterRoutes(router *gin.Engine) {
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
+}
+
+// @Summary Liveness probe
+// @Description Answers as long as the process is serving HTTP. Dependencies are checked by /readyz
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": constants.HealthStatusOK})
+}
+
+// @Summary Readiness probe
+// @Description Checks database connectivity, the migration version, queue saturation and that the queue worker is making progress. Fails once graceful shutdown has begun
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.Readiness
+// @Failure 503 {object} models.Readiness
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	readiness := h.service.Ready(c.Request.Context())
+
+	status := http.StatusOK
+	if readiness.Status != constants.ReadinessReady {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
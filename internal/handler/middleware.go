@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"bytes"
+	"cashback-serv/internal/logging"
+	"cashback-serv/models"
+	"cashback-serv/pkg/signature"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+const (
+	// RequestIDHeader carries the request ID in both directions.
+	RequestIDHeader = "X-Request-ID"
+	// IdempotencyKeyHeader makes an increase or decrease safe to retry.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks the answer to a repeated key.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+const (
+	requestIDKey = "request_id"
+	operatorKey  = "operator"
+)
+
+// RequestID takes the caller's X-Request-ID, or makes one up, and echoes it
+// in the response. The ID is also stored in the request context for logging.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			b := make([]byte, 16)
+			rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+
+		c.Set(requestIDKey, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// AccessLog logs every request once it is handled: server errors at error
+// level, client errors at warn and the rest at info.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.Any("errors", c.Errors.Errors()))
+		}
+		slog.LogAttrs(c.Request.Context(), level, "http request", attrs...)
+	}
+}
+
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// Recovery turns a panic into a 500 and logs it with its stack.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, err any) {
+		slog.ErrorContext(c.Request.Context(), "panic recovered",
+			"panic", fmt.Sprint(err),
+			"stack", string(debug.Stack()),
+		)
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// MaxSignedBodySize bounds the body VerifySignature reads into memory. It
+// leaves room for a bulk upload of service.MaxBulkRows rows.
+const MaxSignedBodySize = 32 << 20
+
+// VerifySignature rejects requests that are not signed with one of secrets
+// (see package signature). Requests whose path, or whose route template, is
+// one of exempt are let through.
+func VerifySignature(secrets []string, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, path := range exempt {
+			if c.Request.URL.Path == path || c.FullPath() == path {
+				c.Next()
+				return
+			}
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxSignedBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				abortSignature(c, http.StatusRequestEntityTooLarge, models.ErrorCodeInvalidArgument,
+					fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+				return
+			}
+			abortSignature(c, http.StatusBadRequest, models.ErrorCodeInvalidArgument, "failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		err = signature.Verify(
+			secrets,
+			c.GetHeader(signature.TimestampHeader),
+			c.GetHeader(signature.SignatureHeader),
+			c.Request.Method,
+			c.Request.URL.RequestURI(),
+			body,
+			time.Now(),
+		)
+		if err != nil {
+			abortSignature(c, http.StatusUnauthorized, models.ErrorCodeUnauthenticated, err.Error())
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// abortSignature answers in the envelope on /v2 routes and as {"error": ...}
+// elsewhere.
+func abortSignature(c *gin.Context, status int, code, message string) {
+	if strings.HasPrefix(c.Request.URL.Path, "/v2/") {
+		c.AbortWithStatusJSON(status, models.Envelope{
+			Error:     &models.APIError{Code: code, Message: message},
+			RequestID: requestID(c),
+		})
+		return
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// AuthenticateOperator identifies the operator behind each request whose
+// path starts with prefix by its bearer token, looked up in tokens (token to
+// operator), and rejects those without a known one. Handlers read the
+// identity with operator.
+func AuthenticateOperator(tokens map[string]string, prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, prefix) {
+			c.Next()
+			return
+		}
+
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok {
+			for allowed, name := range tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(allowed)) == 1 {
+					c.Set(operatorKey, name)
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "valid operator credentials are required"})
+	}
+}
+
+// operator returns the operator authenticated by AuthenticateOperator.
+func operator(c *gin.Context) string {
+	return c.GetString(operatorKey)
+}
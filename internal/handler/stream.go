@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"cashback-serv/pkg/signature"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// streamKeepAlive is how often an idle stream sends a comment line, so
+// proxies do not close it.
+const streamKeepAlive = 15 * time.Second
+
+// @Summary Stream balance updates
+// @Description Server-Sent Events stream of the user's cashback. A balance event with the current balance is sent on connect and after every change, preceded by one history event per new history entry. Event IDs are history IDs: reconnecting with Last-Event-ID (or last_event_id) replays the entries missed since then
+// @Tags cashback
+// @Produce text/event-stream
+// @Param turon_user_id path int true "Turon User ID"
+// @Param Last-Event-ID header int false "ID of the last event received"
+// @Param last_event_id query int false "Same as the Last-Event-ID header, for clients that cannot set it"
+// @Param token query string false "Stream token of the user; required when API requests are signed"
+// @Param expires query int false "Unix time the stream token expires at, at most an hour ahead"
+// @Success 200 {string} string "event: history (models.CashbackHistoryV1) and event: balance (models.Cashback)"
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "Missing, expired or invalid stream token"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /cashback/{turon_user_id}/stream [get]
+func (h *CashbackHandler) StreamCashback(c *gin.Context) {
+	turonUserID, err := strconv.ParseInt(c.Param("turon_user_id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid turon_user_id format"), http.StatusBadRequest)
+		return
+	}
+
+	if len(h.streamSecrets) > 0 {
+		err := signature.VerifyStreamToken(
+			h.streamSecrets,
+			turonUserID,
+			c.Query(signature.StreamExpiresParam),
+			c.Query(signature.StreamTokenParam),
+			time.Now(),
+		)
+		if err != nil {
+			h.handleError(c, err, http.StatusUnauthorized)
+			return
+		}
+	}
+
+	lastEventParam := c.GetHeader("Last-Event-ID")
+	if lastEventParam == "" {
+		lastEventParam = c.Query("last_event_id")
+	}
+	var lastEventID int64
+	if lastEventParam != "" {
+		if lastEventID, err = strconv.ParseInt(lastEventParam, 10, 64); err != nil {
+			h.handleError(c, errors.New("invalid Last-Event-ID format"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	wake, cancel, lastID, err := h.service.SubscribeCashback(turonUserID, lastEventID)
+	if err != nil {
+		if errors.Is(err, service.ErrStreamUnavailable) {
+			h.handleError(c, err, http.StatusServiceUnavailable)
+			return
+		}
+		h.handleAccountError(c, err)
+		return
+	}
+	defer cancel()
+
+	// Resolve the account before committing to a stream, so an erased
+	// account still gets a plain 404.
+	cashback, err := h.service.GetCashbackByUserID(c.Request.Context(), turonUserID)
+	if err != nil {
+		h.handleAccountError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	// A resuming client first gets what it missed; the balance it then
+	// receives already includes it.
+	sentID, err := sendHistoryUpdates(w, h.service, turonUserID, lastID)
+	if err != nil {
+		return
+	}
+	if sentID != lastID {
+		lastID = sentID
+		if cashback, err = h.service.GetCashbackByUserID(c.Request.Context(), turonUserID); err != nil {
+			return
+		}
+	}
+	if err := writeEvent(w, lastID, "balance", cashback); err != nil {
+		return
+	}
+	w.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-h.streams.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		case <-wake:
+			sentID, err := sendHistoryUpdates(w, h.service, turonUserID, lastID)
+			if err != nil {
+				return
+			}
+			if sentID == lastID {
+				continue
+			}
+			lastID = sentID
+
+			cashback, err := h.service.GetCashbackByUserID(c.Request.Context(), turonUserID)
+			if err != nil {
+				return
+			}
+			if err := writeEvent(w, lastID, "balance", cashback); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+// sendHistoryUpdates writes a history event for every entry after lastID and
+// returns the ID of the last one written.
+func sendHistoryUpdates(w gin.ResponseWriter, cashbackService *service.CashbackService, turonUserID, lastID int64) (int64, error) {
+	for {
+		history, err := cashbackService.GetCashbackUpdates(turonUserID, lastID)
+		if err != nil {
+			return lastID, err
+		}
+		for _, entry := range models.NewCashbackHistoryV1(history) {
+			if err := writeEvent(w, entry.ID, "history", &entry); err != nil {
+				return lastID, err
+			}
+			lastID = entry.ID
+		}
+		if len(history) < service.StreamBatchSize {
+			return lastID, nil
+		}
+	}
+}
+
+func writeEvent(w io.Writer, id int64, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+	return err
+}
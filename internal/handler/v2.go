@@ -0,0 +1,381 @@
+package handler
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+func (h *CashbackHandler) registerV2(router *gin.RouterGroup) {
+	cashback := router.Group("/cashback")
+	{
+		cashback.POST("/increase", h.IncreaseCashbackV2)
+		cashback.POST("/decrease", h.DecreaseCashbackV2)
+		cashback.POST("/bulk", h.BulkCreditV2)
+		cashback.POST("/balances", h.GetBalancesV2)
+		cashback.GET("/bulk/:batch_id", h.GetBatchV2)
+		cashback.GET("/:turon_user_id", h.GetCashbackV2)
+		cashback.GET("/:turon_user_id/history", h.GetCashbackHistoryV2)
+	}
+
+	router.GET("/operations/:id", h.GetOperationV2)
+}
+
+func respond(c *gin.Context, status int, data interface{}) {
+	c.JSON(status, models.Envelope{Data: data, RequestID: requestID(c)})
+}
+
+func respondError(c *gin.Context, status int, apiErr *models.APIError) {
+	c.JSON(status, models.Envelope{Error: apiErr, RequestID: requestID(c)})
+}
+
+// respondInvalid answers 400 for input rejected before reaching the service.
+func respondInvalid(c *gin.Context, message string) {
+	respondError(c, http.StatusBadRequest, &models.APIError{Code: models.ErrorCodeInvalidArgument, Message: message})
+}
+
+// respondServiceError maps a service error to its status and code. Internal
+// errors are recorded on the context and not shown to the client.
+func respondServiceError(c *gin.Context, err error) {
+	var validationErr *service.BulkValidationError
+	status, code := http.StatusInternalServerError, models.ErrorCodeInternal
+	switch {
+	case errors.As(err, &validationErr):
+		respondError(c, http.StatusBadRequest, &models.APIError{
+			Code:    models.ErrorCodeValidationFailed,
+			Message: err.Error(),
+			Details: validationErr.Rows,
+		})
+		return
+	case errors.Is(err, service.ErrInvalidArgument):
+		status, code = http.StatusBadRequest, models.ErrorCodeInvalidArgument
+	case errors.Is(err, service.ErrAccountErased):
+		status, code = http.StatusNotFound, models.ErrorCodeAccountErased
+	case errors.Is(err, service.ErrOperationNotFound), errors.Is(err, service.ErrBatchNotFound):
+		status, code = http.StatusNotFound, models.ErrorCodeNotFound
+	case errors.Is(err, service.ErrAccountFrozen):
+		status, code = http.StatusConflict, models.ErrorCodeAccountFrozen
+	case errors.Is(err, service.ErrAccountClosed):
+		status, code = http.StatusConflict, models.ErrorCodeAccountClosed
+	case errors.Is(err, service.ErrInsufficientCashback), errors.Is(err, service.ErrNoCashback):
+		status, code = http.StatusConflict, models.ErrorCodeInsufficientCashback
+	case errors.Is(err, service.ErrBatchConflict):
+		status, code = http.StatusConflict, models.ErrorCodeBatchConflict
+	case errors.Is(err, service.ErrIdempotencyConflict):
+		status, code = http.StatusConflict, models.ErrorCodeIdempotencyConflict
+	case errors.Is(err, service.ErrTooManyOperations):
+		status, code = http.StatusServiceUnavailable, models.ErrorCodeUnavailable
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		c.Error(err)
+		message = "internal server error"
+	}
+	respondError(c, status, &models.APIError{Code: code, Message: message})
+}
+
+// @Summary Cashback increase
+// @Description Increase cashback of the user. Returns the resulting balance and history entry; with async=true, the pending operation
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Param request body models.CashbackRequest true "Cashback increase"
+// @Param Idempotency-Key header string false "Client key; a repeated request with the same key posts nothing and returns the original history entry with replayed=true"
+// @Param async query bool false "Accept the operation and process it in the background; poll /v2/operations/{id} for the outcome"
+// @Success 200 {object} models.Envelope{data=models.CashbackMutation}
+// @Success 202 {object} models.Envelope{data=models.Operation}
+// @Failure 400 {object} models.Envelope
+// @Failure 409 {object} models.Envelope "account_frozen, account_closed or idempotency_conflict"
+// @Failure 500 {object} models.Envelope
+// @Failure 503 {object} models.Envelope "unavailable: too many async operations pending; retry later"
+// @Router /v2/cashback/increase [post]
+func (h *CashbackHandler) IncreaseCashbackV2(c *gin.Context) {
+	h.mutateCashbackV2(c, constants.Increase, h.service.IncreaseCashback)
+}
+
+// @Summary Cashback decrease
+// @Description Decrease cashback of the user. Returns the resulting balance and history entry; with async=true, the pending operation
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Param request body models.CashbackRequest true "Cashback decrease"
+// @Param Idempotency-Key header string false "Client key; a repeated request with the same key posts nothing and returns the original history entry with replayed=true"
+// @Param async query bool false "Accept the operation and process it in the background; poll /v2/operations/{id} for the outcome"
+// @Success 200 {object} models.Envelope{data=models.CashbackMutation}
+// @Success 202 {object} models.Envelope{data=models.Operation}
+// @Failure 400 {object} models.Envelope
+// @Failure 409 {object} models.Envelope "account_frozen, account_closed, insufficient_cashback or idempotency_conflict"
+// @Failure 500 {object} models.Envelope
+// @Failure 503 {object} models.Envelope "unavailable: too many async operations pending; retry later"
+// @Router /v2/cashback/decrease [post]
+func (h *CashbackHandler) DecreaseCashbackV2(c *gin.Context) {
+	h.mutateCashbackV2(c, constants.Decrease, h.service.DecreaseCashback)
+}
+
+func (h *CashbackHandler) mutateCashbackV2(c *gin.Context, opType string, mutate func(context.Context, *models.CashbackRequest) (*models.CashbackMutation, error)) {
+	var req models.CashbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalid(c, err.Error())
+		return
+	}
+	req.IdempotencyKey = c.GetHeader(IdempotencyKeyHeader)
+
+	async, err := strconv.ParseBool(c.DefaultQuery("async", "false"))
+	if err != nil {
+		respondInvalid(c, "invalid async format")
+		return
+	}
+	if async {
+		operation, err := h.service.SubmitCashback(c.Request.Context(), opType, &req)
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+
+		c.Header("Location", "/v2/operations/"+operation.ID)
+		respond(c, http.StatusAccepted, operation)
+		return
+	}
+
+	mutation, err := mutate(c.Request.Context(), &req)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+	if mutation.Replayed {
+		c.Header(IdempotentReplayedHeader, "true")
+	}
+
+	respond(c, http.StatusOK, mutation)
+}
+
+// @Summary Async operation status
+// @Description Status of an increase or decrease submitted with async=true
+// @Tags v2
+// @Produce json
+// @Param id path string true "Operation ID"
+// @Success 200 {object} models.Envelope{data=models.Operation}
+// @Failure 404 {object} models.Envelope
+// @Failure 500 {object} models.Envelope
+// @Router /v2/operations/{id} [get]
+func (h *CashbackHandler) GetOperationV2(c *gin.Context) {
+	operation, err := h.service.GetOperation(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, operation)
+}
+
+// @Summary Get cashback
+// @Description Cashback amount and account status of the user; with as_of, the balance at that instant
+// @Tags v2
+// @Produce json
+// @Param turon_user_id path int true "Turon User ID"
+// @Param as_of query string false "Point in time (RFC 3339, or YYYY-MM-DD for the end of that day)" example(2024-03-31T23:59:59Z)
+// @Param tz query string false "IANA time zone for a date-only as_of (default server TIMEZONE)" example(Asia/Tashkent)
+// @Success 200 {object} models.Envelope{data=models.Cashback}
+// @Failure 400 {object} models.Envelope
+// @Failure 404 {object} models.Envelope "account_erased"
+// @Failure 500 {object} models.Envelope
+// @Router /v2/cashback/{turon_user_id} [get]
+func (h *CashbackHandler) GetCashbackV2(c *gin.Context) {
+	turonUserID, err := strconv.ParseInt(c.Param("turon_user_id"), 10, 64)
+	if err != nil {
+		respondInvalid(c, "invalid turon_user_id format")
+		return
+	}
+
+	if asOfParam := c.Query("as_of"); asOfParam != "" {
+		asOf, err := h.service.ParseAsOf(asOfParam, c.Query("tz"))
+		if err != nil {
+			respondInvalid(c, err.Error())
+			return
+		}
+
+		balance, err := h.service.GetCashbackBalanceAsOf(c.Request.Context(), turonUserID, asOf)
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+
+		respond(c, http.StatusOK, balance)
+		return
+	}
+
+	cashback, err := h.service.GetCashbackByUserID(c.Request.Context(), turonUserID)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, cashback)
+}
+
+// @Summary Cashback history of the user
+// @Description History with the filters, sorting and pagination of /cashback/{turon_user_id}/history
+// @Tags v2
+// @Produce json
+// @Param turon_user_id path int true "Turon User ID"
+// @Param from_date query string false "Start: RFC 3339 timestamp or YYYY-MM-DD date" example(2024-03-01)
+// @Param to_date query string false "End, inclusive: RFC 3339 timestamp or YYYY-MM-DD date (the whole day)" example(2024-03-20)
+// @Param tz query string false "IANA time zone for date-only bounds (default server TIMEZONE)" example(Asia/Tashkent)
+// @Param operation query []string false "Operation types" collectionFormat(multi) Enums(credit, debit, opening, adjustment, expiry, payout, forfeiture, legacy)
+// @Param source query []string false "Source slugs" collectionFormat(multi)
+// @Param min_amount query number false "Minimum amount" minimum(0)
+// @Param max_amount query number false "Maximum amount" minimum(0)
+// @Param reason query string false "Free-text search in the reason"
+// @Param sort_by query string false "Sort field" Enums(created_at, amount) default(created_at)
+// @Param sort_order query string false "Sort direction" Enums(asc, desc) default(desc)
+// @Param pagination query string false "Pagination mode" Enums(offset, cursor) default(offset)
+// @Param cursor query string false "Opaque cursor from next_cursor or prev_cursor; implies cursor mode"
+// @Param include_total query bool false "Count matching rows (default true in offset mode, false in cursor mode)"
+// @Param page query int false "Page number (offset mode)" default(1) minimum(1)
+// @Param page_size query int false "Items per page" default(10) minimum(1) maximum(100)
+// @Success 200 {object} models.Envelope{data=models.HistoryPage}
+// @Failure 400 {object} models.Envelope
+// @Failure 500 {object} models.Envelope
+// @Router /v2/cashback/{turon_user_id}/history [get]
+func (h *CashbackHandler) GetCashbackHistoryV2(c *gin.Context) {
+	turonUserID, err := strconv.ParseInt(c.Param("turon_user_id"), 10, 64)
+	if err != nil {
+		respondInvalid(c, "invalid turon_user_id format")
+		return
+	}
+
+	filter, err := historyFilterFromQuery(c)
+	if err != nil {
+		respondInvalid(c, err.Error())
+		return
+	}
+
+	pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 64)
+
+	cursor := c.Query("cursor")
+	if cursor != "" || c.Query("pagination") == "cursor" {
+		includeTotal, _ := strconv.ParseBool(c.DefaultQuery("include_total", "false"))
+		page := &models.CursorPagination{
+			Cursor:       cursor,
+			PageSize:     pageSize,
+			IncludeTotal: includeTotal,
+		}
+
+		history, err := h.service.GetCashbackHistoryByCursor(c.Request.Context(), turonUserID, filter, page)
+		if err != nil {
+			respondServiceError(c, err)
+			return
+		}
+
+		respond(c, http.StatusOK, models.HistoryPage{Items: history, Pagination: page})
+		return
+	}
+
+	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	includeTotal, _ := strconv.ParseBool(c.DefaultQuery("include_total", "true"))
+
+	pagination := &models.Pagination{
+		Page:      page,
+		PageSize:  pageSize,
+		SkipTotal: !includeTotal,
+	}
+
+	history, err := h.service.GetCashbackHistoryByUserID(c.Request.Context(), turonUserID, filter, pagination)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, models.HistoryPage{Items: history, Pagination: pagination})
+}
+
+// @Summary Batch balance lookup
+// @Description Balances of many users in one call. Users without a cashback wallet are listed in missing
+// @Tags v2
+// @Accept json
+// @Produce json
+// @Param request body models.BalanceLookupRequest true "Users to look up, at most 500"
+// @Success 200 {object} models.Envelope{data=models.BalanceLookupResult}
+// @Failure 400 {object} models.Envelope
+// @Failure 500 {object} models.Envelope
+// @Router /v2/cashback/balances [post]
+func (h *CashbackHandler) GetBalancesV2(c *gin.Context) {
+	var req models.BalanceLookupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondInvalid(c, err.Error())
+		return
+	}
+
+	result, err := h.service.GetCashbacksByUserIDs(c.Request.Context(), req.TuronUserIDs)
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, result)
+}
+
+// @Summary Bulk cashback credit
+// @Description Credit many users in one batch, as /cashback/bulk: rows are stored and posted in the background. Row-level problems come back as validation_failed with the rows in error.details
+// @Tags v2
+// @Accept json
+// @Accept text/csv
+// @Accept multipart/form-data
+// @Produce json
+// @Param batch_id query string false "Client batch ID; generated when omitted" example(partner-2024-03)
+// @Param request body []models.BulkCreditRow false "Rows, when sending JSON"
+// @Param file formData file false "CSV file, when uploading"
+// @Success 202 {object} models.Envelope{data=models.CashbackBatch}
+// @Failure 400 {object} models.Envelope{error=models.APIError{details=[]models.BulkRowError}}
+// @Failure 409 {object} models.Envelope
+// @Failure 500 {object} models.Envelope
+// @Failure 503 {object} models.Envelope "unavailable: too many batches pending; retry later"
+// @Router /v2/cashback/bulk [post]
+func (h *CashbackHandler) BulkCreditV2(c *gin.Context) {
+	rows, err := bulkRowsFromRequest(c)
+	if err != nil {
+		var validationErr *service.BulkValidationError
+		if errors.As(err, &validationErr) {
+			respondServiceError(c, err)
+		} else {
+			respondInvalid(c, err.Error())
+		}
+		return
+	}
+
+	batch, err := h.service.BulkCredit(c.Request.Context(), c.Query("batch_id"), rows, c.ClientIP())
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	c.Header("Location", "/v2/cashback/bulk/"+url.PathEscape(batch.ID))
+	respond(c, http.StatusAccepted, batch)
+}
+
+// @Summary Bulk cashback credit report
+// @Description Status of a bulk credit batch and the outcome of every row
+// @Tags v2
+// @Produce json
+// @Param batch_id path string true "Batch ID"
+// @Success 200 {object} models.Envelope{data=models.CashbackBatch}
+// @Failure 404 {object} models.Envelope
+// @Failure 500 {object} models.Envelope
+// @Router /v2/cashback/bulk/{batch_id} [get]
+func (h *CashbackHandler) GetBatchV2(c *gin.Context) {
+	batch, err := h.service.GetBatch(c.Request.Context(), c.Param("batch_id"))
+	if err != nil {
+		respondServiceError(c, err)
+		return
+	}
+
+	respond(c, http.StatusOK, batch)
+}
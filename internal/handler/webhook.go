@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+type WebhookHandler struct {
+	service *service.WebhookService
+}
+
+func NewWebhookHandler(service *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+func (h *WebhookHandler) RegisterRoutes(router *gin.Engine) {
+	webhooks := router.Group("/admin/webhooks")
+	{
+		webhooks.POST("", h.CreateSubscription)
+		webhooks.GET("", h.ListSubscriptions)
+		webhooks.DELETE("/:id", h.DeleteSubscription)
+		webhooks.GET("/dead-letters", h.ListDeadLetters)
+		webhooks.POST("/deliveries/:id/redeliver", h.Redeliver)
+	}
+}
+
+func (h *WebhookHandler) handleError(c *gin.Context, err error, status int) {
+	c.JSON(status, gin.H{"error": err.Error()})
+}
+
+// @Summary Subscribe to webhook events
+// @Description Register a URL for cashback.credited, cashback.debited, cashback.debit_rejected, source.created and account.closed events, of one source or of all sources when source is empty. Deliveries are signed with the returned secret: X-Cashback-Signature is sha256= followed by the hex HMAC-SHA256 of X-Cashback-Timestamp, a dot and the body. The secret is not shown again
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param request body models.WebhookSubscriptionRequest true "Subscription"
+// @Success 201 {object} models.WebhookSubscription
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/webhooks [post]
+func (h *WebhookHandler) CreateSubscription(c *gin.Context) {
+	var req models.WebhookSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.handleError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.service.CreateSubscription(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, webhookErrorStatus(err))
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// @Summary List webhook subscriptions
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} map[string]interface{} "data: array of subscriptions"
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/webhooks [get]
+func (h *WebhookHandler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.service.ListSubscriptions(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": subscriptions})
+}
+
+// @Summary Delete a webhook subscription
+// @Description Stop delivering to the subscription; its pending deliveries move to the dead-letter list
+// @Tags webhooks
+// @Param id path int true "Subscription ID"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteSubscription(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid subscription id format"), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.DeleteSubscription(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, webhookErrorStatus(err))
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// @Summary List dead-lettered webhook deliveries
+// @Description Deliveries that exhausted their attempts or whose subscription was deleted, most recent first
+// @Tags webhooks
+// @Produce json
+// @Param page query int false "Page number" default(1) minimum(1)
+// @Param page_size query int false "Items per page" default(10) minimum(1) maximum(100)
+// @Success 200 {object} map[string]interface{} "data: array of deliveries, pagination: pagination info"
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/webhooks/dead-letters [get]
+func (h *WebhookHandler) ListDeadLetters(c *gin.Context) {
+	page, _ := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	pageSize, _ := strconv.ParseInt(c.DefaultQuery("page_size", "10"), 10, 64)
+
+	pagination := &models.Pagination{
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	deliveries, err := h.service.ListDeadLetters(c.Request.Context(), pagination)
+	if err != nil {
+		h.handleError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       deliveries,
+		"pagination": pagination,
+	})
+}
+
+// @Summary Redeliver a webhook
+// @Description Queue a delivery again with a fresh attempt budget
+// @Tags webhooks
+// @Param id path int true "Delivery ID"
+// @Success 202
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security OperatorToken
+// @Router /admin/webhooks/deliveries/{id}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		h.handleError(c, errors.New("invalid delivery id format"), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Redeliver(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, webhookErrorStatus(err))
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
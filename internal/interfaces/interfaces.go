@@ -1,7 +1,33 @@
 package core
 
-import "cashback-serv/models"
+import (
+	"cashback-serv/models"
+	"context"
+)
 
 type SourceFinderCreator interface {
-	FindSourceOrCreate(turonUserID int64, hostIP string) (*models.Source, error)
+	FindSourceOrCreate(ctx context.Context, turonUserID int64, hostIP string) (*models.Source, error)
+}
+
+// CashbackTx is the set of repository operations available inside a single
+// database transaction. History rows and their ledger postings are always
+// written through it so they commit together.
+type CashbackTx interface {
+	GetCashbackByUserIDForUpdate(turonUserID int64) (*models.Cashback, error)
+	GetCashbackAccount(ctx context.Context, turonUserID int64) (*models.Cashback, error)
+	UpdateCashbackStatus(change *models.AccountStatusChange) error
+	CreateCashback(cashback *models.Cashback) error
+	CreateCashbackHistory(history *models.CashbackHistory) error
+	EnsureUserAccount(cashbackID int64) (int64, error)
+	EnsureSourceAccount(sourceID int64) (int64, error)
+	GetSystemAccountID(code string) (int64, error)
+	CreateLedgerEntries(historyID int64, entries []models.LedgerEntry) error
+	GetLedgerBalance(cashbackID int64) (float64, error)
+	RefreshCashbackBalance(cashbackID int64) (float64, error)
+	UpdateRollups(history *models.CashbackHistory) error
+	MarkAdjustmentPosted(id, historyID int64, approvedBy, comment string) error
+	MarkBatchItemSucceeded(batchID string, row int, historyID int64) error
+	MarkOperationSucceeded(id string, historyID int64) error
+	CreateWebhookEvent(event *models.WebhookEvent) error
+	NotifyCashbackUpdate(turonUserID int64) error
 }
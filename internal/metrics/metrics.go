@@ -0,0 +1,133 @@
+// Package metrics holds the service's Prometheus collectors. They are
+// registered on Registry, which Handler serves.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cashback"
+
+// Registry holds every collector in this package plus the Go runtime and
+// process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests handled, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	QueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "queue",
+		Name:      "depth",
+		Help:      "Operations waiting in the cashback queue.",
+	})
+
+	QueueWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "queue",
+		Name:      "wait_seconds",
+		Help:      "Time an operation spent in the cashback queue before processing, by operation type.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	QueueProcessing = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "queue",
+		Name:      "processing_seconds",
+		Help:      "Time spent processing an operation, by operation type and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+
+	QueueUserLocks = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "queue",
+		Name:      "user_locks",
+		Help:      "Per-user locks currently held by the cashback queue worker.",
+	})
+
+	CreditedAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "credited_amount_total",
+		Help:      "Cashback credited to users, by source.",
+	}, []string{"source_id"})
+
+	DebitedAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "debited_amount_total",
+		Help:      "Cashback debited from users, by source.",
+	}, []string{"source_id"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		QueueDepth,
+		QueueWait,
+		QueueProcessing,
+		QueueUserLocks,
+		CreditedAmount,
+		DebitedAmount,
+	)
+}
+
+// RegisterDB exposes db's connection pool statistics.
+func RegisterDB(db *sql.DB) {
+	Registry.MustRegister(collectors.NewDBStatsCollector(db, "cashback"))
+}
+
+// Handler serves Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Middleware records HTTP request counts and latency. Requests are labelled
+// by route template rather than path, so IDs in the path do not blow up the
+// label set; unmatched requests share the route "unmatched".
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		HTTPRequestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// SourceLabel formats a source ID for the source_id label. Operations
+// without a source, such as reconciliations, are labelled "none".
+func SourceLabel(sourceID int64) string {
+	if sourceID == 0 {
+		return "none"
+	}
+	return strconv.FormatInt(sourceID, 10)
+}
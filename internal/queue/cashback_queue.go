@@ -3,16 +3,35 @@ package queue
 import (
 	constants "cashback-serv/const"
 	core "cashback-serv/internal/interfaces"
+	"cashback-serv/internal/metrics"
+	"cashback-serv/internal/tracing"
 	"cashback-serv/models"
+	"context"
+	"encoding/json"
 	"errors"
+	"log/slog"
+	"math"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	ErrAccountFrozen        = errors.New("cashback account is frozen")
+	ErrAccountClosed        = errors.New("cashback account is closed")
+	ErrNoCashback           = errors.New("no cashback found for user")
+	ErrInsufficientCashback = errors.New("insufficient cashback amount")
+	// ErrInvalidAmount guards the postings of increase and decrease, whose
+	// amount is unsigned; the service rejects such requests before they
+	// are queued.
+	ErrInvalidAmount = errors.New("cashback amount must be positive")
 )
 
 type CashbackRepository interface {
-	GetCashbackByUserID(turonUserID int64) (*models.Cashback, error)
-	CreateCashback(cashback *models.Cashback) error
-	UpdateCashbackAmount(id int64, amount float64) error
-	CreateCashbackHistory(history *models.CashbackHistory) error
+	WithTxContext(ctx context.Context, fn func(tx core.CashbackTx) error) error
 }
 
 type SourceFinderCreator interface {
@@ -22,12 +41,54 @@ type SourceFinderCreator interface {
 type QueueRequest struct {
 	*models.CashbackRequest
 	SourceID int64
+	// AdjustmentID links an Adjust operation to its cashback_adjustments row;
+	// Approval is the second operator's decision when one was required.
+	AdjustmentID int64
+	Approval     *models.AdjustmentDecision
+	// BatchID and BatchRow identify the bulk payout row an Increase posts.
+	BatchID  string
+	BatchRow int
+	// OperationID is the async operation record an Increase or Decrease
+	// completes.
+	OperationID string
+	// Closure settles and closes the account in a Close operation.
+	Closure *models.CloseAccountRequest
+
+	ctx context.Context
+}
+
+// Context returns the context the request was enqueued with, which carries
+// its request ID and trace.
+func (r *QueueRequest) Context() context.Context {
+	return r.ctx
+}
+
+// isRejection reports whether err refused the operation on business grounds,
+// as opposed to failing it.
+func isRejection(err error) bool {
+	return errors.Is(err, ErrNoCashback) ||
+		errors.Is(err, ErrAccountFrozen) ||
+		errors.Is(err, ErrAccountClosed) ||
+		errors.Is(err, ErrInsufficientCashback)
+}
+
+// OperationResult is what a committed operation produced. History is nil when
+// the operation had nothing to post.
+type OperationResult struct {
+	Cashback *models.Cashback
+	History  *models.CashbackHistory
+}
+
+type operationResponse struct {
+	result *OperationResult
+	err    error
 }
 
 type CashbackOperation struct {
-	Type     string
-	Request  *QueueRequest
-	Response chan error
+	Type       string
+	Request    *QueueRequest
+	Response   chan operationResponse
+	EnqueuedAt time.Time
 }
 
 type CashbackQueue struct {
@@ -36,6 +97,18 @@ type CashbackQueue struct {
 	mu            sync.RWMutex
 	repo          CashbackRepository
 	sourceService core.SourceFinderCreator
+	// busySince is when the operation being processed started, in Unix
+	// nanoseconds, or zero while the worker is idle.
+	busySince atomic.Int64
+}
+
+// Stats describes the queue's load.
+type Stats struct {
+	Depth    int
+	Capacity int
+	// Busy is how long the worker has been on its current operation; zero
+	// when it is idle.
+	Busy time.Duration
 }
 
 func NewCashbackQueue(repo CashbackRepository, sourceService core.SourceFinderCreator) *CashbackQueue {
@@ -53,109 +126,594 @@ func NewCashbackQueue(repo CashbackRepository, sourceService core.SourceFinderCr
 
 func (q *CashbackQueue) process() {
 	for op := range q.operations {
-		q.mu.Lock()
-		lock, exists := q.userLocks[op.Request.TuronUserID]
-		if !exists {
-			lock = &sync.Mutex{}
-			q.userLocks[op.Request.TuronUserID] = lock
+		metrics.QueueDepth.Set(float64(len(q.operations)))
+		metrics.QueueWait.WithLabelValues(op.Type).Observe(time.Since(op.EnqueuedAt).Seconds())
+
+		start := time.Now()
+		q.busySince.Store(start.UnixNano())
+		result, err := q.run(op)
+		q.busySince.Store(0)
+		observe(op, result, err, time.Since(start))
+		op.Response <- operationResponse{result: result, err: err}
+	}
+}
+
+// Stats returns the queue's current load.
+func (q *CashbackQueue) Stats() Stats {
+	stats := Stats{
+		Depth:    len(q.operations),
+		Capacity: cap(q.operations),
+	}
+	if since := q.busySince.Load(); since != 0 {
+		stats.Busy = time.Since(time.Unix(0, since))
+	}
+	return stats
+}
+
+// run processes op under its user's lock. The time op waited in the queue,
+// the lock acquisition and the processing are traced as separate spans.
+func (q *CashbackQueue) run(op *CashbackOperation) (*OperationResult, error) {
+	ctx := op.Request.Context()
+	_, waitSpan := tracing.Tracer.Start(ctx, "queue wait",
+		trace.WithTimestamp(op.EnqueuedAt),
+		trace.WithAttributes(attribute.String("cashback.operation", op.Type)),
+	)
+	waitSpan.End()
+
+	ctx, span := tracing.Tracer.Start(ctx, "queue "+op.Type, trace.WithAttributes(
+		attribute.String("cashback.operation", op.Type),
+		attribute.Int64("cashback.turon_user_id", op.Request.TuronUserID),
+		attribute.Int64("cashback.source_id", op.Request.SourceID),
+		attribute.Float64("cashback.amount", op.Request.CashbackAmount),
+	))
+
+	q.mu.Lock()
+	lock, exists := q.userLocks[op.Request.TuronUserID]
+	if !exists {
+		lock = &sync.Mutex{}
+		q.userLocks[op.Request.TuronUserID] = lock
+	}
+	q.mu.Unlock()
+
+	_, lockSpan := tracing.Tracer.Start(ctx, "queue user lock")
+	lock.Lock()
+	lockSpan.End()
+	metrics.QueueUserLocks.Inc()
+
+	var (
+		result *OperationResult
+		err    error
+	)
+
+	switch op.Type {
+	case constants.Increase:
+		result, err = q.handleIncrease(ctx, op.Request)
+	case constants.Decrease:
+		result, err = q.handleDecrease(ctx, op.Request)
+	case constants.Reconcile:
+		result, err = q.handleReconcile(ctx, op.Request)
+	case constants.Adjust:
+		result, err = q.handleAdjust(ctx, op.Request)
+	case constants.Close:
+		result, err = q.handleClose(ctx, op.Request)
+	default:
+		err = errors.New("unknown operation type")
+	}
+
+	metrics.QueueUserLocks.Dec()
+	lock.Unlock()
+	tracing.End(span, err)
+	return result, err
+}
+
+// observe logs op's outcome and records how long it took and, for committed
+// credits and debits, the amount that moved.
+func observe(op *CashbackOperation, result *OperationResult, err error, elapsed time.Duration) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.QueueProcessing.WithLabelValues(op.Type, outcome).Observe(elapsed.Seconds())
+
+	attrs := []slog.Attr{
+		slog.String("operation", op.Type),
+		slog.Int64("turon_user_id", op.Request.TuronUserID),
+		slog.Int64("source_id", op.Request.SourceID),
+		slog.Float64("amount", op.Request.CashbackAmount),
+		slog.String("outcome", outcome),
+		slog.Duration("wait", time.Since(op.EnqueuedAt)-elapsed),
+		slog.Duration("duration", elapsed),
+	}
+	if op.Request.OperationID != "" {
+		attrs = append(attrs, slog.String("operation_id", op.Request.OperationID))
+	}
+	if op.Request.BatchID != "" {
+		attrs = append(attrs, slog.String("batch_id", op.Request.BatchID), slog.Int("batch_row", op.Request.BatchRow))
+	}
+	level := slog.LevelInfo
+	switch {
+	case err == nil:
+		if result != nil && result.History != nil {
+			attrs = append(attrs, slog.Int64("history_id", result.History.ID))
+		}
+		if result != nil && result.Cashback != nil {
+			attrs = append(attrs, slog.Float64("balance", result.Cashback.CashbackAmount))
+		}
+	case isRejection(err):
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	default:
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	slog.LogAttrs(op.Request.Context(), level, "cashback operation", attrs...)
+
+	if err != nil || result == nil || result.History == nil {
+		return
+	}
+	source := metrics.SourceLabel(op.Request.SourceID)
+	switch op.Type {
+	case constants.Increase:
+		metrics.CreditedAmount.WithLabelValues(source).Add(op.Request.CashbackAmount)
+	case constants.Decrease:
+		metrics.DebitedAmount.WithLabelValues(source).Add(op.Request.CashbackAmount)
+	}
+}
+
+func (q *CashbackQueue) handleIncrease(ctx context.Context, req *QueueRequest) (*OperationResult, error) {
+	if req.CashbackAmount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	result := &OperationResult{}
+	err := q.repo.WithTxContext(ctx, func(tx core.CashbackTx) error {
+		cashback, err := tx.GetCashbackByUserIDForUpdate(req.TuronUserID)
+		if err != nil {
+			return err
 		}
-		q.mu.Unlock()
 
-		lock.Lock()
-		var err error
+		if cashback == nil {
+			if cashback, err = q.openCashback(ctx, tx, req.TuronUserID); err != nil {
+				return err
+			}
+		}
 
-		switch op.Type {
-		case constants.Increase:
-			err = q.handleIncrease(op.Request)
-		case constants.Decrease:
-			err = q.handleDecrease(op.Request)
-		default:
-			err = errors.New("unknown operation type")
+		if cashback.Status == constants.AccountStatusFrozen && cashback.FreezeCredits {
+			return ErrAccountFrozen
 		}
 
-		lock.Unlock()
-		op.Response <- err
+		sourceAccountID, err := tx.EnsureSourceAccount(req.SourceID)
+		if err != nil {
+			return err
+		}
+
+		result.Cashback = cashback
+		result.History, err = q.post(tx, cashback, req, constants.OperationCredit, sourceAccountID, req.CashbackAmount)
+		if err != nil {
+			return err
+		}
+		if err := q.publish(tx, constants.EventCashbackCredited, req, result.History, ""); err != nil {
+			return err
+		}
+		return q.recordOutcome(tx, req, result.History)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
 }
 
-func (q *CashbackQueue) handleIncrease(req *QueueRequest) error {
-	cashback, err := q.repo.GetCashbackByUserID(req.TuronUserID)
+func (q *CashbackQueue) handleDecrease(ctx context.Context, req *QueueRequest) (*OperationResult, error) {
+	if req.CashbackAmount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	result := &OperationResult{}
+	err := q.repo.WithTxContext(ctx, func(tx core.CashbackTx) error {
+		cashback, err := tx.GetCashbackByUserIDForUpdate(req.TuronUserID)
+		if err != nil {
+			return err
+		}
+
+		if cashback == nil {
+			return ErrNoCashback
+		}
+
+		if cashback.Status == constants.AccountStatusFrozen {
+			return ErrAccountFrozen
+		}
+
+		if cashback.CashbackAmount < req.CashbackAmount {
+			return ErrInsufficientCashback
+		}
+
+		sourceAccountID, err := tx.EnsureSourceAccount(req.SourceID)
+		if err != nil {
+			return err
+		}
+
+		result.Cashback = cashback
+		result.History, err = q.post(tx, cashback, req, constants.OperationDebit, sourceAccountID, -req.CashbackAmount)
+		if err != nil {
+			return err
+		}
+		if err := q.publish(tx, constants.EventCashbackDebited, req, result.History, ""); err != nil {
+			return err
+		}
+		return q.recordOutcome(tx, req, result.History)
+	})
 	if err != nil {
-		return err
+		if errors.Is(err, ErrNoCashback) || errors.Is(err, ErrAccountFrozen) || errors.Is(err, ErrInsufficientCashback) {
+			// The rejection is published on its own, since the debit rolled
+			// back. Failing to publish it must not hide the rejection itself.
+			if publishErr := q.repo.WithTxContext(ctx, func(tx core.CashbackTx) error {
+				return q.publish(tx, constants.EventCashbackDebitRejected, req, nil, err.Error())
+			}); publishErr != nil {
+				slog.ErrorContext(req.Context(), "failed to publish debit rejection",
+					"turon_user_id", req.TuronUserID,
+					"error", publishErr,
+				)
+			}
+		}
+		return nil, err
 	}
+	return result, nil
+}
+
+// handleReconcile brings the ledger in line with the stored balance by posting
+// the difference against the system adjustment account. The difference is
+// taken under the user lock, so it reflects the balance at posting time.
+func (q *CashbackQueue) handleReconcile(ctx context.Context, req *QueueRequest) (*OperationResult, error) {
+	result := &OperationResult{}
+	err := q.repo.WithTxContext(ctx, func(tx core.CashbackTx) error {
+		cashback, err := tx.GetCashbackByUserIDForUpdate(req.TuronUserID)
+		if err != nil {
+			return err
+		}
 
-	if cashback == nil {
-		cashback = &models.Cashback{
-			CashbackAmount: req.CashbackAmount,
-			TuronUserID:    req.TuronUserID,
+		if cashback == nil {
+			return ErrNoCashback
 		}
-		if err := q.repo.CreateCashback(cashback); err != nil {
+		result.Cashback = cashback
+
+		ledgerBalance, err := tx.GetLedgerBalance(cashback.ID)
+		if err != nil {
+			return err
+		}
+
+		delta := math.Round((cashback.CashbackAmount-ledgerBalance)*100) / 100
+		if delta == 0 {
+			return nil
+		}
+
+		adjustmentAccountID, err := tx.GetSystemAccountID(constants.SystemAccountAdjustment)
+		if err != nil {
 			return err
 		}
-	} else {
-		newAmount := cashback.CashbackAmount + req.CashbackAmount
-		if err := q.repo.UpdateCashbackAmount(cashback.ID, newAmount); err != nil {
+
+		adjustment := &QueueRequest{
+			CashbackRequest: &models.CashbackRequest{
+				TuronUserID:    req.TuronUserID,
+				CashbackAmount: math.Abs(delta),
+				HostIP:         req.HostIP,
+				Reason:         constants.ReasonReconciliation,
+			},
+		}
+		if result.History, err = q.post(tx, cashback, adjustment, constants.OperationAdjustment, adjustmentAccountID, delta); err != nil {
 			return err
 		}
-		cashback.CashbackAmount = newAmount
+		return q.publishPosting(tx, adjustment, result.History)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
+
+// handleAdjust posts a manual adjustment. Unlike increase and decrease, the
+// request's CashbackAmount is signed here: negative amounts debit the user.
+func (q *CashbackQueue) handleAdjust(ctx context.Context, req *QueueRequest) (*OperationResult, error) {
+	result := &OperationResult{}
+	err := q.repo.WithTxContext(ctx, func(tx core.CashbackTx) error {
+		cashback, err := tx.GetCashbackByUserIDForUpdate(req.TuronUserID)
+		if err != nil {
+			return err
+		}
+
+		amount := req.CashbackAmount
+		if cashback == nil {
+			if amount < 0 {
+				return ErrNoCashback
+			}
+			if cashback, err = q.openCashback(ctx, tx, req.TuronUserID); err != nil {
+				return err
+			}
+		}
+
+		// A frozen account takes no debits, and no credits either when
+		// credits are frozen too, as with increase and decrease.
+		if cashback.Status == constants.AccountStatusFrozen && (amount < 0 || cashback.FreezeCredits) {
+			return ErrAccountFrozen
+		}
+
+		if cashback.CashbackAmount+amount < 0 {
+			return ErrInsufficientCashback
+		}
+
+		adjustmentAccountID, err := tx.GetSystemAccountID(constants.SystemAccountAdjustment)
+		if err != nil {
+			return err
+		}
+
+		adjustment := *req.CashbackRequest
+		adjustment.CashbackAmount = math.Abs(amount)
+		posting := &QueueRequest{CashbackRequest: &adjustment}
+
+		result.Cashback = cashback
+		result.History, err = q.post(tx, cashback, posting, constants.OperationAdjustment, adjustmentAccountID, amount)
+		if err != nil {
+			return err
+		}
+		if err := q.publishPosting(tx, posting, result.History); err != nil {
+			return err
+		}
 
-	if req.SourceID <= 0 {
+		if req.AdjustmentID > 0 {
+			var approvedBy, comment string
+			if req.Approval != nil {
+				approvedBy, comment = req.Approval.Operator, req.Approval.Comment
+			}
+			return tx.MarkAdjustmentPosted(req.AdjustmentID, result.History.ID, approvedBy, comment)
+		}
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
+}
 
-	history := &models.CashbackHistory{
-		CashbackID:     cashback.ID,
-		SourceID:       req.SourceID,
-		CashbackAmount: req.CashbackAmount,
-		HostIP:         req.HostIP,
-		Type:           req.Type,
+// handleClose settles the remaining balance against the payout or
+// forfeiture account and then closes and soft-deletes the wallet.
+func (q *CashbackQueue) handleClose(ctx context.Context, req *QueueRequest) (*OperationResult, error) {
+	result := &OperationResult{}
+	err := q.repo.WithTxContext(ctx, func(tx core.CashbackTx) error {
+		cashback, err := tx.GetCashbackByUserIDForUpdate(req.TuronUserID)
+		if err != nil {
+			return err
+		}
+
+		if cashback == nil {
+			return ErrNoCashback
+		}
+		result.Cashback = cashback
+
+		balance, err := tx.GetLedgerBalance(cashback.ID)
+		if err != nil {
+			return err
+		}
+
+		change := &models.AccountStatusChange{
+			CashbackID: cashback.ID,
+			FromStatus: cashback.Status,
+			ToStatus:   constants.AccountStatusClosed,
+			Reason:     req.Closure.Reason,
+			Operator:   req.Closure.Operator,
+		}
+
+		if balance = math.Round(balance*100) / 100; balance > 0 {
+			operation, accountCode := constants.OperationPayout, constants.SystemAccountPayout
+			if req.Closure.Disposition == constants.ClosureDispositionForfeit {
+				operation, accountCode = constants.OperationForfeiture, constants.SystemAccountForfeiture
+			}
+
+			accountID, err := tx.GetSystemAccountID(accountCode)
+			if err != nil {
+				return err
+			}
+
+			settlement := &QueueRequest{
+				CashbackRequest: &models.CashbackRequest{
+					TuronUserID:    req.TuronUserID,
+					CashbackAmount: balance,
+					HostIP:         req.HostIP,
+					Reason:         req.Closure.Reason,
+				},
+			}
+			if result.History, err = q.post(tx, cashback, settlement, operation, accountID, -balance); err != nil {
+				return err
+			}
+			if err := q.publish(tx, constants.EventCashbackDebited, settlement, result.History, ""); err != nil {
+				return err
+			}
+			change.HistoryID = &result.History.ID
+		}
+
+		if err := tx.UpdateCashbackStatus(change); err != nil {
+			return err
+		}
+		cashback.Status = constants.AccountStatusClosed
+		cashback.DeletedAt = &change.CreatedAt
+		return q.publishClosure(tx, req, result.History)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return q.repo.CreateCashbackHistory(history)
+	return result, nil
 }
 
-func (q *CashbackQueue) handleDecrease(req *QueueRequest) error {
-	cashback, err := q.repo.GetCashbackByUserID(req.TuronUserID)
+// openCashback creates the user's wallet on first use. A user whose wallet
+// was closed does not get a new one.
+func (q *CashbackQueue) openCashback(ctx context.Context, tx core.CashbackTx, turonUserID int64) (*models.Cashback, error) {
+	account, err := tx.GetCashbackAccount(ctx, turonUserID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if account != nil && account.Status == constants.AccountStatusClosed {
+		return nil, ErrAccountClosed
 	}
 
-	if cashback == nil {
-		return errors.New("no cashback found for user")
+	cashback := &models.Cashback{
+		TuronUserID: turonUserID,
+	}
+	if err := tx.CreateCashback(cashback); err != nil {
+		return nil, err
+	}
+	return cashback, nil
+}
+
+// publish writes a cashback webhook event to the outbox in tx. history is
+// nil for rejections, which carry the reason they failed in message.
+func (q *CashbackQueue) publish(tx core.CashbackTx, eventType string, req *QueueRequest, history *models.CashbackHistory, message string) error {
+	data := models.CashbackEventData{
+		TuronUserID: req.TuronUserID,
+		Amount:      req.CashbackAmount,
+		Reason:      req.GetReason(),
+		Error:       message,
+	}
+	if history != nil {
+		data.HistoryID = &history.ID
+		data.BalanceAfter = history.BalanceAfter
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
 	}
+	return tx.CreateWebhookEvent(&models.WebhookEvent{
+		Type:     eventType,
+		SourceID: req.SourceID,
+		Payload:  payload,
+	})
+}
 
-	if cashback.CashbackAmount < req.CashbackAmount {
-		return errors.New("insufficient cashback amount")
+// publishPosting publishes a posting made outside increase and decrease as
+// cashback.credited or cashback.debited, by the direction it moved the
+// balance.
+func (q *CashbackQueue) publishPosting(tx core.CashbackTx, req *QueueRequest, history *models.CashbackHistory) error {
+	eventType := constants.EventCashbackCredited
+	if history.Amount != nil && *history.Amount < 0 {
+		eventType = constants.EventCashbackDebited
 	}
+	return q.publish(tx, eventType, req, history, "")
+}
 
-	newAmount := cashback.CashbackAmount - req.CashbackAmount
-	if err := q.repo.UpdateCashbackAmount(cashback.ID, newAmount); err != nil {
+// publishClosure publishes account.closed with the settlement of the
+// remaining balance, if there was one.
+func (q *CashbackQueue) publishClosure(tx core.CashbackTx, req *QueueRequest, settlement *models.CashbackHistory) error {
+	data := models.CashbackEventData{
+		TuronUserID: req.TuronUserID,
+		Reason:      req.Closure.Reason,
+		Disposition: req.Closure.Disposition,
+	}
+	if settlement != nil {
+		data.HistoryID = &settlement.ID
+		data.Amount = settlement.CashbackAmount
+		data.BalanceAfter = settlement.BalanceAfter
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
 		return err
 	}
+	return tx.CreateWebhookEvent(&models.WebhookEvent{
+		Type:     constants.EventAccountClosed,
+		SourceID: req.SourceID,
+		Payload:  payload,
+	})
+}
+
+// recordOutcome links the history row to the batch row or async operation
+// that asked for it, in the posting transaction.
+func (q *CashbackQueue) recordOutcome(tx core.CashbackTx, req *QueueRequest, history *models.CashbackHistory) error {
+	if req.BatchID != "" {
+		if err := tx.MarkBatchItemSucceeded(req.BatchID, req.BatchRow, history.ID); err != nil {
+			return err
+		}
+	}
+	if req.OperationID != "" {
+		return tx.MarkOperationSucceeded(req.OperationID, history.ID)
+	}
+	return nil
+}
+
+// post records a history row and its balanced ledger transaction: the user
+// wallet moves by the signed amount and the counterparty account by -amount.
+// The cached cashback balance is then refreshed from the ledger, and balance
+// streams are notified once the transaction commits.
+func (q *CashbackQueue) post(tx core.CashbackTx, cashback *models.Cashback, req *QueueRequest, operation string, counterpartyAccountID int64, amount float64) (*models.CashbackHistory, error) {
+	ledgerBalance, err := tx.GetLedgerBalance(cashback.ID)
+	if err != nil {
+		return nil, err
+	}
+	balanceAfter := math.Round((ledgerBalance+amount)*100) / 100
 
 	history := &models.CashbackHistory{
 		CashbackID:     cashback.ID,
+		TuronUserID:    cashback.TuronUserID,
 		SourceID:       req.SourceID,
+		Operation:      operation,
+		Reason:         req.GetReason(),
 		CashbackAmount: req.CashbackAmount,
+		Amount:         &amount,
+		BalanceAfter:   &balanceAfter,
 		HostIP:         req.HostIP,
-		Type:           req.Type,
+		IdempotencyKey: req.IdempotencyKey,
+	}
+	if err := tx.CreateCashbackHistory(history); err != nil {
+		return nil, err
+	}
+
+	userAccountID, err := tx.EnsureUserAccount(cashback.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Postings carry the history timestamp so as-of queries over the ledger
+	// agree with the history rows.
+	entries := []models.LedgerEntry{
+		{AccountID: userAccountID, Amount: amount, CreatedAt: history.CreatedAt},
+		{AccountID: counterpartyAccountID, Amount: -amount, CreatedAt: history.CreatedAt},
+	}
+	if err := tx.CreateLedgerEntries(history.ID, entries); err != nil {
+		return nil, err
+	}
+
+	if err := tx.UpdateRollups(history); err != nil {
+		return nil, err
+	}
+
+	if err := tx.NotifyCashbackUpdate(cashback.TuronUserID); err != nil {
+		return nil, err
 	}
-	return q.repo.CreateCashbackHistory(history)
+
+	balance, err := tx.RefreshCashbackBalance(cashback.ID)
+	if err != nil {
+		return nil, err
+	}
+	cashback.CashbackAmount = balance
+	return history, nil
 }
 
-func (q *CashbackQueue) Enqueue(opType string, req *models.CashbackRequest, sourceID int64) error {
-	queueReq := &QueueRequest{
+func (q *CashbackQueue) Enqueue(ctx context.Context, opType string, req *models.CashbackRequest, sourceID int64) (*OperationResult, error) {
+	return q.EnqueueRequest(ctx, opType, &QueueRequest{
 		CashbackRequest: req,
 		SourceID:        sourceID,
-	}
+	})
+}
 
+// EnqueueRequest is Enqueue for operations that need more than a source ID.
+// The operation runs to the end even if ctx is canceled meanwhile.
+func (q *CashbackQueue) EnqueueRequest(ctx context.Context, opType string, queueReq *QueueRequest) (*OperationResult, error) {
+	queueReq.ctx = context.WithoutCancel(ctx)
 	op := &CashbackOperation{
-		Type:     opType,
-		Request:  queueReq,
-		Response: make(chan error, 1),
+		Type:       opType,
+		Request:    queueReq,
+		Response:   make(chan operationResponse, 1),
+		EnqueuedAt: time.Now(),
 	}
 
 	q.operations <- op
+	metrics.QueueDepth.Set(float64(len(q.operations)))
 
-	return <-op.Response
+	response := <-op.Response
+	return response.result, response.err
 }
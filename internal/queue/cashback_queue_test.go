@@ -0,0 +1,354 @@
+package queue_test
+
+import (
+	constants "cashback-serv/const"
+	core "cashback-serv/internal/interfaces"
+	"cashback-serv/internal/queue"
+	"cashback-serv/models"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// sourceAccountBase offsets source ledger accounts from user accounts,
+	// which reuse the wallet ID.
+	sourceAccountBase   = 1000
+	adjustmentAccountID = 9000
+)
+
+// fakeRepository keeps wallets and the ledger in memory. Only the methods
+// the queue handlers reach are implemented; any other call panics on the
+// nil embedded interface.
+type fakeRepository struct {
+	mu        sync.Mutex
+	cashbacks map[int64]*models.Cashback
+	closed    map[int64]bool
+	balances  map[int64]float64
+	history   []models.CashbackHistory
+	entries   []models.LedgerEntry
+	events    []models.WebhookEvent
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		cashbacks: map[int64]*models.Cashback{},
+		closed:    map[int64]bool{},
+		balances:  map[int64]float64{},
+	}
+}
+
+// addCashback stores a wallet whose cached balance is cashback.CashbackAmount
+// and whose ledger sums to ledger.
+func (r *fakeRepository) addCashback(cashback models.Cashback, ledger float64) {
+	cashback.ID = int64(len(r.cashbacks) + 1)
+	if cashback.Status == "" {
+		cashback.Status = constants.AccountStatusActive
+	}
+	r.cashbacks[cashback.TuronUserID] = &cashback
+	r.balances[cashback.ID] = ledger
+}
+
+// WithTxContext runs fn under the repository lock. Nothing is rolled back
+// on error; the tests only fail operations before they write.
+func (r *fakeRepository) WithTxContext(ctx context.Context, fn func(tx core.CashbackTx) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fn(&fakeTx{repo: r})
+}
+
+// fakeTx is the transaction of fakeRepository; its caller holds the lock.
+type fakeTx struct {
+	core.CashbackTx
+	repo *fakeRepository
+}
+
+func (t *fakeTx) GetCashbackByUserIDForUpdate(turonUserID int64) (*models.Cashback, error) {
+	cashback, ok := t.repo.cashbacks[turonUserID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *cashback
+	return &copied, nil
+}
+
+func (t *fakeTx) GetCashbackAccount(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
+	if t.repo.closed[turonUserID] {
+		return &models.Cashback{TuronUserID: turonUserID, Status: constants.AccountStatusClosed}, nil
+	}
+	return t.GetCashbackByUserIDForUpdate(turonUserID)
+}
+
+func (t *fakeTx) CreateCashback(cashback *models.Cashback) error {
+	cashback.ID = int64(len(t.repo.cashbacks) + 1)
+	cashback.Status = constants.AccountStatusActive
+	stored := *cashback
+	t.repo.cashbacks[cashback.TuronUserID] = &stored
+	return nil
+}
+
+func (t *fakeTx) CreateCashbackHistory(history *models.CashbackHistory) error {
+	history.ID = int64(len(t.repo.history) + 1)
+	history.CreatedAt = time.Now()
+	t.repo.history = append(t.repo.history, *history)
+	return nil
+}
+
+func (t *fakeTx) EnsureUserAccount(cashbackID int64) (int64, error) {
+	return cashbackID, nil
+}
+
+func (t *fakeTx) EnsureSourceAccount(sourceID int64) (int64, error) {
+	return sourceAccountBase + sourceID, nil
+}
+
+func (t *fakeTx) GetSystemAccountID(code string) (int64, error) {
+	return adjustmentAccountID, nil
+}
+
+func (t *fakeTx) CreateLedgerEntries(historyID int64, entries []models.LedgerEntry) error {
+	for _, entry := range entries {
+		t.repo.balances[entry.AccountID] += entry.Amount
+	}
+	t.repo.entries = append(t.repo.entries, entries...)
+	return nil
+}
+
+func (t *fakeTx) GetLedgerBalance(cashbackID int64) (float64, error) {
+	return t.repo.balances[cashbackID], nil
+}
+
+func (t *fakeTx) RefreshCashbackBalance(cashbackID int64) (float64, error) {
+	balance := t.repo.balances[cashbackID]
+	for _, cashback := range t.repo.cashbacks {
+		if cashback.ID == cashbackID {
+			cashback.CashbackAmount = balance
+		}
+	}
+	return balance, nil
+}
+
+func (t *fakeTx) UpdateRollups(history *models.CashbackHistory) error {
+	return nil
+}
+
+func (t *fakeTx) CreateWebhookEvent(event *models.WebhookEvent) error {
+	t.repo.events = append(t.repo.events, *event)
+	return nil
+}
+
+func (t *fakeTx) NotifyCashbackUpdate(turonUserID int64) error {
+	return nil
+}
+
+func enqueue(repo *fakeRepository, opType string, turonUserID int64, amount float64) (*queue.OperationResult, error) {
+	q := queue.NewCashbackQueue(repo, nil)
+	return q.Enqueue(context.Background(), opType, &models.CashbackRequest{
+		TuronUserID:    turonUserID,
+		CashbackAmount: amount,
+	}, 1)
+}
+
+func TestPostingSign(t *testing.T) {
+	tests := []struct {
+		opType    string
+		operation string
+		event     string
+		amount    float64
+		balance   float64
+	}{
+		{opType: constants.Increase, operation: constants.OperationCredit, event: constants.EventCashbackCredited, amount: 4, balance: 14},
+		{opType: constants.Decrease, operation: constants.OperationDebit, event: constants.EventCashbackDebited, amount: -4, balance: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.opType, func(t *testing.T) {
+			repo := newFakeRepository()
+			repo.addCashback(models.Cashback{TuronUserID: 1, CashbackAmount: 10}, 10)
+
+			result, err := enqueue(repo, tt.opType, 1, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			history := result.History
+			if history.Operation != tt.operation {
+				t.Errorf("got operation %q, want %q", history.Operation, tt.operation)
+			}
+			if history.CashbackAmount != 4 {
+				t.Errorf("got cashback_amount %v, want the unsigned 4", history.CashbackAmount)
+			}
+			if *history.Amount != tt.amount {
+				t.Errorf("got amount %v, want %v", *history.Amount, tt.amount)
+			}
+			if *history.BalanceAfter != tt.balance {
+				t.Errorf("got balance_after %v, want %v", *history.BalanceAfter, tt.balance)
+			}
+			if result.Cashback.CashbackAmount != tt.balance {
+				t.Errorf("got balance %v, want %v", result.Cashback.CashbackAmount, tt.balance)
+			}
+
+			user, source := repo.entries[0], repo.entries[1]
+			if user.AccountID != 1 || user.Amount != tt.amount {
+				t.Errorf("got user entry %+v, want %v on account 1", user, tt.amount)
+			}
+			if source.AccountID != sourceAccountBase+1 || source.Amount != -tt.amount {
+				t.Errorf("got source entry %+v, want %v on account %d", source, -tt.amount, sourceAccountBase+1)
+			}
+			if len(repo.events) != 1 || repo.events[0].Type != tt.event {
+				t.Errorf("got events %+v, want one %s", repo.events, tt.event)
+			}
+		})
+	}
+}
+
+func TestInvalidAmount(t *testing.T) {
+	for _, opType := range []string{constants.Increase, constants.Decrease} {
+		for _, amount := range []float64{0, -5} {
+			repo := newFakeRepository()
+			repo.addCashback(models.Cashback{TuronUserID: 1, CashbackAmount: 10}, 10)
+
+			if _, err := enqueue(repo, opType, 1, amount); !errors.Is(err, queue.ErrInvalidAmount) {
+				t.Errorf("%s %v: got %v, want %v", opType, amount, err, queue.ErrInvalidAmount)
+			}
+			if len(repo.history) != 0 {
+				t.Errorf("%s %v: %d history rows were written", opType, amount, len(repo.history))
+			}
+		}
+	}
+}
+
+func TestDecreaseRejections(t *testing.T) {
+	tests := []struct {
+		name     string
+		cashback *models.Cashback
+		amount   float64
+		wantErr  error
+	}{
+		{name: "whole balance", cashback: &models.Cashback{TuronUserID: 1, CashbackAmount: 10}, amount: 10},
+		{name: "no wallet", amount: 5, wantErr: queue.ErrNoCashback},
+		{name: "over balance", cashback: &models.Cashback{TuronUserID: 1, CashbackAmount: 10}, amount: 10.01, wantErr: queue.ErrInsufficientCashback},
+		{
+			name:     "frozen",
+			cashback: &models.Cashback{TuronUserID: 1, CashbackAmount: 10, Status: constants.AccountStatusFrozen},
+			amount:   5,
+			wantErr:  queue.ErrAccountFrozen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			if tt.cashback != nil {
+				repo.addCashback(*tt.cashback, tt.cashback.CashbackAmount)
+			}
+
+			_, err := enqueue(repo, constants.Decrease, 1, tt.amount)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("got %v, want success", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if len(repo.history) != 0 {
+				t.Errorf("%d history rows were written", len(repo.history))
+			}
+			if len(repo.events) != 1 || repo.events[0].Type != constants.EventCashbackDebitRejected {
+				t.Errorf("got events %+v, want one %s", repo.events, constants.EventCashbackDebitRejected)
+			}
+		})
+	}
+}
+
+func TestIncreaseAccountStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		cashback *models.Cashback
+		closed   bool
+		wantErr  error
+	}{
+		{name: "new wallet"},
+		{name: "frozen debits only", cashback: &models.Cashback{TuronUserID: 1, Status: constants.AccountStatusFrozen}},
+		{
+			name:     "frozen credits",
+			cashback: &models.Cashback{TuronUserID: 1, Status: constants.AccountStatusFrozen, FreezeCredits: true},
+			wantErr:  queue.ErrAccountFrozen,
+		},
+		{name: "closed", closed: true, wantErr: queue.ErrAccountClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			if tt.cashback != nil {
+				repo.addCashback(*tt.cashback, 0)
+			}
+			repo.closed[1] = tt.closed
+
+			result, err := enqueue(repo, constants.Increase, 1, 5)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("got %v, want success", err)
+				}
+				if result.Cashback.CashbackAmount != 5 {
+					t.Errorf("got balance %v, want 5", result.Cashback.CashbackAmount)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if len(repo.history) != 0 || len(repo.cashbacks) > 1 {
+				t.Errorf("got %d history rows and %d wallets, want nothing written", len(repo.history), len(repo.cashbacks))
+			}
+		})
+	}
+}
+
+func TestReconcileDelta(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored float64
+		ledger float64
+		delta  float64
+	}{
+		{name: "ledger short", stored: 10, ledger: 7.5, delta: 2.5},
+		{name: "ledger over", stored: 5, ledger: 8, delta: -3},
+		{name: "sub-cent drift", stored: 5, ledger: 5.004},
+		{name: "in line", stored: 5, ledger: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			repo.addCashback(models.Cashback{TuronUserID: 1, CashbackAmount: tt.stored}, tt.ledger)
+
+			result, err := enqueue(repo, constants.Reconcile, 1, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.delta == 0 {
+				if result.History != nil || len(repo.entries) != 0 {
+					t.Errorf("posted %+v, want nothing", result.History)
+				}
+				return
+			}
+
+			history := result.History
+			if history.Operation != constants.OperationAdjustment || *history.Amount != tt.delta {
+				t.Errorf("got %s of %v, want an adjustment of %v", history.Operation, *history.Amount, tt.delta)
+			}
+			if history.CashbackAmount != max(tt.delta, -tt.delta) {
+				t.Errorf("got cashback_amount %v, want %v", history.CashbackAmount, max(tt.delta, -tt.delta))
+			}
+			if counterparty := repo.entries[1]; counterparty.AccountID != adjustmentAccountID || counterparty.Amount != -tt.delta {
+				t.Errorf("got counterparty entry %+v, want %v on the adjustment account", counterparty, -tt.delta)
+			}
+			if result.Cashback.CashbackAmount != tt.stored {
+				t.Errorf("got balance %v, want the stored %v", result.Cashback.CashbackAmount, tt.stored)
+			}
+		})
+	}
+}
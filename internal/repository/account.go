@@ -0,0 +1,77 @@
+package repository
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"fmt"
+	"time"
+)
+
+// UpdateCashbackStatus moves a wallet to change.ToStatus and records the
+// transition. Closing also soft-deletes the wallet. Callers hold the row
+// lock and have checked that the transition is allowed.
+func (r *CashbackRepository) UpdateCashbackStatus(change *models.AccountStatusChange) error {
+	query := `
+		UPDATE cashback
+		SET
+			status = $status$,
+			freeze_credits = $freeze_credits$,
+			status_reason = $status_reason$,
+			status_changed_at = $now$,
+			updated_at = $now$`
+
+	now := time.Now()
+	args := map[string]interface{}{
+		"$status$":         change.ToStatus,
+		"$freeze_credits$": change.FreezeCredits,
+		"$status_reason$":  change.Reason,
+		"$now$":            now,
+		"$id$":             change.CashbackID,
+	}
+	if change.ToStatus == constants.AccountStatusClosed {
+		query += ", deleted_at = $now$"
+	}
+	query += " WHERE id = $id$"
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if _, err := r.db.Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to update cashback status: %w", err)
+	}
+
+	eventQuery := `
+		INSERT INTO cashback_status_events (
+			cashback_id,
+			from_status,
+			to_status,
+			reason,
+			operator,
+			history_id,
+			created_at
+		) VALUES (
+			$cashback_id$,
+			$from_status$,
+			$to_status$,
+			$reason$,
+			$operator$,
+			$history_id$,
+			$created_at$
+		)`
+
+	eventArgs := map[string]interface{}{
+		"$cashback_id$": change.CashbackID,
+		"$from_status$": change.FromStatus,
+		"$to_status$":   change.ToStatus,
+		"$reason$":      change.Reason,
+		"$operator$":    change.Operator,
+		"$history_id$":  change.HistoryID,
+		"$created_at$":  now,
+	}
+
+	namedQuery, namedArgs = buildNamedQuery(eventQuery, eventArgs)
+	if _, err := r.db.Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to record cashback status change: %w", err)
+	}
+
+	change.CreatedAt = now
+	return nil
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrAdjustmentNotPending = errors.New("adjustment is no longer awaiting a decision")
+
+const adjustmentColumns = `
+			id,
+			turon_user_id,
+			amount,
+			reason_code,
+			comment,
+			requested_by,
+			approved_by,
+			rejected_by,
+			decision_comment,
+			status,
+			history_id,
+			error,
+			host_ip,
+			created_at,
+			updated_at,
+			decided_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAdjustment(row rowScanner) (*models.CashbackAdjustment, error) {
+	a := &models.CashbackAdjustment{}
+	err := row.Scan(
+		&a.ID,
+		&a.TuronUserID,
+		&a.Amount,
+		&a.ReasonCode,
+		&a.Comment,
+		&a.RequestedBy,
+		&a.ApprovedBy,
+		&a.RejectedBy,
+		&a.DecisionComment,
+		&a.Status,
+		&a.HistoryID,
+		&a.Error,
+		&a.HostIP,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+		&a.DecidedAt,
+	)
+	return a, err
+}
+
+func (r *CashbackRepository) CreateAdjustment(adjustment *models.CashbackAdjustment) error {
+	query := `
+		INSERT INTO cashback_adjustments (
+			turon_user_id,
+			amount,
+			reason_code,
+			comment,
+			requested_by,
+			status,
+			host_ip,
+			created_at,
+			updated_at
+		) VALUES (
+			$turon_user_id$,
+			$amount$,
+			$reason_code$,
+			$comment$,
+			$requested_by$,
+			$status$,
+			$host_ip$,
+			$created_at$,
+			$updated_at$
+		) RETURNING id`
+
+	now := time.Now()
+	args := map[string]interface{}{
+		"$turon_user_id$": adjustment.TuronUserID,
+		"$amount$":        adjustment.Amount,
+		"$reason_code$":   adjustment.ReasonCode,
+		"$comment$":       adjustment.Comment,
+		"$requested_by$":  adjustment.RequestedBy,
+		"$status$":        adjustment.Status,
+		"$host_ip$":       adjustment.HostIP,
+		"$created_at$":    now,
+		"$updated_at$":    now,
+	}
+
+	adjustment.CreatedAt = now
+	adjustment.UpdatedAt = now
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	return r.db.QueryRow(namedQuery, namedArgs...).Scan(&adjustment.ID)
+}
+
+func (r *CashbackRepository) GetAdjustmentByID(id int64) (*models.CashbackAdjustment, error) {
+	query := `
+		SELECT` + adjustmentColumns + `
+		FROM cashback_adjustments
+		WHERE id = $id$`
+
+	args := map[string]interface{}{
+		"$id$": id,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	adjustment, err := scanAdjustment(r.db.QueryRow(namedQuery, namedArgs...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get adjustment: %w", err)
+	}
+	return adjustment, nil
+}
+
+func (r *CashbackRepository) ListAdjustments(status string, pagination *models.Pagination) ([]models.CashbackAdjustment, error) {
+	countQuery := `
+		SELECT COUNT(*)
+		FROM cashback_adjustments
+		WHERE 1 = 1`
+
+	args := map[string]interface{}{}
+	if status != "" {
+		countQuery += " AND status = $status$"
+		args["$status$"] = status
+	}
+
+	namedCountQuery, namedCountArgs := buildNamedQuery(countQuery, args)
+	var total int64
+	if err := r.db.QueryRow(namedCountQuery, namedCountArgs...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to get total count: %w", err)
+	}
+
+	pagination.ItemTotal = total
+	pagination.PageTotal = (total + pagination.PageSize - 1) / pagination.PageSize
+
+	query := `
+		SELECT` + adjustmentColumns + `
+		FROM cashback_adjustments
+		WHERE 1 = 1`
+
+	if status != "" {
+		query += " AND status = $status$"
+	}
+	query += " ORDER BY created_at DESC LIMIT $limit$ OFFSET $offset$"
+	args["$limit$"] = pagination.Limit
+	args["$offset$"] = pagination.Offset
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	rows, err := r.db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query adjustments: %w", err)
+	}
+	defer rows.Close()
+
+	adjustments := []models.CashbackAdjustment{}
+	for rows.Next() {
+		adjustment, err := scanAdjustment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan adjustment row: %w", err)
+		}
+		adjustments = append(adjustments, *adjustment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating adjustment rows: %w", err)
+	}
+
+	return adjustments, nil
+}
+
+// MarkAdjustmentPosted links an adjustment to its history row. It runs inside
+// the posting transaction and fails with ErrAdjustmentNotPending if another
+// decision got there first.
+func (r *CashbackRepository) MarkAdjustmentPosted(id, historyID int64, approvedBy, comment string) error {
+	query := `
+		UPDATE cashback_adjustments
+		SET 
+			status = $posted$,
+			history_id = $history_id$,
+			approved_by = COALESCE($approved_by$, approved_by),
+			decision_comment = COALESCE($decision_comment$, decision_comment),
+			decided_at = $now$,
+			updated_at = $now$
+		WHERE id = $id$
+		AND status IN ($pending_approval$, $approved$)`
+
+	args := map[string]interface{}{
+		"$posted$":           constants.AdjustmentStatusPosted,
+		"$history_id$":       historyID,
+		"$approved_by$":      nullString(approvedBy),
+		"$decision_comment$": nullString(comment),
+		"$now$":              time.Now(),
+		"$id$":               id,
+		"$pending_approval$": constants.AdjustmentStatusPendingApproval,
+		"$approved$":         constants.AdjustmentStatusApproved,
+	}
+
+	return r.execAdjustmentTransition(args, query)
+}
+
+func (r *CashbackRepository) MarkAdjustmentRejected(id int64, rejectedBy, comment string) error {
+	query := `
+		UPDATE cashback_adjustments
+		SET 
+			status = $rejected$,
+			rejected_by = $rejected_by$,
+			decision_comment = $decision_comment$,
+			decided_at = $now$,
+			updated_at = $now$
+		WHERE id = $id$
+		AND status = $pending_approval$`
+
+	args := map[string]interface{}{
+		"$rejected$":         constants.AdjustmentStatusRejected,
+		"$rejected_by$":      rejectedBy,
+		"$decision_comment$": nullString(comment),
+		"$now$":              time.Now(),
+		"$id$":               id,
+		"$pending_approval$": constants.AdjustmentStatusPendingApproval,
+	}
+
+	return r.execAdjustmentTransition(args, query)
+}
+
+func (r *CashbackRepository) MarkAdjustmentFailed(id int64, approvedBy, comment, message string) error {
+	query := `
+		UPDATE cashback_adjustments
+		SET 
+			status = $failed$,
+			approved_by = COALESCE($approved_by$, approved_by),
+			decision_comment = COALESCE($decision_comment$, decision_comment),
+			error = $error$,
+			decided_at = $now$,
+			updated_at = $now$
+		WHERE id = $id$
+		AND status IN ($pending_approval$, $approved$)`
+
+	args := map[string]interface{}{
+		"$failed$":           constants.AdjustmentStatusFailed,
+		"$approved_by$":      nullString(approvedBy),
+		"$decision_comment$": nullString(comment),
+		"$error$":            message,
+		"$now$":              time.Now(),
+		"$id$":               id,
+		"$pending_approval$": constants.AdjustmentStatusPendingApproval,
+		"$approved$":         constants.AdjustmentStatusApproved,
+	}
+
+	return r.execAdjustmentTransition(args, query)
+}
+
+func (r *CashbackRepository) execAdjustmentTransition(args map[string]interface{}, query string) error {
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := r.db.Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to update adjustment: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update adjustment: %w", err)
+	}
+	if affected == 0 {
+		return ErrAdjustmentNotPending
+	}
+	return nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
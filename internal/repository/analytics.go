@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"cashback-serv/models"
+	"fmt"
+	"time"
+)
+
+// UpdateRollups adds a history row to the hourly analytics rollups. It runs
+// in the transaction that writes the row, so the rollups never drift from
+// the history.
+func (r *CashbackRepository) UpdateRollups(history *models.CashbackHistory) error {
+	if history.Amount == nil {
+		return nil
+	}
+
+	var credited, debited float64
+	if *history.Amount > 0 {
+		credited = *history.Amount
+	} else {
+		debited = -*history.Amount
+	}
+
+	query := `
+		INSERT INTO cashback_rollups (
+			bucket,
+			source_id,
+			operation,
+			credited,
+			debited,
+			entries
+		) VALUES (
+			$bucket$,
+			$source_id$,
+			$operation$,
+			$credited$,
+			$debited$,
+			1
+		)
+		ON CONFLICT (bucket, source_id, operation) DO UPDATE SET
+			credited = cashback_rollups.credited + EXCLUDED.credited,
+			debited = cashback_rollups.debited + EXCLUDED.debited,
+			entries = cashback_rollups.entries + 1`
+
+	args := map[string]interface{}{
+		"$bucket$":    history.CreatedAt.UTC().Truncate(time.Hour),
+		"$source_id$": history.SourceID,
+		"$operation$": history.Operation,
+		"$credited$":  credited,
+		"$debited$":   debited,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if _, err := r.db.Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to update cashback rollups: %w", err)
+	}
+
+	userQuery := `
+		INSERT INTO cashback_user_rollups (
+			bucket,
+			cashback_id,
+			credited,
+			debited,
+			entries
+		) VALUES (
+			$bucket$,
+			$cashback_id$,
+			$credited$,
+			$debited$,
+			1
+		)
+		ON CONFLICT (bucket, cashback_id) DO UPDATE SET
+			credited = cashback_user_rollups.credited + EXCLUDED.credited,
+			debited = cashback_user_rollups.debited + EXCLUDED.debited,
+			entries = cashback_user_rollups.entries + 1`
+
+	userArgs := map[string]interface{}{
+		"$bucket$":      history.CreatedAt.UTC().Truncate(time.Hour),
+		"$cashback_id$": history.CashbackID,
+		"$credited$":    credited,
+		"$debited$":     debited,
+	}
+
+	namedQuery, namedArgs = buildNamedQuery(userQuery, userArgs)
+	if _, err := r.db.Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to update user rollups: %w", err)
+	}
+	return nil
+}
+
+// analyticsPeriod groups hourly buckets into the periods of the filter's
+// interval in its time zone. The interval is validated by the service.
+const analyticsPeriod = "(date_trunc($interval$, r.bucket AT TIME ZONE $tz$) AT TIME ZONE $tz$)"
+
+func analyticsArgs(filter *models.AnalyticsFilter) map[string]interface{} {
+	return map[string]interface{}{
+		"$interval$": filter.Interval,
+		"$tz$":       filter.TimeZone,
+		"$from$":     *filter.From,
+		"$until$":    *filter.Until,
+	}
+}
+
+func (r *CashbackRepository) GetAnalyticsTotals(filter *models.AnalyticsFilter) ([]models.AnalyticsTotal, error) {
+	groupColumns := "''::text, ''::text"
+	groupBy := "1"
+	switch filter.GroupBy {
+	case "source":
+		groupColumns = "COALESCE(s.slug, ''), ''::text"
+		groupBy = "1, 2"
+	case "operation":
+		groupColumns = "''::text, r.operation"
+		groupBy = "1, 3"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s AS period,
+			%s,
+			SUM(r.credited),
+			SUM(r.debited),
+			SUM(r.entries)
+		FROM cashback_rollups r
+		LEFT JOIN sources s ON s.id = r.source_id
+		WHERE r.bucket >= $from$
+		AND r.bucket < $until$
+		GROUP BY %s
+		ORDER BY %s`, analyticsPeriod, groupColumns, groupBy, groupBy)
+
+	namedQuery, namedArgs := buildNamedQuery(query, analyticsArgs(filter))
+	rows, err := r.db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analytics totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []models.AnalyticsTotal{}
+	for rows.Next() {
+		var total models.AnalyticsTotal
+		if err := rows.Scan(
+			&total.Period,
+			&total.Source,
+			&total.Operation,
+			&total.Credited,
+			&total.Debited,
+			&total.Entries,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan analytics total: %w", err)
+		}
+		total.Net = total.Credited - total.Debited
+		totals = append(totals, total)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating analytics totals: %w", err)
+	}
+	return totals, nil
+}
+
+// GetLiability returns the outstanding balance at the end of every period:
+// everything posted before the range plus the running net within it.
+func (r *CashbackRepository) GetLiability(filter *models.AnalyticsFilter) ([]models.LiabilityPoint, error) {
+	query := fmt.Sprintf(`
+		WITH opening AS (
+			SELECT COALESCE(SUM(credited - debited), 0) AS balance
+			FROM cashback_rollups
+			WHERE bucket < $from$
+		),
+		periods AS (
+			SELECT
+				%s AS period,
+				SUM(r.credited - r.debited) AS net
+			FROM cashback_rollups r
+			WHERE r.bucket >= $from$
+			AND r.bucket < $until$
+			GROUP BY 1
+		)
+		SELECT
+			p.period,
+			o.balance + SUM(p.net) OVER (ORDER BY p.period)
+		FROM periods p
+		CROSS JOIN opening o
+		ORDER BY p.period`, analyticsPeriod)
+
+	namedQuery, namedArgs := buildNamedQuery(query, analyticsArgs(filter))
+	rows, err := r.db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query liability: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.LiabilityPoint{}
+	for rows.Next() {
+		var point models.LiabilityPoint
+		if err := rows.Scan(&point.Period, &point.Liability); err != nil {
+			return nil, fmt.Errorf("failed to scan liability point: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating liability points: %w", err)
+	}
+	return points, nil
+}
+
+func (r *CashbackRepository) GetTopEarners(filter *models.AnalyticsFilter) ([]models.TopEarner, error) {
+	query := `
+		SELECT
+			c.turon_user_id,
+			r.cashback_id,
+			SUM(r.credited),
+			SUM(r.debited),
+			SUM(r.entries)
+		FROM cashback_user_rollups r
+		JOIN cashback c ON c.id = r.cashback_id
+		WHERE r.bucket >= $from$
+		AND r.bucket < $until$
+		GROUP BY c.turon_user_id, r.cashback_id
+		ORDER BY SUM(r.credited) DESC, c.turon_user_id
+		LIMIT $limit$`
+
+	args := map[string]interface{}{
+		"$from$":  *filter.From,
+		"$until$": *filter.Until,
+		"$limit$": filter.Limit,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	rows, err := r.db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top earners: %w", err)
+	}
+	defer rows.Close()
+
+	earners := []models.TopEarner{}
+	for rows.Next() {
+		var earner models.TopEarner
+		if err := rows.Scan(
+			&earner.TuronUserID,
+			&earner.CashbackID,
+			&earner.Credited,
+			&earner.Debited,
+			&earner.Entries,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan top earner: %w", err)
+		}
+		earner.Net = earner.Credited - earner.Debited
+		earners = append(earners, earner)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top earners: %w", err)
+	}
+	return earners, nil
+}
+
+func (r *CashbackRepository) GetActiveUsers(filter *models.AnalyticsFilter) ([]models.ActiveUsersPoint, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			%s AS period,
+			COUNT(DISTINCT r.cashback_id)
+		FROM cashback_user_rollups r
+		WHERE r.bucket >= $from$
+		AND r.bucket < $until$
+		GROUP BY 1
+		ORDER BY 1`, analyticsPeriod)
+
+	namedQuery, namedArgs := buildNamedQuery(query, analyticsArgs(filter))
+	rows, err := r.db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active users: %w", err)
+	}
+	defer rows.Close()
+
+	points := []models.ActiveUsersPoint{}
+	for rows.Next() {
+		var point models.ActiveUsersPoint
+		if err := rows.Scan(&point.Period, &point.ActiveUsers); err != nil {
+			return nil, fmt.Errorf("failed to scan active users point: %w", err)
+		}
+		points = append(points, point)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating active users points: %w", err)
+	}
+	return points, nil
+}
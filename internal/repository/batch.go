@@ -0,0 +1,333 @@
+package repository
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrBatchItemDone = errors.New("batch row has already been posted")
+
+// CreateBatch stores a batch and its rows as pending in one transaction. It
+// reports false, storing nothing, when a batch with the same ID exists.
+func (r *CashbackRepository) CreateBatch(ctx context.Context, batch *models.CashbackBatch) (bool, error) {
+	tx, err := r.pool.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO cashback_batches (
+			id,
+			checksum,
+			status,
+			total,
+			host_ip,
+			created_at,
+			updated_at
+		) VALUES (
+			$id$,
+			$checksum$,
+			$status$,
+			$total$,
+			$host_ip$,
+			$now$,
+			$now$
+		)
+		ON CONFLICT (id) DO NOTHING`
+
+	args := map[string]interface{}{
+		"$id$":       batch.ID,
+		"$checksum$": batch.Checksum,
+		"$status$":   constants.BatchStatusProcessing,
+		"$total$":    len(batch.Items),
+		"$host_ip$":  batch.HostIP,
+		"$now$":      time.Now(),
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := tx.Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return false, fmt.Errorf("failed to create batch: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	// COPY keeps inserting tens of thousands of rows to a single round trip.
+	stmt, err := tx.Prepare(pq.CopyIn("cashback_batch_items",
+		"batch_id", "row_number", "turon_user_id", "cashback_amount", "reason", "status"))
+	if err != nil {
+		return false, fmt.Errorf("failed to prepare batch rows: %w", err)
+	}
+	for _, item := range batch.Items {
+		if _, err := stmt.Exec(batch.ID, item.Row, item.TuronUserID, item.CashbackAmount, item.Reason, constants.BatchItemStatusPending); err != nil {
+			stmt.Close()
+			return false, fmt.Errorf("failed to copy batch row %d: %w", item.Row, err)
+		}
+	}
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return false, fmt.Errorf("failed to copy batch rows: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return false, fmt.Errorf("failed to copy batch rows: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// GetBatch returns the batch with every row and its outcome.
+func (r *CashbackRepository) GetBatch(ctx context.Context, id string) (*models.CashbackBatch, error) {
+	query := `
+		SELECT
+			id,
+			checksum,
+			status,
+			total,
+			host_ip,
+			created_at,
+			updated_at
+		FROM cashback_batches
+		WHERE id = $id$`
+
+	args := map[string]interface{}{
+		"$id$": id,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	batch := &models.CashbackBatch{}
+	err := r.conn(ctx).QueryRow(namedQuery, namedArgs...).Scan(
+		&batch.ID,
+		&batch.Checksum,
+		&batch.Status,
+		&batch.Total,
+		&batch.HostIP,
+		&batch.CreatedAt,
+		&batch.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get batch: %w", err)
+	}
+
+	batch.Items, err = r.listBatchItems(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range batch.Items {
+		switch item.Status {
+		case constants.BatchItemStatusSucceeded:
+			batch.Succeeded++
+		case constants.BatchItemStatusFailed:
+			batch.Failed++
+		default:
+			batch.Pending++
+		}
+	}
+	return batch, nil
+}
+
+// ListProcessingBatches returns the ID and host IP of every batch still
+// being processed, so unfinished ones can be picked up after a restart.
+func (r *CashbackRepository) ListProcessingBatches(ctx context.Context) ([]models.CashbackBatch, error) {
+	query := `
+		SELECT
+			id,
+			host_ip
+		FROM cashback_batches
+		WHERE status = $processing$
+		ORDER BY created_at`
+
+	args := map[string]interface{}{
+		"$processing$": constants.BatchStatusProcessing,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	rows, err := r.conn(ctx).Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query processing batches: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []models.CashbackBatch
+	for rows.Next() {
+		var batch models.CashbackBatch
+		if err := rows.Scan(&batch.ID, &batch.HostIP); err != nil {
+			return nil, fmt.Errorf("failed to scan batch: %w", err)
+		}
+		batches = append(batches, batch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating batches: %w", err)
+	}
+	return batches, nil
+}
+
+// ListUnfinishedBatchItems returns the rows of a batch that have not been
+// posted yet, failed ones included, in file order.
+func (r *CashbackRepository) ListUnfinishedBatchItems(ctx context.Context, batchID string) ([]models.BatchItem, error) {
+	return r.listBatchItems(ctx, batchID, true)
+}
+
+func (r *CashbackRepository) listBatchItems(ctx context.Context, batchID string, unfinished bool) ([]models.BatchItem, error) {
+	query := `
+		SELECT
+			row_number,
+			turon_user_id,
+			cashback_amount,
+			reason,
+			status,
+			history_id,
+			error
+		FROM cashback_batch_items
+		WHERE batch_id = $batch_id$`
+
+	args := map[string]interface{}{
+		"$batch_id$": batchID,
+	}
+	if unfinished {
+		query += " AND status <> $succeeded$"
+		args["$succeeded$"] = constants.BatchItemStatusSucceeded
+	}
+	query += " ORDER BY row_number"
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	rows, err := r.conn(ctx).Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query batch rows: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.BatchItem{}
+	for rows.Next() {
+		var (
+			item      models.BatchItem
+			historyID sql.NullInt64
+			message   sql.NullString
+		)
+		if err := rows.Scan(
+			&item.Row,
+			&item.TuronUserID,
+			&item.CashbackAmount,
+			&item.Reason,
+			&item.Status,
+			&historyID,
+			&message,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan batch row: %w", err)
+		}
+		if historyID.Valid {
+			item.HistoryID = &historyID.Int64
+		}
+		if message.Valid {
+			item.Error = &message.String
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating batch rows: %w", err)
+	}
+	return items, nil
+}
+
+// MarkBatchItemSucceeded links a batch row to its history row. It runs inside
+// the posting transaction and fails with ErrBatchItemDone if the row was
+// already posted, which rolls the duplicate credit back.
+func (r *CashbackRepository) MarkBatchItemSucceeded(batchID string, row int, historyID int64) error {
+	query := `
+		UPDATE cashback_batch_items
+		SET
+			status = $succeeded$,
+			history_id = $history_id$,
+			error = NULL,
+			updated_at = $now$
+		WHERE batch_id = $batch_id$
+		AND row_number = $row_number$
+		AND status <> $succeeded$`
+
+	args := map[string]interface{}{
+		"$succeeded$":  constants.BatchItemStatusSucceeded,
+		"$history_id$": historyID,
+		"$now$":        time.Now(),
+		"$batch_id$":   batchID,
+		"$row_number$": row,
+	}
+
+	return execBatchItemTransition(r.db, args, query)
+}
+
+func (r *CashbackRepository) MarkBatchItemFailed(ctx context.Context, batchID string, row int, message string) error {
+	query := `
+		UPDATE cashback_batch_items
+		SET
+			status = $failed$,
+			error = $error$,
+			updated_at = $now$
+		WHERE batch_id = $batch_id$
+		AND row_number = $row_number$
+		AND status <> $succeeded$`
+
+	args := map[string]interface{}{
+		"$failed$":     constants.BatchItemStatusFailed,
+		"$error$":      message,
+		"$now$":        time.Now(),
+		"$batch_id$":   batchID,
+		"$row_number$": row,
+		"$succeeded$":  constants.BatchItemStatusSucceeded,
+	}
+
+	return execBatchItemTransition(r.conn(ctx), args, query)
+}
+
+func execBatchItemTransition(db dbtx, args map[string]interface{}, query string) error {
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := db.Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to update batch row: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update batch row: %w", err)
+	}
+	if affected == 0 {
+		return ErrBatchItemDone
+	}
+	return nil
+}
+
+func (r *CashbackRepository) SetBatchStatus(ctx context.Context, id, status string) error {
+	query := `
+		UPDATE cashback_batches
+		SET
+			status = $status$,
+			updated_at = $now$
+		WHERE id = $id$`
+
+	args := map[string]interface{}{
+		"$status$": status,
+		"$now$":    time.Now(),
+		"$id$":     id,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if _, err := r.conn(ctx).Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to update batch status: %w", err)
+	}
+	return nil
+}
@@ -1,19 +1,84 @@
 package repository
 
 import (
+	constants "cashback-serv/const"
+	core "cashback-serv/internal/interfaces"
+	"cashback-serv/internal/tracing"
 	"cashback-serv/models"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrDuplicateIdempotencyKey is returned when a history row with the same
+// idempotency key exists already.
+var ErrDuplicateIdempotencyKey = errors.New("idempotency key has already been used")
+
+// dbtx runs queries on either the pool or a transaction, so the same
+// repository methods run either standalone or inside WithTx. tracedConn
+// implements it.
+type dbtx interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// rows is the part of *sql.Rows the repositories read.
+type rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
 type CashbackRepository struct {
-	db *sql.DB
+	db   dbtx
+	pool *sql.DB
+	// inTx is set on the repository WithTx hands to its callback.
+	inTx bool
 }
 
 func NewCashbackRepository(db *sql.DB) *CashbackRepository {
-	return &CashbackRepository{db: db}
+	return &CashbackRepository{db: &tracedConn{conn: db, ctx: context.Background()}, pool: db}
+}
+
+func (r *CashbackRepository) WithTx(fn func(tx core.CashbackTx) error) error {
+	return r.WithTxContext(context.Background(), fn)
+}
+
+// WithTxContext is WithTx within ctx. When ctx carries a trace, every query
+// in the transaction and the commit get their own span in it.
+func (r *CashbackRepository) WithTxContext(ctx context.Context, fn func(tx core.CashbackTx) error) error {
+	tx, err := r.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	traced := trace.SpanContextFromContext(ctx).IsValid()
+	db := &tracedConn{conn: tx, ctx: ctx}
+
+	if err := fn(&CashbackRepository{db: db, pool: r.pool, inTx: true}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if traced {
+		_, span := tracing.Tracer.Start(ctx, "db commit", trace.WithSpanKind(trace.SpanKindClient))
+		err = tx.Commit()
+		tracing.End(span, err)
+	} else {
+		err = tx.Commit()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
 func (r *CashbackRepository) CreateCashback(cashback *models.Cashback) error {
@@ -38,6 +103,10 @@ func (r *CashbackRepository) CreateCashback(cashback *models.Cashback) error {
 		"$updated_at$":      now,
 	}
 
+	cashback.Status = constants.AccountStatusActive
+	cashback.CreatedAt = now
+	cashback.UpdatedAt = now
+
 	namedQuery, namedArgs := buildNamedQuery(query, args)
 	return r.db.QueryRow(namedQuery, namedArgs...).Scan(&cashback.ID)
 }
@@ -47,17 +116,25 @@ func (r *CashbackRepository) CreateCashbackHistory(history *models.CashbackHisto
 		INSERT INTO "cashback_history" (
 			cashback_id,
 			source_id,
+			operation,
+			reason,
 			cashback_amount,
+			amount,
+			balance_after,
 			host_ip,
-			type,
+			idempotency_key,
 			created_at,
 			updated_at
 		) VALUES (
 			$cashback_id$,
 			$source_id$,
+			$operation$,
+			$reason$,
 			$cashback_amount$,
+			$amount$,
+			$balance_after$,
 			$host_ip$,
-			$type$,
+			$idempotency_key$,
 			$created_at$,
 			$updated_at$
 		) RETURNING id`
@@ -65,95 +142,263 @@ func (r *CashbackRepository) CreateCashbackHistory(history *models.CashbackHisto
 	now := time.Now()
 	args := map[string]interface{}{
 		"$cashback_id$":     history.CashbackID,
-		"$source_id$":       history.SourceID,
+		"$source_id$":       nullInt64(history.SourceID),
+		"$operation$":       history.Operation,
+		"$reason$":          history.Reason,
 		"$cashback_amount$": history.CashbackAmount,
+		"$amount$":          history.Amount,
+		"$balance_after$":   history.BalanceAfter,
 		"$host_ip$":         history.HostIP,
-		"$type$":            history.Type,
+		"$idempotency_key$": nullString(history.IdempotencyKey),
 		"$created_at$":      now,
 		"$updated_at$":      now,
 	}
 
+	history.CreatedAt = now
+	history.UpdatedAt = now
+
 	namedQuery, namedArgs := buildNamedQuery(query, args)
-	return r.db.QueryRow(namedQuery, namedArgs...).Scan(&history.ID)
+	err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&history.ID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_cashback_history_idempotency_key" {
+		return ErrDuplicateIdempotencyKey
+	}
+	return err
 }
 
-func (r *CashbackRepository) GetCashbackByUserID(turonUserID int64) (*models.Cashback, error) {
+// GetCashbackHistoryByIdempotencyKey returns the history row written by the
+// request with the key, or nil.
+func (r *CashbackRepository) GetCashbackHistoryByIdempotencyKey(ctx context.Context, key string) (*models.CashbackHistory, error) {
+	args := map[string]interface{}{
+		"$idempotency_key$": key,
+	}
+
+	history, err := queryHistory(r.conn(ctx), historySelectQuery+" AND ch.idempotency_key = $idempotency_key$", args)
+	if err != nil || len(history) == 0 {
+		return nil, err
+	}
+	return &history[0], nil
+}
+
+func (r *CashbackRepository) GetCashbackByUserID(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
+	return getCashbackByUserID(r.conn(ctx), turonUserID, false)
+}
+
+func (r *CashbackRepository) GetCashbackByUserIDForUpdate(turonUserID int64) (*models.Cashback, error) {
+	return getCashbackByUserID(r.db, turonUserID, true)
+}
+
+func getCashbackByUserID(db dbtx, turonUserID int64, forUpdate bool) (*models.Cashback, error) {
 	query := `
-		SELECT 
-			id,
-			cashback_amount,
-			turon_user_id,
-			created_at,
-			updated_at,
-			deleted_at
+		SELECT ` + cashbackColumns + `
 		FROM cashback
 		WHERE turon_user_id = $turon_user_id$
 		AND deleted_at IS NULL`
 
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	args := map[string]interface{}{
+		"$turon_user_id$": turonUserID,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	cashback, err := scanCashback(db.QueryRow(namedQuery, namedArgs...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return cashback, err
+}
+
+// GetCashbackAccount returns the user's most recent wallet, live or soft
+// deleted, so callers can tell a closed or erased account from none at all.
+func (r *CashbackRepository) GetCashbackAccount(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
+	query := `
+		SELECT ` + cashbackColumns + `
+		FROM cashback
+		WHERE turon_user_id = $turon_user_id$
+		ORDER BY deleted_at IS NULL DESC, id DESC
+		LIMIT 1`
+
 	args := map[string]interface{}{
 		"$turon_user_id$": turonUserID,
 	}
 
 	namedQuery, namedArgs := buildNamedQuery(query, args)
+	cashback, err := scanCashback(r.conn(ctx).QueryRow(namedQuery, namedArgs...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cashback account: %w", err)
+	}
+	return cashback, nil
+}
+
+const cashbackColumns = `
+			id,
+			cashback_amount,
+			turon_user_id,
+			status,
+			freeze_credits,
+			status_reason,
+			status_changed_at,
+			created_at,
+			updated_at,
+			deleted_at`
+
+func scanCashback(row rowScanner) (*models.Cashback, error) {
 	cashback := &models.Cashback{}
-	err := r.db.QueryRow(namedQuery, namedArgs...).Scan(
+	err := row.Scan(
 		&cashback.ID,
 		&cashback.CashbackAmount,
 		&cashback.TuronUserID,
+		&cashback.Status,
+		&cashback.FreezeCredits,
+		&cashback.StatusReason,
+		&cashback.StatusChangedAt,
 		&cashback.CreatedAt,
 		&cashback.UpdatedAt,
 		&cashback.DeletedAt,
 	)
-	if err == sql.ErrNoRows {
-		return nil, nil
+	if err != nil {
+		return nil, err
 	}
-	return cashback, err
+	return cashback, nil
 }
 
-func (r *CashbackRepository) UpdateCashbackAmount(id int64, newAmount float64) error {
+// GetCashbacksByUserIDs returns the live wallets of the given users in one
+// query, ordered by turon_user_id. Users without a wallet are simply absent.
+func (r *CashbackRepository) GetCashbacksByUserIDs(ctx context.Context, turonUserIDs []int64) ([]models.Cashback, error) {
 	query := `
-		UPDATE cashback
-		SET 
-			cashback_amount = $cashback_amount$,
-			updated_at = $updated_at$
-		WHERE id = $id$ 
-		AND deleted_at IS NULL`
+		SELECT ` + cashbackColumns + `
+		FROM cashback
+		WHERE turon_user_id = ANY($turon_user_ids$)
+		AND deleted_at IS NULL
+		ORDER BY turon_user_id`
 
 	args := map[string]interface{}{
-		"$cashback_amount$": newAmount,
-		"$updated_at$":      time.Now(),
-		"$id$":              id,
+		"$turon_user_ids$": pq.Array(turonUserIDs),
 	}
 
 	namedQuery, namedArgs := buildNamedQuery(query, args)
-	_, err := r.db.Exec(namedQuery, namedArgs...)
-	return err
+	rows, err := r.conn(ctx).Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cashbacks: %w", err)
+	}
+	defer rows.Close()
+
+	cashbacks := []models.Cashback{}
+	for rows.Next() {
+		cashback, err := scanCashback(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan cashback row: %w", err)
+		}
+		cashbacks = append(cashbacks, *cashback)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cashback rows: %w", err)
+	}
+	return cashbacks, nil
 }
 
-func (r *CashbackRepository) buildDateFilters(query string, args map[string]interface{}, fromDate, toDate string) string {
-	if fromDate != "" {
+func (r *CashbackRepository) buildDateFilters(query string, args map[string]interface{}, from, until *time.Time) string {
+	if from != nil {
 		query += " AND ch.created_at >= $from_date$"
-		args["$from_date$"] = fromDate
+		args["$from_date$"] = *from
 	}
-	if toDate != "" {
-		query += " AND ch.created_at <= $to_date$"
-		args["$to_date$"] = toDate
+	if until != nil {
+		query += " AND ch.created_at < $until_date$"
+		args["$until_date$"] = *until
 	}
 	return query
 }
 
-func (r *CashbackRepository) buildPagination(query string, args map[string]interface{}, pagination *models.Pagination) string {
-	query += " ORDER BY ch.created_at DESC LIMIT $limit$ OFFSET $offset$"
+// historySortColumns whitelists the sortable fields; user input never reaches
+// the ORDER BY clause directly.
+var historySortColumns = map[string]string{
+	"created_at": "ch.created_at",
+	"amount":     "ch.cashback_amount",
+}
+
+func (r *CashbackRepository) buildHistoryFilters(query string, args map[string]interface{}, filter *models.HistoryFilter) string {
+	query = r.buildDateFilters(query, args, filter.From, filter.Until)
+	if len(filter.Operations) > 0 {
+		query += " AND ch.operation = ANY($operations$)"
+		args["$operations$"] = pq.Array(filter.Operations)
+	}
+	if len(filter.Sources) > 0 {
+		query += " AND s.slug = ANY($sources$)"
+		args["$sources$"] = pq.Array(filter.Sources)
+	}
+	if filter.MinAmount != nil {
+		query += " AND ch.cashback_amount >= $min_amount$"
+		args["$min_amount$"] = *filter.MinAmount
+	}
+	if filter.MaxAmount != nil {
+		query += " AND ch.cashback_amount <= $max_amount$"
+		args["$max_amount$"] = *filter.MaxAmount
+	}
+	if filter.Reason != "" {
+		query += ` AND ch.reason ILIKE $reason$ ESCAPE '\'`
+		args["$reason$"] = "%" + likeEscaper.Replace(filter.Reason) + "%"
+	}
+	return query
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *CashbackRepository) buildHistoryOrder(query string, filter *models.HistoryFilter) string {
+	column, ok := historySortColumns[filter.SortBy]
+	if !ok {
+		column = historySortColumns["created_at"]
+	}
+	direction := "DESC"
+	if strings.EqualFold(filter.SortOrder, "asc") {
+		direction = "ASC"
+	}
+
+	return query + fmt.Sprintf(" ORDER BY %s %s, ch.id %s", column, direction, direction)
+}
+
+func (r *CashbackRepository) buildPagination(query string, args map[string]interface{}, filter *models.HistoryFilter, pagination *models.Pagination) string {
+	query = r.buildHistoryOrder(query, filter)
+	query += " LIMIT $limit$ OFFSET $offset$"
 	args["$limit$"] = pagination.Limit
 	args["$offset$"] = pagination.Offset
 	return query
 }
 
-func (r *CashbackRepository) GetCashbackHistoryByUserID(turonUserID int64, fromDate, toDate string, pagination *models.Pagination) ([]models.CashbackHistory, error) {
+const historySelectQuery = `
+		SELECT 
+			ch.id,
+			ch.cashback_id,
+			c.turon_user_id,
+			s.slug as source_slug,
+			ch.operation,
+			ch.reason,
+			ch.cashback_amount,
+			ch.amount,
+			ch.balance_after,
+			ch.host_ip,
+			ch.created_at,
+			ch.updated_at,
+			ch.deleted_at
+		FROM cashback_history ch
+		JOIN cashback c ON c.id = ch.cashback_id
+		LEFT JOIN sources s ON s.id = ch.source_id
+		WHERE ch.deleted_at IS NULL`
+
+const historyUserCondition = " AND c.turon_user_id = $turon_user_id$"
+
+func (r *CashbackRepository) countHistory(db dbtx, turonUserID int64, filter *models.HistoryFilter) (int64, error) {
 	countQuery := `
 		SELECT COUNT(*)
 		FROM cashback_history ch
 		JOIN cashback c ON c.id = ch.cashback_id
+		LEFT JOIN sources s ON s.id = ch.source_id
 		WHERE c.turon_user_id = $turon_user_id$ 
 		AND ch.deleted_at IS NULL`
 
@@ -161,66 +406,167 @@ func (r *CashbackRepository) GetCashbackHistoryByUserID(turonUserID int64, fromD
 		"$turon_user_id$": turonUserID,
 	}
 
-	countQuery = r.buildDateFilters(countQuery, args, fromDate, toDate)
+	countQuery = r.buildHistoryFilters(countQuery, args, filter)
 
 	namedCountQuery, namedCountArgs := buildNamedQuery(countQuery, args)
 	var total int64
-	err := r.db.QueryRow(namedCountQuery, namedCountArgs...).Scan(&total)
+	err := db.QueryRow(namedCountQuery, namedCountArgs...).Scan(&total)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get total count: %w", err)
+		return 0, fmt.Errorf("failed to get total count: %w", err)
 	}
+	return total, nil
+}
 
-	pagination.ItemTotal = total
-	pagination.PageTotal = (total + pagination.PageSize - 1) / pagination.PageSize
+func (r *CashbackRepository) GetCashbackHistoryByUserID(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, pagination *models.Pagination) ([]models.CashbackHistory, error) {
+	db := r.conn(ctx)
+	if !pagination.SkipTotal {
+		total, err := r.countHistory(db, turonUserID, filter)
+		if err != nil {
+			return nil, err
+		}
 
-	query := `
-		SELECT 
-			ch.id,
-			ch.cashback_id,
-			s.slug as source_slug,
-			ch.cashback_amount,
-			ch.host_ip,
-			ch.type,
-			ch.created_at,
-			ch.updated_at,
-			ch.deleted_at
-		FROM cashback_history ch
-		JOIN cashback c ON c.id = ch.cashback_id
-		LEFT JOIN sources s ON s.id = ch.source_id
-		WHERE c.turon_user_id = $turon_user_id$ 
-		AND ch.deleted_at IS NULL`
+		pagination.ItemTotal = total
+		pagination.PageTotal = (total + pagination.PageSize - 1) / pagination.PageSize
+	}
 
-	query = r.buildDateFilters(query, args, fromDate, toDate)
-	query = r.buildPagination(query, args, pagination)
+	args := map[string]interface{}{
+		"$turon_user_id$": turonUserID,
+	}
+
+	query := r.buildHistoryFilters(historySelectQuery+historyUserCondition, args, filter)
+	query = r.buildPagination(query, args, filter, pagination)
+
+	return queryHistory(db, query, args)
+}
+
+// GetCashbackHistoryByCursor returns one keyset page ordered by
+// (created_at, id) in the filter's sort order and fills in the page cursors.
+func (r *CashbackRepository) GetCashbackHistoryByCursor(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, page *models.CursorPagination) ([]models.CashbackHistory, error) {
+	var cursor *models.HistoryCursor
+	if page.Cursor != "" {
+		var err error
+		if cursor, err = models.DecodeHistoryCursor(page.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	db := r.conn(ctx)
+	if page.IncludeTotal {
+		total, err := r.countHistory(db, turonUserID, filter)
+		if err != nil {
+			return nil, err
+		}
+		page.ItemTotal = &total
+	}
+
+	args := map[string]interface{}{
+		"$turon_user_id$": turonUserID,
+	}
+	query := r.buildHistoryFilters(historySelectQuery+historyUserCondition, args, filter)
+
+	// A backward page is read in the opposite order and reversed afterwards.
+	ascending := strings.EqualFold(filter.SortOrder, "asc")
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		ascending = !ascending
+	}
+
+	comparison, direction := "<", "DESC"
+	if ascending {
+		comparison, direction = ">", "ASC"
+	}
+
+	if cursor != nil {
+		query += fmt.Sprintf(" AND (ch.created_at, ch.id) %s ($cursor_created_at$, $cursor_id$)", comparison)
+		args["$cursor_created_at$"] = cursor.CreatedAt
+		args["$cursor_id$"] = cursor.ID
+	}
+	query += fmt.Sprintf(" ORDER BY ch.created_at %s, ch.id %s LIMIT $limit$", direction, direction)
+	args["$limit$"] = page.PageSize + 1
+
+	history, err := queryHistory(db, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := int64(len(history)) > page.PageSize
+	if hasMore {
+		history = history[:page.PageSize]
+	}
+	if backward {
+		for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+			history[i], history[j] = history[j], history[i]
+		}
+	}
+
+	page.NextCursor, page.PrevCursor = nil, nil
+	if len(history) == 0 {
+		return history, nil
+	}
+
+	first, last := history[0], history[len(history)-1]
+	if hasMore || backward {
+		next := models.HistoryCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+		page.NextCursor = &next
+	}
+	if (!backward && cursor != nil) || (backward && hasMore) {
+		prev := models.HistoryCursor{CreatedAt: first.CreatedAt, ID: first.ID, Backward: true}.Encode()
+		page.PrevCursor = &prev
+	}
+	return history, nil
+}
+
+// StreamCashbackHistory calls fn for every matching history row straight off
+// the result set. A zero turonUserID streams all users.
+func (r *CashbackRepository) StreamCashbackHistory(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, fn func(history *models.CashbackHistory) error) error {
+	query := historySelectQuery
+	args := map[string]interface{}{}
+	if turonUserID != 0 {
+		query += historyUserCondition
+		args["$turon_user_id$"] = turonUserID
+	}
+
+	query = r.buildHistoryFilters(query, args, filter)
+	query = r.buildHistoryOrder(query, filter)
 
 	namedQuery, namedArgs := buildNamedQuery(query, args)
-	rows, err := r.db.Query(namedQuery, namedArgs...)
+	rows, err := r.conn(ctx).Query(namedQuery, namedArgs...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query cashback history: %w", err)
+		return fmt.Errorf("failed to query cashback history: %w", err)
 	}
 	defer rows.Close()
 
-	var history []models.CashbackHistory
 	for rows.Next() {
-		var h models.CashbackHistory
-		var sourceSlug sql.NullString
-		if err := rows.Scan(
-			&h.ID,
-			&h.CashbackID,
-			&sourceSlug,
-			&h.CashbackAmount,
-			&h.HostIP,
-			&h.Type,
-			&h.CreatedAt,
-			&h.UpdatedAt,
-			&h.DeletedAt,
-		); err != nil {
-			return nil, fmt.Errorf("failed to scan cashback history row: %w", err)
+		h, err := scanHistory(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(h); err != nil {
+			return err
 		}
-		if sourceSlug.Valid {
-			h.SourceSlug = sourceSlug.String
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating cashback history rows: %w", err)
+	}
+	return nil
+}
+
+func queryHistory(db dbtx, query string, args map[string]interface{}) ([]models.CashbackHistory, error) {
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	rows, err := db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cashback history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.CashbackHistory{}
+	for rows.Next() {
+		h, err := scanHistory(rows)
+		if err != nil {
+			return nil, err
 		}
-		history = append(history, h)
+		history = append(history, *h)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -230,6 +576,32 @@ func (r *CashbackRepository) GetCashbackHistoryByUserID(turonUserID int64, fromD
 	return history, nil
 }
 
+func scanHistory(row rowScanner) (*models.CashbackHistory, error) {
+	var h models.CashbackHistory
+	var sourceSlug sql.NullString
+	if err := row.Scan(
+		&h.ID,
+		&h.CashbackID,
+		&h.TuronUserID,
+		&sourceSlug,
+		&h.Operation,
+		&h.Reason,
+		&h.CashbackAmount,
+		&h.Amount,
+		&h.BalanceAfter,
+		&h.HostIP,
+		&h.CreatedAt,
+		&h.UpdatedAt,
+		&h.DeletedAt,
+	); err != nil {
+		return nil, fmt.Errorf("failed to scan cashback history row: %w", err)
+	}
+	if sourceSlug.Valid {
+		h.SourceSlug = sourceSlug.String
+	}
+	return &h, nil
+}
+
 func buildNamedQuery(query string, args map[string]interface{}) (string, []interface{}) {
 	var positionalArgs []interface{}
 	position := 1
@@ -242,6 +614,11 @@ func buildNamedQuery(query string, args map[string]interface{}) (string, []inter
 	return query, positionalArgs
 }
 
+// nullInt64 maps the zero ID to NULL for optional foreign keys.
+func nullInt64(id int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: id, Valid: id != 0}
+}
+
 func replaceAll(s, old, new string) string {
 	return strings.ReplaceAll(s, old, new)
 }
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+type HealthRepository struct {
+	db *sql.DB
+}
+
+func NewHealthRepository(db *sql.DB) *HealthRepository {
+	return &HealthRepository{db: db}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// GetMigrationVersion returns the newest migration goose has applied. As in
+// goose, the latest row of a version decides: a rolled back migration gets
+// a newer row with is_applied false.
+func (r *HealthRepository) GetMigrationVersion(ctx context.Context) (int64, error) {
+	query := `
+		SELECT MAX(version_id)
+		FROM (
+			SELECT DISTINCT ON (version_id) version_id, is_applied
+			FROM goose_db_version
+			ORDER BY version_id, id DESC
+		) latest
+		WHERE is_applied`
+
+	var version sql.NullInt64
+	db := &tracedConn{conn: r.db, ctx: ctx}
+	err := db.QueryRow(query).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get migration version: %w", err)
+	}
+	return version.Int64, nil
+}
@@ -0,0 +1,369 @@
+package repository
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+func (r *CashbackRepository) EnsureUserAccount(cashbackID int64) (int64, error) {
+	query := `
+		INSERT INTO ledger_accounts (
+			kind,
+			cashback_id,
+			created_at,
+			updated_at
+		) VALUES (
+			$kind$,
+			$cashback_id$,
+			$created_at$,
+			$updated_at$
+		)
+		ON CONFLICT (cashback_id) WHERE cashback_id IS NOT NULL
+		DO UPDATE SET updated_at = ledger_accounts.updated_at
+		RETURNING id`
+
+	now := time.Now()
+	args := map[string]interface{}{
+		"$kind$":        constants.AccountKindUser,
+		"$cashback_id$": cashbackID,
+		"$created_at$":  now,
+		"$updated_at$":  now,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var id int64
+	if err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to ensure user ledger account: %w", err)
+	}
+	return id, nil
+}
+
+func (r *CashbackRepository) EnsureSourceAccount(sourceID int64) (int64, error) {
+	query := `
+		INSERT INTO ledger_accounts (
+			kind,
+			source_id,
+			created_at,
+			updated_at
+		) VALUES (
+			$kind$,
+			$source_id$,
+			$created_at$,
+			$updated_at$
+		)
+		ON CONFLICT (source_id) WHERE source_id IS NOT NULL
+		DO UPDATE SET updated_at = ledger_accounts.updated_at
+		RETURNING id`
+
+	now := time.Now()
+	args := map[string]interface{}{
+		"$kind$":       constants.AccountKindSource,
+		"$source_id$":  sourceID,
+		"$created_at$": now,
+		"$updated_at$": now,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var id int64
+	if err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to ensure source ledger account: %w", err)
+	}
+	return id, nil
+}
+
+func (r *CashbackRepository) GetSystemAccountID(code string) (int64, error) {
+	query := `
+		SELECT id
+		FROM ledger_accounts
+		WHERE kind = $kind$
+		AND code = $code$`
+
+	args := map[string]interface{}{
+		"$kind$": constants.AccountKindSystem,
+		"$code$": code,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var id int64
+	err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, fmt.Errorf("system ledger account %q does not exist", code)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get system ledger account: %w", err)
+	}
+	return id, nil
+}
+
+// CreateLedgerEntries writes the postings of one transaction. The postings
+// must sum to zero; the database re-checks this at commit.
+func (r *CashbackRepository) CreateLedgerEntries(historyID int64, entries []models.LedgerEntry) error {
+	var totalCents int64
+	for _, entry := range entries {
+		totalCents += int64(math.Round(entry.Amount * 100))
+	}
+	if len(entries) < 2 || totalCents != 0 {
+		return fmt.Errorf("unbalanced ledger transaction for history %d", historyID)
+	}
+
+	query := `
+		INSERT INTO ledger_entries (
+			history_id,
+			account_id,
+			amount,
+			created_at
+		) VALUES (
+			$history_id$,
+			$account_id$,
+			$amount$,
+			$created_at$
+		) RETURNING id`
+
+	now := time.Now()
+	for i := range entries {
+		entries[i].HistoryID = historyID
+		if entries[i].CreatedAt.IsZero() {
+			entries[i].CreatedAt = now
+		}
+
+		args := map[string]interface{}{
+			"$history_id$": historyID,
+			"$account_id$": entries[i].AccountID,
+			"$amount$":     entries[i].Amount,
+			"$created_at$": entries[i].CreatedAt,
+		}
+
+		namedQuery, namedArgs := buildNamedQuery(query, args)
+		if err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&entries[i].ID); err != nil {
+			return fmt.Errorf("failed to create ledger entry: %w", err)
+		}
+	}
+	return nil
+}
+
+// RefreshCashbackBalance recomputes the cached cashback_amount of one wallet
+// from its ledger postings and returns the new balance.
+func (r *CashbackRepository) RefreshCashbackBalance(cashbackID int64) (float64, error) {
+	query := `
+		UPDATE cashback c
+		SET 
+			cashback_amount = COALESCE((
+				SELECT SUM(e.amount)
+				FROM ledger_entries e
+				JOIN ledger_accounts a ON a.id = e.account_id
+				WHERE a.cashback_id = c.id
+			), 0),
+			updated_at = $updated_at$
+		WHERE c.id = $id$
+		RETURNING c.cashback_amount`
+
+	args := map[string]interface{}{
+		"$updated_at$": time.Now(),
+		"$id$":         cashbackID,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var balance float64
+	if err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("failed to refresh cashback balance: %w", err)
+	}
+	return balance, nil
+}
+
+// RebuildCashbackBalances rewrites every cached balance that differs from the
+// ledger and returns how many rows were changed. Each wallet is rebuilt in
+// its own transaction under its row lock, as postings are, so a posting
+// committed meanwhile is never overwritten by a sum taken before it.
+func (r *CashbackRepository) RebuildCashbackBalances() (int64, error) {
+	query := `
+		SELECT c.id
+		FROM cashback c
+		LEFT JOIN ledger_accounts a ON a.cashback_id = c.id
+		LEFT JOIN ledger_entries e ON e.account_id = a.id
+		GROUP BY c.id, c.cashback_amount
+		HAVING c.cashback_amount <> COALESCE(SUM(e.amount), 0)
+		ORDER BY c.id`
+
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query drifted cashback balances: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan cashback id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating drifted cashback balances: %w", err)
+	}
+
+	var changed int64
+	for _, id := range ids {
+		rebuilt, err := r.rebuildCashbackBalance(id)
+		if err != nil {
+			return changed, err
+		}
+		if rebuilt {
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// rebuildCashbackBalance locks the wallet row, then refreshes its cached
+// balance if it still differs from the ledger.
+func (r *CashbackRepository) rebuildCashbackBalance(cashbackID int64) (bool, error) {
+	tx, err := r.pool.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT cashback_amount
+		FROM cashback
+		WHERE id = $id$
+		FOR UPDATE`
+
+	args := map[string]interface{}{
+		"$id$": cashbackID,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var stored float64
+	err = tx.QueryRow(namedQuery, namedArgs...).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to lock cashback: %w", err)
+	}
+
+	locked := &CashbackRepository{db: &tracedConn{conn: tx, ctx: context.Background()}, pool: r.pool, inTx: true}
+	balance, err := locked.GetLedgerBalance(cashbackID)
+	if err != nil {
+		return false, err
+	}
+	if balance == stored {
+		return false, nil
+	}
+
+	if _, err := locked.RefreshCashbackBalance(cashbackID); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// GetLedgerBalance returns the signed sum of all postings on a user wallet.
+func (r *CashbackRepository) GetLedgerBalance(cashbackID int64) (float64, error) {
+	query := `
+		SELECT COALESCE(SUM(e.amount), 0)
+		FROM ledger_entries e
+		JOIN ledger_accounts a ON a.id = e.account_id
+		WHERE a.cashback_id = $cashback_id$`
+
+	args := map[string]interface{}{
+		"$cashback_id$": cashbackID,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var balance float64
+	if err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("failed to get ledger balance: %w", err)
+	}
+	return balance, nil
+}
+
+func (r *CashbackRepository) buildUserRangeFilters(query string, args map[string]interface{}, fromTuronUserID, toTuronUserID int64) string {
+	if fromTuronUserID > 0 {
+		query += " AND c.turon_user_id >= $from_turon_user_id$"
+		args["$from_turon_user_id$"] = fromTuronUserID
+	}
+	if toTuronUserID > 0 {
+		query += " AND c.turon_user_id <= $to_turon_user_id$"
+		args["$to_turon_user_id$"] = toTuronUserID
+	}
+	return query
+}
+
+// CountCashbackAccounts counts live wallets in an optional turon_user_id
+// range; zero bounds are open.
+func (r *CashbackRepository) CountCashbackAccounts(fromTuronUserID, toTuronUserID int64) (int64, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM cashback c
+		WHERE c.deleted_at IS NULL`
+
+	args := map[string]interface{}{}
+	query = r.buildUserRangeFilters(query, args, fromTuronUserID, toTuronUserID)
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var total int64
+	if err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count cashback accounts: %w", err)
+	}
+	return total, nil
+}
+
+// FindBalanceDiscrepancies compares every live wallet in the range with the
+// sum of its ledger postings and returns the ones that differ.
+func (r *CashbackRepository) FindBalanceDiscrepancies(fromTuronUserID, toTuronUserID int64) ([]models.BalanceDiscrepancy, error) {
+	query := `
+		SELECT
+			c.id,
+			c.turon_user_id,
+			c.cashback_amount,
+			COALESCE(SUM(e.amount), 0) AS recomputed
+		FROM cashback c
+		LEFT JOIN ledger_accounts a ON a.cashback_id = c.id
+		LEFT JOIN ledger_entries e ON e.account_id = a.id
+		WHERE c.deleted_at IS NULL`
+
+	args := map[string]interface{}{}
+	query = r.buildUserRangeFilters(query, args, fromTuronUserID, toTuronUserID)
+	query += `
+		GROUP BY c.id, c.turon_user_id, c.cashback_amount
+		HAVING c.cashback_amount <> COALESCE(SUM(e.amount), 0)
+		ORDER BY c.turon_user_id`
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	rows, err := r.db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query balance discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	discrepancies := []models.BalanceDiscrepancy{}
+	for rows.Next() {
+		var d models.BalanceDiscrepancy
+		if err := rows.Scan(
+			&d.CashbackID,
+			&d.TuronUserID,
+			&d.StoredBalance,
+			&d.RecomputedBalance,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan balance discrepancy row: %w", err)
+		}
+		d.Difference = math.Round((d.StoredBalance-d.RecomputedBalance)*100) / 100
+		discrepancies = append(discrepancies, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating balance discrepancy rows: %w", err)
+	}
+
+	return discrepancies, nil
+}
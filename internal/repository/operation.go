@@ -0,0 +1,276 @@
+package repository
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+var ErrOperationFinished = errors.New("operation has already finished")
+
+const operationColumns = `
+			id,
+			type,
+			turon_user_id,
+			cashback_amount,
+			status,
+			history_id,
+			error,
+			created_at,
+			updated_at,
+			finished_at,
+			idempotency_key`
+
+// CreateOperation records an operation. It is usually pending, but may be
+// created succeeded with its history row already set. A duplicate
+// idempotency key is ErrDuplicateIdempotencyKey.
+func (r *CashbackRepository) CreateOperation(ctx context.Context, operation *models.Operation) error {
+	query := `
+		INSERT INTO cashback_operations (
+			id,
+			type,
+			turon_user_id,
+			cashback_amount,
+			status,
+			history_id,
+			idempotency_key,
+			created_at,
+			updated_at,
+			finished_at
+		) VALUES (
+			$id$,
+			$type$,
+			$turon_user_id$,
+			$cashback_amount$,
+			$status$,
+			$history_id$,
+			$idempotency_key$,
+			$now$,
+			$now$,
+			$finished_at$
+		)`
+
+	now := time.Now()
+	var finishedAt *time.Time
+	if operation.Status != constants.OperationStatusPending {
+		finishedAt = &now
+	}
+
+	args := map[string]interface{}{
+		"$id$":              operation.ID,
+		"$type$":            operation.Type,
+		"$turon_user_id$":   operation.TuronUserID,
+		"$cashback_amount$": operation.CashbackAmount,
+		"$status$":          operation.Status,
+		"$history_id$":      operation.HistoryID,
+		"$idempotency_key$": nullString(operation.IdempotencyKey),
+		"$now$":             now,
+		"$finished_at$":     finishedAt,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	_, err := r.conn(ctx).Exec(namedQuery, namedArgs...)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_cashback_operations_idempotency_key" {
+		return ErrDuplicateIdempotencyKey
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create operation: %w", err)
+	}
+
+	operation.CreatedAt = now
+	operation.UpdatedAt = now
+	operation.FinishedAt = finishedAt
+	return nil
+}
+
+func (r *CashbackRepository) GetOperation(ctx context.Context, id string) (*models.Operation, error) {
+	query := `
+		SELECT` + operationColumns + `
+		FROM cashback_operations
+		WHERE id = $id$`
+
+	args := map[string]interface{}{
+		"$id$": id,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	return scanOperation(r.conn(ctx).QueryRow(namedQuery, namedArgs...))
+}
+
+// GetOperationByIdempotencyKey returns the operation submitted with key, or
+// nil when there is none.
+func (r *CashbackRepository) GetOperationByIdempotencyKey(ctx context.Context, key string) (*models.Operation, error) {
+	query := `
+		SELECT` + operationColumns + `
+		FROM cashback_operations
+		WHERE idempotency_key = $idempotency_key$`
+
+	args := map[string]interface{}{
+		"$idempotency_key$": key,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	return scanOperation(r.conn(ctx).QueryRow(namedQuery, namedArgs...))
+}
+
+func scanOperation(row rowScanner) (*models.Operation, error) {
+	var (
+		operation      models.Operation
+		historyID      sql.NullInt64
+		message        sql.NullString
+		finished       sql.NullTime
+		idempotencyKey sql.NullString
+	)
+	err := row.Scan(
+		&operation.ID,
+		&operation.Type,
+		&operation.TuronUserID,
+		&operation.CashbackAmount,
+		&operation.Status,
+		&historyID,
+		&message,
+		&operation.CreatedAt,
+		&operation.UpdatedAt,
+		&finished,
+		&idempotencyKey,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operation: %w", err)
+	}
+
+	if historyID.Valid {
+		operation.HistoryID = &historyID.Int64
+	}
+	if message.Valid {
+		operation.Error = &message.String
+	}
+	if finished.Valid {
+		operation.FinishedAt = &finished.Time
+	}
+	operation.IdempotencyKey = idempotencyKey.String
+	return &operation, nil
+}
+
+// MarkOperationSucceeded links an operation to its history row. It runs
+// inside the posting transaction, so a committed operation is never left
+// pending.
+func (r *CashbackRepository) MarkOperationSucceeded(id string, historyID int64) error {
+	query := `
+		UPDATE cashback_operations
+		SET
+			status = $succeeded$,
+			history_id = $history_id$,
+			updated_at = $now$,
+			finished_at = $now$
+		WHERE id = $id$
+		AND status = $pending$`
+
+	args := map[string]interface{}{
+		"$succeeded$":  constants.OperationStatusSucceeded,
+		"$history_id$": historyID,
+		"$now$":        time.Now(),
+		"$id$":         id,
+		"$pending$":    constants.OperationStatusPending,
+	}
+
+	return execOperationTransition(r.db, args, query)
+}
+
+func (r *CashbackRepository) MarkOperationFailed(ctx context.Context, id, message string) error {
+	query := `
+		UPDATE cashback_operations
+		SET
+			status = $failed$,
+			error = $error$,
+			updated_at = $now$,
+			finished_at = $now$
+		WHERE id = $id$
+		AND status = $pending$`
+
+	args := map[string]interface{}{
+		"$failed$":  constants.OperationStatusFailed,
+		"$error$":   message,
+		"$now$":     time.Now(),
+		"$id$":      id,
+		"$pending$": constants.OperationStatusPending,
+	}
+
+	return execOperationTransition(r.conn(ctx), args, query)
+}
+
+func execOperationTransition(db dbtx, args map[string]interface{}, query string) error {
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := db.Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to update operation: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update operation: %w", err)
+	}
+	if affected == 0 {
+		return ErrOperationFinished
+	}
+	return nil
+}
+
+// FailStaleOperations fails operations still pending since before, which
+// were lost when the process that accepted them stopped.
+func (r *CashbackRepository) FailStaleOperations(ctx context.Context, before time.Time, message string) (int64, error) {
+	query := `
+		UPDATE cashback_operations
+		SET
+			status = $failed$,
+			error = $error$,
+			updated_at = $now$,
+			finished_at = $now$
+		WHERE status = $pending$
+		AND created_at < $before$`
+
+	args := map[string]interface{}{
+		"$failed$":  constants.OperationStatusFailed,
+		"$error$":   message,
+		"$now$":     time.Now(),
+		"$pending$": constants.OperationStatusPending,
+		"$before$":  before,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := r.conn(ctx).Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail stale operations: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteOperationsBefore removes finished operations that finished before
+// the given instant.
+func (r *CashbackRepository) DeleteOperationsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM cashback_operations
+		WHERE status <> $pending$
+		AND finished_at < $before$`
+
+	args := map[string]interface{}{
+		"$pending$": constants.OperationStatusPending,
+		"$before$":  before,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := r.conn(ctx).Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete operations: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"cashback-serv/models"
+	"fmt"
+	"time"
+)
+
+// CreateBalanceSnapshots stores the balance as of asOf of every wallet open
+// at that time: its previous snapshot plus the ledger entries posted since.
+// Snapshots that already exist for asOf are left untouched.
+func (r *CashbackRepository) CreateBalanceSnapshots(asOf time.Time) (int64, error) {
+	query := `
+		WITH latest AS (
+			SELECT DISTINCT ON (cashback_id)
+				cashback_id,
+				balance,
+				as_of
+			FROM cashback_balance_snapshots
+			WHERE as_of < $as_of$
+			ORDER BY cashback_id, as_of DESC
+		)
+		INSERT INTO cashback_balance_snapshots (
+			cashback_id,
+			balance,
+			as_of,
+			created_at
+		)
+		SELECT
+			c.id,
+			COALESCE(l.balance, 0) + COALESCE((
+				SELECT SUM(e.amount)
+				FROM ledger_entries e
+				JOIN ledger_accounts a ON a.id = e.account_id
+				WHERE a.cashback_id = c.id
+				AND e.created_at <= $as_of$
+				AND e.created_at > COALESCE(l.as_of, '-infinity'::timestamptz)
+			), 0),
+			$as_of$,
+			$created_at$
+		FROM cashback c
+		LEFT JOIN latest l ON l.cashback_id = c.id
+		WHERE c.created_at <= $as_of$
+		AND (c.deleted_at IS NULL OR c.deleted_at > $as_of$)
+		ON CONFLICT (cashback_id, as_of) DO NOTHING`
+
+	args := map[string]interface{}{
+		"$as_of$":      asOf,
+		"$created_at$": time.Now(),
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := r.db.Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create balance snapshots: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetBalanceAsOf returns the wallet balance at asOf: the latest snapshot taken
+// no later than asOf plus the postings made after it.
+func (r *CashbackRepository) GetBalanceAsOf(cashbackID int64, asOf time.Time) (float64, error) {
+	query := `
+		WITH snapshot AS (
+			SELECT balance, as_of
+			FROM cashback_balance_snapshots
+			WHERE cashback_id = $cashback_id$
+			AND as_of <= $as_of$
+			ORDER BY as_of DESC
+			LIMIT 1
+		)
+		SELECT COALESCE((SELECT balance FROM snapshot), 0) + COALESCE(SUM(e.amount), 0)
+		FROM ledger_entries e
+		JOIN ledger_accounts a ON a.id = e.account_id
+		WHERE a.cashback_id = $cashback_id$
+		AND e.created_at <= $as_of$
+		AND e.created_at > COALESCE((SELECT as_of FROM snapshot), '-infinity'::timestamptz)`
+
+	args := map[string]interface{}{
+		"$cashback_id$": cashbackID,
+		"$as_of$":       asOf,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var balance float64
+	if err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&balance); err != nil {
+		return 0, fmt.Errorf("failed to get balance as of %s: %w", asOf.Format(time.RFC3339), err)
+	}
+	return balance, nil
+}
+
+// StreamBalancesAsOf calls fn with the balance of every wallet that existed
+// at asOf, ordered by turon_user_id, without loading them all into memory.
+func (r *CashbackRepository) StreamBalancesAsOf(asOf time.Time, fn func(balance *models.CashbackBalance) error) error {
+	query := `
+		WITH latest AS (
+			SELECT DISTINCT ON (cashback_id)
+				cashback_id,
+				balance,
+				as_of
+			FROM cashback_balance_snapshots
+			WHERE as_of <= $as_of$
+			ORDER BY cashback_id, as_of DESC
+		)
+		SELECT
+			c.id,
+			c.turon_user_id,
+			COALESCE(l.balance, 0) + COALESCE((
+				SELECT SUM(e.amount)
+				FROM ledger_entries e
+				JOIN ledger_accounts a ON a.id = e.account_id
+				WHERE a.cashback_id = c.id
+				AND e.created_at <= $as_of$
+				AND e.created_at > COALESCE(l.as_of, '-infinity'::timestamptz)
+			), 0)
+		FROM cashback c
+		LEFT JOIN latest l ON l.cashback_id = c.id
+		WHERE c.created_at <= $as_of$
+		AND (c.deleted_at IS NULL OR c.deleted_at > $as_of$)
+		ORDER BY c.turon_user_id`
+
+	args := map[string]interface{}{
+		"$as_of$": asOf,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	rows, err := r.db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to query balances as of %s: %w", asOf.Format(time.RFC3339), err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		balance := &models.CashbackBalance{AsOf: asOf}
+		if err := rows.Scan(
+			&balance.CashbackID,
+			&balance.TuronUserID,
+			&balance.CashbackAmount,
+		); err != nil {
+			return fmt.Errorf("failed to scan balance row: %w", err)
+		}
+		if err := fn(balance); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating balance rows: %w", err)
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package repository
 
 import (
+	constants "cashback-serv/const"
 	"cashback-serv/models"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -14,7 +18,21 @@ func NewSourceRepository(db *sql.DB) *SourceRepository {
 	return &SourceRepository{db: db}
 }
 
-func (r *SourceRepository) CreateSource(source *models.Source) error {
+// conn returns the pool running queries with ctx.
+func (r *SourceRepository) conn(ctx context.Context) dbtx {
+	return &tracedConn{conn: r.db, ctx: ctx, name: callerName(2)}
+}
+
+// CreateSource inserts the source and its source.created webhook event in
+// one transaction.
+func (r *SourceRepository) CreateSource(ctx context.Context, source *models.Source) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	db := &tracedConn{conn: tx, ctx: ctx}
+
 	query := `
 		INSERT INTO sources (
 			host_ip,
@@ -37,10 +55,31 @@ func (r *SourceRepository) CreateSource(source *models.Source) error {
 	}
 
 	namedQuery, namedArgs := buildNamedQuery(query, args)
-	return r.db.QueryRow(namedQuery, namedArgs...).Scan(&source.ID)
+	if err := db.QueryRow(namedQuery, namedArgs...).Scan(&source.ID); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(models.SourceEventData{
+		ID:     source.ID,
+		Slug:   source.Slug,
+		HostIP: source.HostIP,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode source event: %w", err)
+	}
+	event := &models.WebhookEvent{
+		Type:     constants.EventSourceCreated,
+		SourceID: source.ID,
+		Payload:  payload,
+	}
+	if err := insertWebhookEvent(db, event); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (r *SourceRepository) GetSourceBySlug(slug string) (*models.Source, error) {
+func (r *SourceRepository) GetSourceBySlug(ctx context.Context, slug string) (*models.Source, error) {
 	query := `
 		SELECT 
 			id,
@@ -59,7 +98,7 @@ func (r *SourceRepository) GetSourceBySlug(slug string) (*models.Source, error)
 
 	namedQuery, namedArgs := buildNamedQuery(query, args)
 	source := &models.Source{}
-	err := r.db.QueryRow(namedQuery, namedArgs...).Scan(
+	err := r.conn(ctx).QueryRow(namedQuery, namedArgs...).Scan(
 		&source.ID,
 		&source.HostIP,
 		&source.Slug,
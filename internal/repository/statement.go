@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"cashback-serv/models"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SaveStatement stores a generated statement. A statement already stored for
+// the same wallet, period and time zone is kept, so reruns are harmless.
+func (r *CashbackRepository) SaveStatement(statement *models.Statement) (bool, error) {
+	data, err := json.Marshal(statement)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode statement: %w", err)
+	}
+
+	query := `
+		INSERT INTO cashback_statements (
+			cashback_id,
+			turon_user_id,
+			period,
+			time_zone,
+			opening_balance,
+			closing_balance,
+			data,
+			generated_at
+		) VALUES (
+			$cashback_id$,
+			$turon_user_id$,
+			$period$,
+			$time_zone$,
+			$opening_balance$,
+			$closing_balance$,
+			$data$,
+			$generated_at$
+		)
+		ON CONFLICT (cashback_id, period, time_zone) DO NOTHING`
+
+	args := map[string]interface{}{
+		"$cashback_id$":     statement.CashbackID,
+		"$turon_user_id$":   statement.TuronUserID,
+		"$period$":          statement.Period,
+		"$time_zone$":       statement.TimeZone,
+		"$opening_balance$": statement.OpeningBalance,
+		"$closing_balance$": statement.ClosingBalance,
+		"$data$":            data,
+		"$generated_at$":    statement.GeneratedAt,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := r.db.Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return false, fmt.Errorf("failed to save statement: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (r *CashbackRepository) GetStatement(cashbackID int64, period, timeZone string) (*models.Statement, error) {
+	query := `
+		SELECT data
+		FROM cashback_statements
+		WHERE cashback_id = $cashback_id$
+		AND period = $period$
+		AND time_zone = $time_zone$`
+
+	args := map[string]interface{}{
+		"$cashback_id$": cashbackID,
+		"$period$":      period,
+		"$time_zone$":   timeZone,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	var data []byte
+	err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get statement: %w", err)
+	}
+
+	statement := &models.Statement{}
+	if err := json.Unmarshal(data, statement); err != nil {
+		return nil, fmt.Errorf("failed to decode statement: %w", err)
+	}
+	return statement, nil
+}
+
+// ListStatementAccounts returns the wallets that need a statement for
+// [from, until): those with history in the period or a non-zero balance at
+// its start.
+func (r *CashbackRepository) ListStatementAccounts(from, until time.Time) ([]models.Cashback, error) {
+	query := `
+		SELECT c.id, c.turon_user_id
+		FROM cashback c
+		WHERE c.created_at < $until$
+		AND (c.deleted_at IS NULL OR c.deleted_at >= $from$)
+		AND (
+			EXISTS (
+				SELECT 1
+				FROM cashback_history ch
+				WHERE ch.cashback_id = c.id
+				AND ch.deleted_at IS NULL
+				AND ch.created_at >= $from$
+				AND ch.created_at < $until$
+			)
+			OR COALESCE((
+				SELECT SUM(e.amount)
+				FROM ledger_entries e
+				JOIN ledger_accounts a ON a.id = e.account_id
+				WHERE a.cashback_id = c.id
+				AND e.created_at < $from$
+			), 0) <> 0
+		)
+		ORDER BY c.turon_user_id`
+
+	args := map[string]interface{}{
+		"$from$":  from,
+		"$until$": until,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	rows, err := r.db.Query(namedQuery, namedArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statement accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.Cashback{}
+	for rows.Next() {
+		var account models.Cashback
+		if err := rows.Scan(&account.ID, &account.TuronUserID); err != nil {
+			return nil, fmt.Errorf("failed to scan statement account: %w", err)
+		}
+		accounts = append(accounts, account)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating statement accounts: %w", err)
+	}
+	return accounts, nil
+}
@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"cashback-serv/models"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// cashbackUpdatesChannel is the Postgres NOTIFY channel that carries the
+// turon user ID of every committed cashback posting.
+const cashbackUpdatesChannel = "cashback_updates"
+
+// NotifyCashbackUpdate queues a notification for the user. Postgres only
+// delivers it when the surrounding transaction commits.
+func (r *CashbackRepository) NotifyCashbackUpdate(turonUserID int64) error {
+	query := `SELECT pg_notify($channel$, $payload$)`
+
+	args := map[string]interface{}{
+		"$channel$": cashbackUpdatesChannel,
+		"$payload$": strconv.FormatInt(turonUserID, 10),
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if _, err := r.db.Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to notify cashback update: %w", err)
+	}
+	return nil
+}
+
+// GetCashbackHistoryAfter returns up to limit of the user's history rows with
+// an ID above afterID, oldest first.
+func (r *CashbackRepository) GetCashbackHistoryAfter(turonUserID, afterID int64, limit int) ([]models.CashbackHistory, error) {
+	query := historySelectQuery + historyUserCondition + `
+		AND ch.id > $after_id$
+		ORDER BY ch.id
+		LIMIT $limit$`
+
+	args := map[string]interface{}{
+		"$turon_user_id$": turonUserID,
+		"$after_id$":      afterID,
+		"$limit$":         limit,
+	}
+
+	return queryHistory(r.db, query, args)
+}
+
+// GetLastCashbackHistoryID returns the ID of the user's latest history row,
+// or 0 when there is none.
+func (r *CashbackRepository) GetLastCashbackHistoryID(turonUserID int64) (int64, error) {
+	query := `
+		SELECT COALESCE(MAX(ch.id), 0)
+		FROM cashback_history ch
+		JOIN cashback c ON c.id = ch.cashback_id
+		WHERE ch.deleted_at IS NULL
+		AND c.turon_user_id = $turon_user_id$`
+
+	args := map[string]interface{}{
+		"$turon_user_id$": turonUserID,
+	}
+
+	var id int64
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if err := r.db.QueryRow(namedQuery, namedArgs...).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to get last cashback history id: %w", err)
+	}
+	return id, nil
+}
+
+// ListenCashbackUpdates opens a dedicated connection that listens for
+// cashback updates from every replica. onUpdate gets the user ID of each
+// notification; onReconnect is called after the connection was lost, when
+// notifications may have been missed. The listener keeps trying to connect
+// in the background, so an unreachable database does not hold up startup.
+func ListenCashbackUpdates(dsn string, onUpdate func(turonUserID int64), onReconnect func()) {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("cashback update listener", "event", event, "error", err)
+		}
+	})
+
+	go func() {
+		// Listen blocks until the first connection is made.
+		if err := listener.Listen(cashbackUpdatesChannel); err != nil {
+			slog.Error("failed to listen for cashback updates", "error", err)
+			listener.Close()
+			return
+		}
+
+		for notification := range listener.Notify {
+			// A nil notification means the connection was re-established.
+			if notification == nil {
+				onReconnect()
+				continue
+			}
+			turonUserID, err := strconv.ParseInt(notification.Extra, 10, 64)
+			if err != nil {
+				slog.Warn("cashback update listener: invalid payload", "payload", notification.Extra)
+				continue
+			}
+			onUpdate(turonUserID)
+		}
+	}()
+}
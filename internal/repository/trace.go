@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"cashback-serv/internal/tracing"
+	"context"
+	"database/sql"
+	"runtime"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// contextConn is the context-aware half of *sql.DB and *sql.Tx.
+type contextConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// tracedConn runs queries with ctx, each in its own span named after the
+// repository method that issued it. The span of Query ends when its rows are
+// closed. Without a trace in ctx no spans are started.
+type tracedConn struct {
+	conn contextConn
+	ctx  context.Context
+	// name, when set, names the spans instead of the calling method.
+	name string
+}
+
+// conn returns the pool running queries with ctx. Inside WithTx the
+// transaction, with its own context, is returned instead.
+func (r *CashbackRepository) conn(ctx context.Context) dbtx {
+	if r.inTx {
+		return r.db
+	}
+	return &tracedConn{conn: r.pool, ctx: ctx, name: callerName(2)}
+}
+
+func (t *tracedConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(query)
+	result, err := t.conn.ExecContext(ctx, query, args...)
+	tracing.End(span, err)
+	return result, err
+}
+
+func (t *tracedConn) Query(query string, args ...interface{}) (rows, error) {
+	ctx, span := t.start(query)
+	result, err := t.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.End(span, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: result, span: span}, nil
+}
+
+// tracedRows ends the span of its query once closed, so the span covers
+// reading the rows too.
+type tracedRows struct {
+	*sql.Rows
+	span trace.Span
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	if r.span != nil {
+		spanErr := r.Rows.Err()
+		if spanErr == nil {
+			spanErr = err
+		}
+		tracing.End(r.span, spanErr)
+		r.span = nil
+	}
+	return err
+}
+
+func (t *tracedConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(query)
+	row := t.conn.QueryRowContext(ctx, query, args...)
+	tracing.End(span, row.Err())
+	return row
+}
+
+func (t *tracedConn) start(query string) (context.Context, trace.Span) {
+	if !trace.SpanContextFromContext(t.ctx).IsValid() {
+		return t.ctx, noop.Span{}
+	}
+
+	name := t.name
+	if name == "" {
+		name = callerName(3)
+	}
+	return tracing.Tracer.Start(t.ctx, "db "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.query.text", strings.TrimSpace(query)),
+		),
+	)
+}
+
+// callerName returns the short name, e.g. CashbackRepository.GetLedgerBalance,
+// of the function skip frames up the stack.
+func callerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "query"
+	}
+	name := runtime.FuncForPC(pc).Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimPrefix(name, "repository.")
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
@@ -0,0 +1,495 @@
+package repository
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// conn returns the pool running queries with ctx.
+func (r *WebhookRepository) conn(ctx context.Context) dbtx {
+	return &tracedConn{conn: r.db, ctx: ctx, name: callerName(2)}
+}
+
+// insertWebhookEvent writes an event to the outbox together with a pending
+// delivery for every subscription that wants it. An event nobody subscribed
+// to is not stored. db is the caller's transaction, so the event commits or
+// rolls back with its cause.
+func insertWebhookEvent(db dbtx, event *models.WebhookEvent) error {
+	query := `
+		WITH subscribers AS (
+			SELECT s.id
+			FROM webhook_subscriptions s
+			WHERE s.deleted_at IS NULL
+			AND (s.source_id IS NULL OR s.source_id = $source_id$)
+			AND $type$ = ANY(s.events)
+		), event AS (
+			INSERT INTO webhook_events (
+				type,
+				source_id,
+				payload,
+				created_at
+			)
+			SELECT
+				$type$,
+				$source_id$,
+				$payload$,
+				$now$
+			WHERE EXISTS (SELECT 1 FROM subscribers)
+			RETURNING id
+		)
+		INSERT INTO webhook_deliveries (
+			event_id,
+			subscription_id,
+			status,
+			next_attempt_at,
+			created_at,
+			updated_at
+		)
+		SELECT
+			event.id,
+			subscribers.id,
+			$pending$,
+			$now$,
+			$now$,
+			$now$
+		FROM event
+		CROSS JOIN subscribers`
+
+	now := time.Now()
+	args := map[string]interface{}{
+		"$type$":      event.Type,
+		"$source_id$": nullInt64(event.SourceID),
+		"$payload$":   []byte(event.Payload),
+		"$now$":       now,
+		"$pending$":   constants.DeliveryStatusPending,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if _, err := db.Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to create webhook event: %w", err)
+	}
+
+	event.CreatedAt = now
+	return nil
+}
+
+func (r *CashbackRepository) CreateWebhookEvent(event *models.WebhookEvent) error {
+	return insertWebhookEvent(r.db, event)
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription, sourceID int64) error {
+	query := `
+		INSERT INTO webhook_subscriptions (
+			source_id,
+			url,
+			secret,
+			events,
+			created_at,
+			updated_at
+		) VALUES (
+			$source_id$,
+			$url$,
+			$secret$,
+			$events$,
+			$now$,
+			$now$
+		) RETURNING id`
+
+	now := time.Now()
+	args := map[string]interface{}{
+		"$source_id$": nullInt64(sourceID),
+		"$url$":       subscription.URL,
+		"$secret$":    subscription.Secret,
+		"$events$":    pq.Array(subscription.Events),
+		"$now$":       now,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if err := r.conn(ctx).QueryRow(namedQuery, namedArgs...).Scan(&subscription.ID); err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %w", err)
+	}
+
+	subscription.CreatedAt = now
+	return nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	query := `
+		SELECT
+			w.id,
+			s.slug,
+			w.url,
+			w.events,
+			w.created_at
+		FROM webhook_subscriptions w
+		LEFT JOIN sources s ON s.id = w.source_id
+		WHERE w.deleted_at IS NULL
+		ORDER BY w.id`
+
+	rows, err := r.conn(ctx).Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.WebhookSubscription{}
+	for rows.Next() {
+		var (
+			subscription models.WebhookSubscription
+			source       sql.NullString
+		)
+		if err := rows.Scan(
+			&subscription.ID,
+			&source,
+			&subscription.URL,
+			pq.Array(&subscription.Events),
+			&subscription.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %w", err)
+		}
+		if source.Valid {
+			subscription.Source = &source.String
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// DeleteSubscription removes a subscription and abandons its pending
+// deliveries. It reports false when there was no such subscription.
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id int64) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	db := &tracedConn{conn: tx, ctx: ctx}
+
+	now := time.Now()
+	query := `
+		UPDATE webhook_subscriptions
+		SET
+			deleted_at = $now$,
+			updated_at = $now$
+		WHERE id = $id$
+		AND deleted_at IS NULL`
+
+	args := map[string]interface{}{
+		"$now$": now,
+		"$id$":  id,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := db.Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	deliveriesQuery := `
+		UPDATE webhook_deliveries
+		SET
+			status = $dead$,
+			last_error = 'subscription deleted',
+			updated_at = $now$
+		WHERE subscription_id = $id$
+		AND status = $pending$`
+
+	deliveriesArgs := map[string]interface{}{
+		"$dead$":    constants.DeliveryStatusDead,
+		"$now$":     now,
+		"$id$":      id,
+		"$pending$": constants.DeliveryStatusPending,
+	}
+
+	namedQuery, namedArgs = buildNamedQuery(deliveriesQuery, deliveriesArgs)
+	if _, err := db.Exec(namedQuery, namedArgs...); err != nil {
+		return false, fmt.Errorf("failed to abandon webhook deliveries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+const deliveryColumns = `
+			d.id,
+			d.event_id,
+			e.type,
+			d.subscription_id,
+			s.url,
+			s.secret,
+			e.payload,
+			d.status,
+			d.attempts,
+			d.next_attempt_at,
+			d.last_status_code,
+			d.last_error,
+			d.delivered_at,
+			d.created_at,
+			d.updated_at`
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var (
+		delivery   models.WebhookDelivery
+		payload    []byte
+		statusCode sql.NullInt64
+		lastError  sql.NullString
+	)
+	err := row.Scan(
+		&delivery.ID,
+		&delivery.EventID,
+		&delivery.EventType,
+		&delivery.SubscriptionID,
+		&delivery.URL,
+		&delivery.Secret,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.NextAttemptAt,
+		&statusCode,
+		&lastError,
+		&delivery.DeliveredAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Payload = payload
+	if statusCode.Valid {
+		code := int(statusCode.Int64)
+		delivery.LastStatusCode = &code
+	}
+	if lastError.Valid {
+		delivery.LastError = &lastError.String
+	}
+	return &delivery, nil
+}
+
+// ClaimDueDeliveries takes up to limit pending deliveries that are due and
+// leases them until leaseUntil, so other replicas skip them meanwhile. A
+// dispatcher that dies mid-delivery leaves them to be retried after the
+// lease.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]models.WebhookDelivery, error) {
+	query := `
+		WITH due AS (
+			SELECT id
+			FROM webhook_deliveries
+			WHERE status = $pending$
+			AND next_attempt_at <= $now$
+			ORDER BY next_attempt_at
+			LIMIT $limit$
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET
+			next_attempt_at = $lease_until$,
+			updated_at = $now$
+		FROM due, webhook_events e, webhook_subscriptions s
+		WHERE d.id = due.id
+		AND e.id = d.event_id
+		AND s.id = d.subscription_id
+		RETURNING` + deliveryColumns
+
+	args := map[string]interface{}{
+		"$pending$":     constants.DeliveryStatusPending,
+		"$now$":         time.Now(),
+		"$limit$":       limit,
+		"$lease_until$": leaseUntil,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	return r.queryDeliveries(ctx, namedQuery, namedArgs)
+}
+
+func (r *WebhookRepository) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET
+			status = $delivered$,
+			attempts = attempts + 1,
+			last_status_code = $status_code$,
+			last_error = NULL,
+			delivered_at = $now$,
+			updated_at = $now$
+		WHERE id = $id$`
+
+	args := map[string]interface{}{
+		"$delivered$":   constants.DeliveryStatusDelivered,
+		"$status_code$": statusCode,
+		"$now$":         time.Now(),
+		"$id$":          id,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if _, err := r.conn(ctx).Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to mark webhook delivery delivered: %w", err)
+	}
+	return nil
+}
+
+// MarkAttemptFailed records a failed attempt. The delivery is retried at
+// nextAttemptAt, or moved to the dead-letter list when dead is set.
+func (r *WebhookRepository) MarkAttemptFailed(ctx context.Context, id int64, statusCode int, message string, nextAttemptAt time.Time, dead bool) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET
+			status = $status$,
+			attempts = attempts + 1,
+			last_status_code = $status_code$,
+			last_error = $error$,
+			next_attempt_at = $next_attempt_at$,
+			updated_at = $now$
+		WHERE id = $id$`
+
+	status := constants.DeliveryStatusPending
+	if dead {
+		status = constants.DeliveryStatusDead
+	}
+	args := map[string]interface{}{
+		"$status$":          status,
+		"$status_code$":     sql.NullInt64{Int64: int64(statusCode), Valid: statusCode != 0},
+		"$error$":           message,
+		"$next_attempt_at$": nextAttemptAt,
+		"$now$":             time.Now(),
+		"$id$":              id,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	if _, err := r.conn(ctx).Exec(namedQuery, namedArgs...); err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListDeadDeliveries(ctx context.Context, pagination *models.Pagination) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT` + deliveryColumns + `
+		FROM webhook_deliveries d
+		JOIN webhook_events e ON e.id = d.event_id
+		JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		WHERE d.status = $dead$
+		ORDER BY d.updated_at DESC, d.id DESC
+		LIMIT $limit$ OFFSET $offset$`
+
+	args := map[string]interface{}{
+		"$dead$":   constants.DeliveryStatusDead,
+		"$limit$":  pagination.Limit,
+		"$offset$": pagination.Offset,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	return r.queryDeliveries(ctx, namedQuery, namedArgs)
+}
+
+// Redeliver puts a dead or delivered delivery back in the queue with a fresh
+// attempt budget. It reports false when there is no such delivery or its
+// subscription is gone.
+func (r *WebhookRepository) Redeliver(ctx context.Context, id int64) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET
+			status = $pending$,
+			attempts = 0,
+			next_attempt_at = $now$,
+			updated_at = $now$
+		FROM webhook_subscriptions s
+		WHERE d.id = $id$
+		AND s.id = d.subscription_id
+		AND s.deleted_at IS NULL`
+
+	args := map[string]interface{}{
+		"$pending$": constants.DeliveryStatusPending,
+		"$now$":     time.Now(),
+		"$id$":      id,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := r.conn(ctx).Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return false, fmt.Errorf("failed to redeliver webhook: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// DeleteDeliveredBefore removes the deliveries that succeeded before the
+// given instant, then the events created before it that have no delivery
+// left. It returns how many events were removed.
+func (r *WebhookRepository) DeleteDeliveredBefore(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		WITH delivered AS (
+			DELETE FROM webhook_deliveries
+			WHERE status = $delivered$
+			AND delivered_at < $before$
+			RETURNING id
+		)
+		DELETE FROM webhook_events e
+		WHERE e.created_at < $before$
+		AND NOT EXISTS (
+			SELECT 1
+			FROM webhook_deliveries d
+			WHERE d.event_id = e.id
+			AND d.id NOT IN (SELECT id FROM delivered)
+		)`
+
+	args := map[string]interface{}{
+		"$delivered$": constants.DeliveryStatusDelivered,
+		"$before$":    before,
+	}
+
+	namedQuery, namedArgs := buildNamedQuery(query, args)
+	result, err := r.conn(ctx).Exec(namedQuery, namedArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete delivered webhook events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *WebhookRepository) queryDeliveries(ctx context.Context, query string, args []interface{}) ([]models.WebhookDelivery, error) {
+	rows, err := r.conn(ctx).Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
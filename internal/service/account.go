@@ -0,0 +1,131 @@
+package service
+
+import (
+	constants "cashback-serv/const"
+	core "cashback-serv/internal/interfaces"
+	"cashback-serv/internal/queue"
+	"cashback-serv/models"
+	"context"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrAccountNotFound   = errors.New("cashback account not found")
+	ErrInvalidTransition = errors.New("account status does not allow this change")
+	ErrAccountFrozen     = queue.ErrAccountFrozen
+	ErrAccountClosed     = queue.ErrAccountClosed
+)
+
+// FreezeAccount stops debits on the account, and credits too when
+// req.BlockCredits is set.
+func (s *CashbackService) FreezeAccount(turonUserID int64, req *models.AccountStatusRequest) (*models.Cashback, error) {
+	return s.changeAccountStatus(turonUserID, req, constants.AccountStatusActive, constants.AccountStatusFrozen)
+}
+
+func (s *CashbackService) UnfreezeAccount(turonUserID int64, req *models.AccountStatusRequest) (*models.Cashback, error) {
+	unfreeze := *req
+	unfreeze.BlockCredits = false
+	return s.changeAccountStatus(turonUserID, &unfreeze, constants.AccountStatusFrozen, constants.AccountStatusActive)
+}
+
+func (s *CashbackService) changeAccountStatus(turonUserID int64, req *models.AccountStatusRequest, from, to string) (*models.Cashback, error) {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return nil, err
+	}
+	if err := validateStatusReason(req.Reason, req.Operator); err != nil {
+		return nil, err
+	}
+
+	var result *models.Cashback
+	err := s.repo.WithTx(func(tx core.CashbackTx) error {
+		cashback, err := tx.GetCashbackByUserIDForUpdate(turonUserID)
+		if err != nil {
+			return err
+		}
+		if cashback == nil {
+			return ErrAccountNotFound
+		}
+		if cashback.Status != from {
+			return ErrInvalidTransition
+		}
+
+		change := &models.AccountStatusChange{
+			CashbackID:    cashback.ID,
+			FromStatus:    from,
+			ToStatus:      to,
+			Reason:        strings.TrimSpace(req.Reason),
+			Operator:      strings.TrimSpace(req.Operator),
+			FreezeCredits: req.BlockCredits,
+		}
+		if err := tx.UpdateCashbackStatus(change); err != nil {
+			return err
+		}
+
+		cashback.Status = to
+		cashback.FreezeCredits = change.FreezeCredits
+		cashback.StatusReason = &change.Reason
+		cashback.StatusChangedAt = &change.CreatedAt
+		result = cashback
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CloseAccount pays out or forfeits the remaining balance, recording it as a
+// history entry, and then closes the account. The settlement runs through the
+// cashback queue so it cannot race the user's other operations.
+func (s *CashbackService) CloseAccount(ctx context.Context, turonUserID int64, req *models.CloseAccountRequest, hostIP string) (*models.AccountClosure, error) {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return nil, err
+	}
+	if err := validateStatusReason(req.Reason, req.Operator); err != nil {
+		return nil, err
+	}
+	switch req.Disposition {
+	case constants.ClosureDispositionPayout, constants.ClosureDispositionForfeit:
+	default:
+		return nil, invalidArgument("disposition must be payout or forfeit")
+	}
+
+	cashback, err := s.repo.GetCashbackByUserID(ctx, turonUserID)
+	if err != nil {
+		return nil, err
+	}
+	if cashback == nil {
+		return nil, ErrAccountNotFound
+	}
+
+	closure := *req
+	closure.Reason = strings.TrimSpace(req.Reason)
+	closure.Operator = strings.TrimSpace(req.Operator)
+
+	result, err := s.queue.EnqueueRequest(ctx, constants.Close, &queue.QueueRequest{
+		CashbackRequest: &models.CashbackRequest{
+			TuronUserID: turonUserID,
+			HostIP:      hostIP,
+		},
+		Closure: &closure,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AccountClosure{
+		Account:    result.Cashback,
+		Settlement: result.History,
+	}, nil
+}
+
+func validateStatusReason(reason, operator string) error {
+	if strings.TrimSpace(reason) == "" {
+		return invalidArgument("reason must be provided")
+	}
+	if strings.TrimSpace(operator) == "" {
+		return invalidArgument("operator must be provided")
+	}
+	return nil
+}
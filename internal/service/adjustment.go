@@ -0,0 +1,175 @@
+package service
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/internal/queue"
+	"cashback-serv/internal/repository"
+	"cashback-serv/models"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DefaultAdjustmentApprovalThreshold is the absolute amount above which a
+// manual adjustment waits for a second operator.
+const DefaultAdjustmentApprovalThreshold = 1000
+
+var (
+	ErrInvalidAdjustment    = errors.New("invalid adjustment")
+	ErrAdjustmentNotFound   = errors.New("adjustment not found")
+	ErrAdjustmentNotPending = repository.ErrAdjustmentNotPending
+	ErrSelfApproval         = errors.New("adjustment must be decided by a different operator")
+)
+
+var adjustmentReasonCodes = map[string]bool{
+	constants.AdjustmentReasonCorrection:   true,
+	constants.AdjustmentReasonGoodwill:     true,
+	constants.AdjustmentReasonCompensation: true,
+	constants.AdjustmentReasonFraud:        true,
+	constants.AdjustmentReasonOther:        true,
+}
+
+func (s *CashbackService) SetAdjustmentApprovalThreshold(threshold float64) {
+	s.adjustmentApprovalThreshold = threshold
+}
+
+// RequestAdjustment records a manual adjustment and posts it straight away
+// when it is within the approval threshold. Larger adjustments are returned
+// with status pending_approval.
+func (s *CashbackService) RequestAdjustment(turonUserID int64, req *models.AdjustmentRequest, hostIP string) (*models.CashbackAdjustment, error) {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAdjustment, err)
+	}
+	if err := validateAdjustmentRequest(req); err != nil {
+		return nil, err
+	}
+
+	adjustment := &models.CashbackAdjustment{
+		TuronUserID: turonUserID,
+		Amount:      math.Round(req.Amount*100) / 100,
+		ReasonCode:  req.ReasonCode,
+		Comment:     strings.TrimSpace(req.Comment),
+		RequestedBy: strings.TrimSpace(req.Operator),
+		Status:      constants.AdjustmentStatusApproved,
+		HostIP:      hostIP,
+	}
+	if math.Abs(adjustment.Amount) > s.adjustmentApprovalThreshold {
+		adjustment.Status = constants.AdjustmentStatusPendingApproval
+	}
+
+	if err := s.repo.CreateAdjustment(adjustment); err != nil {
+		return nil, fmt.Errorf("failed to create adjustment: %w", err)
+	}
+
+	if adjustment.Status == constants.AdjustmentStatusPendingApproval {
+		return adjustment, nil
+	}
+	return s.postAdjustment(adjustment, nil)
+}
+
+// ApproveAdjustment posts a pending adjustment on behalf of a second operator.
+func (s *CashbackService) ApproveAdjustment(id int64, decision *models.AdjustmentDecision) (*models.CashbackAdjustment, error) {
+	adjustment, err := s.pendingAdjustment(id, decision)
+	if err != nil {
+		return nil, err
+	}
+	return s.postAdjustment(adjustment, decision)
+}
+
+func (s *CashbackService) RejectAdjustment(id int64, decision *models.AdjustmentDecision) (*models.CashbackAdjustment, error) {
+	if _, err := s.pendingAdjustment(id, decision); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.MarkAdjustmentRejected(id, strings.TrimSpace(decision.Operator), strings.TrimSpace(decision.Comment)); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAdjustmentByID(id)
+}
+
+func (s *CashbackService) GetAdjustment(id int64) (*models.CashbackAdjustment, error) {
+	adjustment, err := s.repo.GetAdjustmentByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if adjustment == nil {
+		return nil, ErrAdjustmentNotFound
+	}
+	return adjustment, nil
+}
+
+func (s *CashbackService) ListAdjustments(status string, pagination *models.Pagination) ([]models.CashbackAdjustment, error) {
+	if err := s.validatePagination(pagination); err != nil {
+		return nil, err
+	}
+
+	pagination.Calculate()
+	return s.repo.ListAdjustments(status, pagination)
+}
+
+func (s *CashbackService) pendingAdjustment(id int64, decision *models.AdjustmentDecision) (*models.CashbackAdjustment, error) {
+	if strings.TrimSpace(decision.Operator) == "" {
+		return nil, fmt.Errorf("%w: operator must be provided", ErrInvalidAdjustment)
+	}
+
+	adjustment, err := s.GetAdjustment(id)
+	if err != nil {
+		return nil, err
+	}
+	if adjustment.Status != constants.AdjustmentStatusPendingApproval {
+		return nil, ErrAdjustmentNotPending
+	}
+	if strings.EqualFold(adjustment.RequestedBy, strings.TrimSpace(decision.Operator)) {
+		return nil, ErrSelfApproval
+	}
+	return adjustment, nil
+}
+
+// postAdjustment runs the adjustment through the cashback queue. A posting
+// failure such as insufficient balance is recorded on the adjustment.
+func (s *CashbackService) postAdjustment(adjustment *models.CashbackAdjustment, decision *models.AdjustmentDecision) (*models.CashbackAdjustment, error) {
+	req := &queue.QueueRequest{
+		CashbackRequest: &models.CashbackRequest{
+			TuronUserID:    adjustment.TuronUserID,
+			CashbackAmount: adjustment.Amount,
+			HostIP:         adjustment.HostIP,
+			Reason:         adjustment.ReasonCode,
+		},
+		AdjustmentID: adjustment.ID,
+		Approval:     decision,
+	}
+
+	if _, err := s.queue.EnqueueRequest(context.Background(), constants.Adjust, req); err != nil {
+		if errors.Is(err, ErrAdjustmentNotPending) {
+			return nil, err
+		}
+
+		var approvedBy, comment string
+		if decision != nil {
+			approvedBy, comment = strings.TrimSpace(decision.Operator), strings.TrimSpace(decision.Comment)
+		}
+		if markErr := s.repo.MarkAdjustmentFailed(adjustment.ID, approvedBy, comment, err.Error()); markErr != nil {
+			return nil, fmt.Errorf("failed to post adjustment: %v (and to record the failure: %w)", err, markErr)
+		}
+	}
+
+	return s.repo.GetAdjustmentByID(adjustment.ID)
+}
+
+func validateAdjustmentRequest(req *models.AdjustmentRequest) error {
+	if math.Round(req.Amount*100) == 0 {
+		return fmt.Errorf("%w: amount must be a non-zero value", ErrInvalidAdjustment)
+	}
+	if !adjustmentReasonCodes[req.ReasonCode] {
+		return fmt.Errorf("%w: unknown reason_code %q", ErrInvalidAdjustment, req.ReasonCode)
+	}
+	if strings.TrimSpace(req.Comment) == "" {
+		return fmt.Errorf("%w: comment must be provided", ErrInvalidAdjustment)
+	}
+	if strings.TrimSpace(req.Operator) == "" {
+		return fmt.Errorf("%w: operator must be provided", ErrInvalidAdjustment)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"cashback-serv/models"
+	"time"
+)
+
+const maxTopEarners = 1000
+
+// resolveAnalyticsFilter validates the filter and resolves its bounds. Both
+// bounds are required so a dashboard cannot scan the rollups unbounded.
+// Rollups are hourly, so bounds take effect at hour granularity.
+func (s *CashbackService) resolveAnalyticsFilter(filter *models.AnalyticsFilter) error {
+	if filter.FromDate == "" || filter.ToDate == "" {
+		return invalidArgument("from_date and to_date must be provided")
+	}
+
+	dates := &models.HistoryFilter{
+		FromDate: filter.FromDate,
+		ToDate:   filter.ToDate,
+		TimeZone: filter.TimeZone,
+	}
+	if err := s.resolveDates(dates); err != nil {
+		return err
+	}
+	filter.From, filter.Until = dates.From, dates.Until
+
+	loc, err := s.location(filter.TimeZone)
+	if err != nil {
+		return err
+	}
+	if err := checkWholeHourOffsets(loc, filter.From, filter.Until); err != nil {
+		return err
+	}
+	filter.TimeZone = loc.String()
+
+	switch filter.Interval {
+	case "":
+		filter.Interval = "day"
+	case "day", "week", "month":
+	default:
+		return invalidArgument("interval must be day, week or month")
+	}
+
+	switch filter.GroupBy {
+	case "", "source", "operation":
+	default:
+		return invalidArgument("group_by must be source or operation")
+	}
+
+	if filter.Limit < 1 {
+		filter.Limit = 10
+	}
+	if filter.Limit > maxTopEarners {
+		filter.Limit = maxTopEarners
+	}
+	return nil
+}
+
+// GetAnalyticsTotals returns credited, debited and net amounts per period,
+// optionally split by source or operation.
+func (s *CashbackService) GetAnalyticsTotals(filter *models.AnalyticsFilter) ([]models.AnalyticsTotal, error) {
+	if err := s.resolveAnalyticsFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAnalyticsTotals(filter)
+}
+
+// GetLiability returns the cashback outstanding at the end of each period.
+func (s *CashbackService) GetLiability(filter *models.AnalyticsFilter) ([]models.LiabilityPoint, error) {
+	if err := s.resolveAnalyticsFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.repo.GetLiability(filter)
+}
+
+func (s *CashbackService) GetTopEarners(filter *models.AnalyticsFilter) ([]models.TopEarner, error) {
+	if err := s.resolveAnalyticsFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTopEarners(filter)
+}
+
+func (s *CashbackService) GetActiveUsers(filter *models.AnalyticsFilter) ([]models.ActiveUsersPoint, error) {
+	if err := s.resolveAnalyticsFilter(filter); err != nil {
+		return nil, err
+	}
+	return s.repo.GetActiveUsers(filter)
+}
+
+// checkWholeHourOffsets rejects a zone whose UTC offset is not a whole number
+// of hours anywhere in [from, until). Rollups are bucketed by UTC hour, so
+// local days in such a zone (Asia/Kolkata, say) would start mid-bucket.
+func checkWholeHourOffsets(loc *time.Location, from, until *time.Time) error {
+	if from == nil || until == nil {
+		return nil
+	}
+	for t := from.In(loc); t.Before(*until); {
+		if _, offset := t.Zone(); offset%3600 != 0 {
+			return invalidArgument("time zone %s is not a whole number of hours from UTC, which analytics require", loc)
+		}
+		_, end := t.ZoneBounds()
+		if end.IsZero() {
+			break
+		}
+		t = end.In(loc)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestCheckWholeHourOffsets(t *testing.T) {
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		name     string
+		zone     string
+		from     *time.Time
+		until    *time.Time
+		rejected bool
+	}{
+		{name: "half hour zone", zone: "Asia/Kolkata", from: date(2024, 1, 1), until: date(2024, 2, 1), rejected: true},
+		{name: "whole hour zone", zone: "Asia/Tashkent", from: date(2024, 1, 1), until: date(2025, 1, 1)},
+		{name: "whole hour DST change", zone: "Europe/Berlin", from: date(2024, 3, 1), until: date(2024, 11, 1)},
+		// Lord Howe is +11:00 in summer and +10:30 from the first Sunday of
+		// April.
+		{name: "summer only", zone: "Australia/Lord_Howe", from: date(2024, 1, 1), until: date(2024, 3, 1)},
+		{name: "into half hour standard time", zone: "Australia/Lord_Howe", from: date(2024, 3, 1), until: date(2024, 5, 1), rejected: true},
+		{name: "open range", zone: "Asia/Kolkata", from: date(2024, 1, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = checkWholeHourOffsets(loc, tt.from, tt.until)
+			if !tt.rejected {
+				if err != nil {
+					t.Errorf("got %v, want the zone accepted", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Errorf("got %v, want %v", err, ErrInvalidArgument)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"cashback-serv/models"
+	"context"
+	"encoding/csv"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// snapshotLag keeps snapshots clear of transactions that are still in flight:
+// a posting stamped just before the snapshot instant may not have committed.
+const snapshotLag = 5 * time.Minute
+
+func (s *CashbackService) GetCashbackBalanceAsOf(ctx context.Context, turonUserID int64, asOf time.Time) (*models.CashbackBalance, error) {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return nil, err
+	}
+
+	cashback, err := s.getAccountForHistory(ctx, turonUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance := &models.CashbackBalance{
+		TuronUserID: turonUserID,
+		AsOf:        asOf,
+	}
+	if cashback == nil || cashback.CreatedAt.After(asOf) {
+		return balance, nil
+	}
+	balance.CashbackID = cashback.ID
+
+	balance.CashbackAmount, err = s.repo.GetBalanceAsOf(cashback.ID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return balance, nil
+}
+
+// TakeBalanceSnapshots stores a snapshot of every wallet as of asOf.
+func (s *CashbackService) TakeBalanceSnapshots(asOf time.Time) (int64, error) {
+	return s.repo.CreateBalanceSnapshots(asOf)
+}
+
+// StartBalanceSnapshots takes snapshots every interval in the background so
+// that as-of queries only need to sum the postings since the last one.
+func (s *CashbackService) StartBalanceSnapshots(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			asOf := time.Now().Add(-snapshotLag)
+			if _, err := s.TakeBalanceSnapshots(asOf); err != nil {
+				slog.Error("balance snapshot failed", "error", err)
+			}
+		}
+	}()
+}
+
+var balanceCSVHeader = []string{
+	"turon_user_id",
+	"cashback_id",
+	"cashback_amount",
+	"as_of",
+}
+
+// ExportBalancesAsOf streams every user's balance at asOf as CSV.
+func (s *CashbackService) ExportBalancesAsOf(w io.Writer, asOf time.Time) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(balanceCSVHeader); err != nil {
+		return err
+	}
+
+	asOfText := asOf.Format(time.RFC3339)
+	err := s.repo.StreamBalancesAsOf(asOf, func(balance *models.CashbackBalance) error {
+		return writer.Write([]string{
+			strconv.FormatInt(balance.TuronUserID, 10),
+			strconv.FormatInt(balance.CashbackID, 10),
+			formatAmount(balance.CashbackAmount),
+			asOfText,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
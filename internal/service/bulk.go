@@ -0,0 +1,295 @@
+package service
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/internal/queue"
+	"cashback-serv/internal/repository"
+	"cashback-serv/models"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxBulkRows caps a single bulk payout.
+	MaxBulkRows = 50000
+	// MaxPendingBatches is how many bulk payouts may wait for the batch
+	// worker at once.
+	MaxPendingBatches = 100
+)
+
+var (
+	ErrBatchNotFound = errors.New("batch not found")
+	ErrBatchConflict = errors.New("batch_id was already used for different rows")
+)
+
+// BulkValidationError lists every rejected row of a bulk payout. Nothing is
+// posted when it is returned.
+type BulkValidationError struct {
+	Rows []models.BulkRowError
+}
+
+func (e *BulkValidationError) Error() string {
+	return fmt.Sprintf("%d invalid rows", len(e.Rows))
+}
+
+func (e *BulkValidationError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+// ParseBulkCSV reads bulk payout rows from CSV with a header naming the
+// turon_user_id and cashback_amount columns and, optionally, reason.
+func ParseBulkCSV(r io.Reader) ([]models.BulkCreditRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, invalidArgument("failed to read CSV header: %v", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	userColumn, ok := columns["turon_user_id"]
+	if !ok {
+		return nil, invalidArgument("CSV header must include turon_user_id")
+	}
+	amountColumn, ok := columns["cashback_amount"]
+	if !ok {
+		return nil, invalidArgument("CSV header must include cashback_amount")
+	}
+	reasonColumn, hasReason := columns["reason"]
+
+	var (
+		rows      []models.BulkCreditRow
+		rowErrors []models.BulkRowError
+	)
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, invalidArgument("failed to read CSV row %d: %v", line, err)
+		}
+
+		var row models.BulkCreditRow
+		if row.TuronUserID, err = strconv.ParseInt(strings.TrimSpace(record[userColumn]), 10, 64); err != nil {
+			rowErrors = append(rowErrors, models.BulkRowError{Row: line, Error: "invalid turon_user_id format"})
+			continue
+		}
+		if row.CashbackAmount, err = strconv.ParseFloat(strings.TrimSpace(record[amountColumn]), 64); err != nil {
+			rowErrors = append(rowErrors, models.BulkRowError{Row: line, Error: "invalid cashback_amount format"})
+			continue
+		}
+		if hasReason {
+			row.Reason = record[reasonColumn]
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, &BulkValidationError{Rows: rowErrors}
+	}
+	return rows, nil
+}
+
+// batchJob is a bulk payout waiting for the batch worker.
+type batchJob struct {
+	id     string
+	hostIP string
+}
+
+// BulkCredit stores the rows under the batch ID and credits them through the
+// cashback queue in the background. The returned batch is processing; poll
+// GetBatch for the outcome. Submitting the same batch again resumes it:
+// posted rows are skipped and the rest, failed ones included, are retried.
+// When MaxPendingBatches are waiting, it fails with ErrTooManyOperations and
+// the stored batch is picked up by the next submission.
+func (s *CashbackService) BulkCredit(ctx context.Context, batchID string, rows []models.BulkCreditRow, hostIP string) (*models.CashbackBatch, error) {
+	batchID = strings.TrimSpace(batchID)
+	if batchID == "" {
+		batchID = newBatchID()
+	}
+	if len(batchID) > 64 {
+		return nil, invalidArgument("batch_id must be at most 64 characters")
+	}
+	if err := validateBulkRows(rows); err != nil {
+		return nil, err
+	}
+
+	batch := &models.CashbackBatch{
+		ID:       batchID,
+		Checksum: bulkChecksum(rows),
+		HostIP:   hostIP,
+		Items:    make([]models.BatchItem, len(rows)),
+	}
+	for i, row := range rows {
+		batch.Items[i] = models.BatchItem{
+			Row:            i + 1,
+			TuronUserID:    row.TuronUserID,
+			CashbackAmount: math.Round(row.CashbackAmount*100) / 100,
+			Reason:         strings.TrimSpace(row.Reason),
+		}
+	}
+
+	created, err := s.repo.CreateBatch(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		existing, err := s.repo.GetBatch(ctx, batchID)
+		if err != nil {
+			return nil, err
+		}
+		if existing.Checksum != batch.Checksum {
+			return nil, ErrBatchConflict
+		}
+		if err := s.repo.SetBatchStatus(ctx, batchID, constants.BatchStatusProcessing); err != nil {
+			return nil, err
+		}
+	}
+
+	select {
+	case s.batches <- batchJob{id: batchID, hostIP: hostIP}:
+	default:
+		return nil, ErrTooManyOperations
+	}
+	return s.repo.GetBatch(ctx, batchID)
+}
+
+// ResumeBatches schedules the batches left processing by a previous run.
+// Rows posted before the restart are skipped.
+func (s *CashbackService) ResumeBatches() error {
+	batches, err := s.repo.ListProcessingBatches(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, batch := range batches {
+		select {
+		case s.batches <- batchJob{id: batch.ID, hostIP: batch.HostIP}:
+		default:
+			slog.Warn("Batch queue is full, batch left for resubmission", "batch_id", batch.ID)
+		}
+	}
+	return nil
+}
+
+func (s *CashbackService) GetBatch(ctx context.Context, id string) (*models.CashbackBatch, error) {
+	batch, err := s.repo.GetBatch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, ErrBatchNotFound
+	}
+	return batch, nil
+}
+
+// processBatches runs the scheduled batches one at a time. A batch that
+// fails part way stays processing and is resumed by the next submission or
+// restart.
+func (s *CashbackService) processBatches() {
+	for job := range s.batches {
+		if err := s.processBatch(job.id, job.hostIP); err != nil {
+			slog.Error("Batch processing failed", "batch_id", job.id, "error", err)
+		}
+	}
+}
+
+// processBatch posts the unfinished rows one by one. A row that fails is
+// recorded and the batch carries on.
+func (s *CashbackService) processBatch(batchID, hostIP string) error {
+	ctx := context.Background()
+
+	items, err := s.repo.ListUnfinishedBatchItems(ctx, batchID)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		// The source depends only on the caller, not on the row.
+		source, err := s.sourceService.FindSourceOrCreate(ctx, item.TuronUserID, hostIP)
+		if err != nil {
+			return fmt.Errorf("failed to determine source: %w", err)
+		}
+
+		req := &queue.QueueRequest{
+			CashbackRequest: &models.CashbackRequest{
+				TuronUserID:    item.TuronUserID,
+				CashbackAmount: item.CashbackAmount,
+				HostIP:         hostIP,
+				Reason:         item.Reason,
+			},
+			SourceID: source.ID,
+			BatchID:  batchID,
+			BatchRow: item.Row,
+		}
+
+		_, err = s.queue.EnqueueRequest(ctx, constants.Increase, req)
+		if err == nil || errors.Is(err, repository.ErrBatchItemDone) {
+			continue
+		}
+		if markErr := s.repo.MarkBatchItemFailed(ctx, batchID, item.Row, err.Error()); markErr != nil && !errors.Is(markErr, repository.ErrBatchItemDone) {
+			return fmt.Errorf("failed to record batch row %d failure: %w", item.Row, markErr)
+		}
+	}
+
+	return s.repo.SetBatchStatus(ctx, batchID, constants.BatchStatusCompleted)
+}
+
+func validateBulkRows(rows []models.BulkCreditRow) error {
+	if len(rows) == 0 {
+		return invalidArgument("at least one row must be provided")
+	}
+	if len(rows) > MaxBulkRows {
+		return invalidArgument("at most %d rows are allowed per batch", MaxBulkRows)
+	}
+
+	var rowErrors []models.BulkRowError
+	for i, row := range rows {
+		var message string
+		switch {
+		case row.TuronUserID <= 0:
+			message = "turon_user_id must be positive"
+		case math.Round(row.CashbackAmount*100) <= 0:
+			message = "cashback_amount must be positive"
+		case len(strings.TrimSpace(row.Reason)) > 255:
+			message = "reason must be at most 255 characters"
+		default:
+			continue
+		}
+		rowErrors = append(rowErrors, models.BulkRowError{Row: i + 1, Error: message})
+	}
+
+	if len(rowErrors) > 0 {
+		return &BulkValidationError{Rows: rowErrors}
+	}
+	return nil
+}
+
+// bulkChecksum fingerprints the rows so a retry can be told apart from a
+// different file reusing the batch ID.
+func bulkChecksum(rows []models.BulkCreditRow) string {
+	data, _ := json.Marshal(rows)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newBatchID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"cashback-serv/models"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBulkCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      []models.BulkCreditRow
+		rowErrors []models.BulkRowError
+		rejected  bool
+	}{
+		{
+			name:  "all columns",
+			input: "turon_user_id,cashback_amount,reason\n1,10.5,purchase\n2, 3,\n",
+			want: []models.BulkCreditRow{
+				{TuronUserID: 1, CashbackAmount: 10.5, Reason: "purchase"},
+				{TuronUserID: 2, CashbackAmount: 3},
+			},
+		},
+		{
+			name:  "header case and order",
+			input: "Cashback_Amount, TURON_USER_ID\n7,42\n",
+			want:  []models.BulkCreditRow{{TuronUserID: 42, CashbackAmount: 7}},
+		},
+		{name: "empty input", input: "", rejected: true},
+		{name: "missing user column", input: "user,cashback_amount\n1,10\n", rejected: true},
+		{name: "missing amount column", input: "turon_user_id,amount\n1,10\n", rejected: true},
+		{name: "short row", input: "turon_user_id,cashback_amount\n1\n", rejected: true},
+		{
+			// Rows are numbered from the first one after the header.
+			name:  "bad numbers",
+			input: "turon_user_id,cashback_amount\n1,10\nabc,10\n3,ten\n4,\n5,5\n",
+			rowErrors: []models.BulkRowError{
+				{Row: 2, Error: "invalid turon_user_id format"},
+				{Row: 3, Error: "invalid cashback_amount format"},
+				{Row: 4, Error: "invalid cashback_amount format"},
+			},
+			rejected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ParseBulkCSV(strings.NewReader(tt.input))
+			if !tt.rejected {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(rows, tt.want) {
+					t.Errorf("got %+v, want %+v", rows, tt.want)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidArgument)
+			}
+			if tt.rowErrors == nil {
+				return
+			}
+			var validationErr *BulkValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("got %v, want a BulkValidationError", err)
+			}
+			if !reflect.DeepEqual(validationErr.Rows, tt.rowErrors) {
+				t.Errorf("got %+v, want %+v", validationErr.Rows, tt.rowErrors)
+			}
+		})
+	}
+}
+
+func TestValidateBulkRows(t *testing.T) {
+	valid := models.BulkCreditRow{TuronUserID: 1, CashbackAmount: 10}
+	repeat := func(n int) []models.BulkCreditRow {
+		rows := make([]models.BulkCreditRow, n)
+		for i := range rows {
+			rows[i] = valid
+		}
+		return rows
+	}
+
+	tests := []struct {
+		name      string
+		rows      []models.BulkCreditRow
+		rowErrors []models.BulkRowError
+		rejected  bool
+	}{
+		{name: "valid", rows: repeat(3)},
+		{name: "at the cap", rows: repeat(MaxBulkRows)},
+		{name: "over the cap", rows: repeat(MaxBulkRows + 1), rejected: true},
+		{name: "empty", rejected: true},
+		{
+			name: "invalid rows",
+			rows: []models.BulkCreditRow{
+				valid,
+				{TuronUserID: 0, CashbackAmount: 10},
+				{TuronUserID: 1, CashbackAmount: -1},
+				// Rounds to zero cents.
+				{TuronUserID: 1, CashbackAmount: 0.004},
+				{TuronUserID: 1, CashbackAmount: 10, Reason: strings.Repeat("a", 256)},
+				{TuronUserID: 1, CashbackAmount: 10, Reason: strings.Repeat("a", 255)},
+			},
+			rowErrors: []models.BulkRowError{
+				{Row: 2, Error: "turon_user_id must be positive"},
+				{Row: 3, Error: "cashback_amount must be positive"},
+				{Row: 4, Error: "cashback_amount must be positive"},
+				{Row: 5, Error: "reason must be at most 255 characters"},
+			},
+			rejected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBulkRows(tt.rows)
+			if !tt.rejected {
+				if err != nil {
+					t.Errorf("got %v, want the rows accepted", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidArgument) {
+				t.Fatalf("got %v, want %v", err, ErrInvalidArgument)
+			}
+			var validationErr *BulkValidationError
+			if tt.rowErrors == nil {
+				if errors.As(err, &validationErr) {
+					t.Errorf("got row errors %+v, want the batch rejected as a whole", validationErr.Rows)
+				}
+				return
+			}
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("got %v, want a BulkValidationError", err)
+			}
+			if !reflect.DeepEqual(validationErr.Rows, tt.rowErrors) {
+				t.Errorf("got %+v, want %+v", validationErr.Rows, tt.rowErrors)
+			}
+		})
+	}
+}
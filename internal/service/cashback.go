@@ -4,80 +4,264 @@ import (
 	constants "cashback-serv/const"
 	core "cashback-serv/internal/interfaces"
 	"cashback-serv/internal/queue"
+	"cashback-serv/internal/repository"
 	"cashback-serv/models"
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"time"
 )
 
 type CashbackRepository interface {
-	GetCashbackByUserID(turonUserID int64) (*models.Cashback, error)
-	CreateCashback(cashback *models.Cashback) error
-	UpdateCashbackAmount(id int64, amount float64) error
-	CreateCashbackHistory(history *models.CashbackHistory) error
-	GetCashbackHistoryByUserID(turonUserID int64, fromDate, toDate string, pagination *models.Pagination) ([]models.CashbackHistory, error)
+	WithTx(fn func(tx core.CashbackTx) error) error
+	WithTxContext(ctx context.Context, fn func(tx core.CashbackTx) error) error
+	GetCashbackByUserID(ctx context.Context, turonUserID int64) (*models.Cashback, error)
+	GetCashbackAccount(ctx context.Context, turonUserID int64) (*models.Cashback, error)
+	GetCashbacksByUserIDs(ctx context.Context, turonUserIDs []int64) ([]models.Cashback, error)
+	RebuildCashbackBalances() (int64, error)
+	CountCashbackAccounts(fromTuronUserID, toTuronUserID int64) (int64, error)
+	FindBalanceDiscrepancies(fromTuronUserID, toTuronUserID int64) ([]models.BalanceDiscrepancy, error)
+	CreateBalanceSnapshots(asOf time.Time) (int64, error)
+	GetBalanceAsOf(cashbackID int64, asOf time.Time) (float64, error)
+	StreamBalancesAsOf(asOf time.Time, fn func(balance *models.CashbackBalance) error) error
+	CreateAdjustment(adjustment *models.CashbackAdjustment) error
+	GetAdjustmentByID(id int64) (*models.CashbackAdjustment, error)
+	ListAdjustments(status string, pagination *models.Pagination) ([]models.CashbackAdjustment, error)
+	MarkAdjustmentRejected(id int64, rejectedBy, comment string) error
+	MarkAdjustmentFailed(id int64, approvedBy, comment, message string) error
+	GetCashbackHistoryByUserID(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, pagination *models.Pagination) ([]models.CashbackHistory, error)
+	GetCashbackHistoryByCursor(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, page *models.CursorPagination) ([]models.CashbackHistory, error)
+	StreamCashbackHistory(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, fn func(history *models.CashbackHistory) error) error
+	SaveStatement(statement *models.Statement) (bool, error)
+	GetStatement(cashbackID int64, period, timeZone string) (*models.Statement, error)
+	ListStatementAccounts(from, until time.Time) ([]models.Cashback, error)
+	GetAnalyticsTotals(filter *models.AnalyticsFilter) ([]models.AnalyticsTotal, error)
+	GetLiability(filter *models.AnalyticsFilter) ([]models.LiabilityPoint, error)
+	GetTopEarners(filter *models.AnalyticsFilter) ([]models.TopEarner, error)
+	GetActiveUsers(filter *models.AnalyticsFilter) ([]models.ActiveUsersPoint, error)
+	CreateBatch(ctx context.Context, batch *models.CashbackBatch) (bool, error)
+	GetBatch(ctx context.Context, id string) (*models.CashbackBatch, error)
+	ListProcessingBatches(ctx context.Context) ([]models.CashbackBatch, error)
+	ListUnfinishedBatchItems(ctx context.Context, batchID string) ([]models.BatchItem, error)
+	MarkBatchItemFailed(ctx context.Context, batchID string, row int, message string) error
+	SetBatchStatus(ctx context.Context, id, status string) error
+	CreateOperation(ctx context.Context, operation *models.Operation) error
+	GetOperation(ctx context.Context, id string) (*models.Operation, error)
+	MarkOperationFailed(ctx context.Context, id, message string) error
+	FailStaleOperations(ctx context.Context, before time.Time, message string) (int64, error)
+	DeleteOperationsBefore(ctx context.Context, before time.Time) (int64, error)
+	GetCashbackHistoryAfter(turonUserID, afterID int64, limit int) ([]models.CashbackHistory, error)
+	GetLastCashbackHistoryID(turonUserID int64) (int64, error)
+	GetCashbackHistoryByIdempotencyKey(ctx context.Context, key string) (*models.CashbackHistory, error)
+	GetOperationByIdempotencyKey(ctx context.Context, key string) (*models.Operation, error)
 }
 
 type CashbackService struct {
 	repo          CashbackRepository
 	queue         *queue.CashbackQueue
 	sourceService core.SourceFinderCreator
+	stream        *CashbackStream
+	// asyncSlots bounds the async operations waiting for the queue.
+	asyncSlots chan struct{}
+	// batches holds the bulk payouts waiting for the batch worker.
+	batches chan batchJob
+
+	adjustmentApprovalThreshold float64
+	timeZone                    *time.Location
 }
 
 func NewCashbackService(repo CashbackRepository, sourceService core.SourceFinderCreator) *CashbackService {
-	return &CashbackService{
+	service := &CashbackService{
 		repo:          repo,
 		queue:         queue.NewCashbackQueue(repo, sourceService),
 		sourceService: sourceService,
+		asyncSlots:    make(chan struct{}, MaxPendingOperations),
+		batches:       make(chan batchJob, MaxPendingBatches),
+
+		adjustmentApprovalThreshold: DefaultAdjustmentApprovalThreshold,
+		timeZone:                    time.UTC,
 	}
+
+	go service.processBatches()
+
+	return service
 }
 
 func (s *CashbackService) validateTuronUserID(turonUserID int64) error {
 	if turonUserID == 0 {
-		return errors.New("turon_user_id must be provided")
+		return invalidArgument("turon_user_id must be provided")
+	}
+	if turonUserID < 0 {
+		return invalidArgument("turon_user_id must be positive")
 	}
 	return nil
 }
 
-func (s *CashbackService) IncreaseCashback(req *models.CashbackRequest) error {
-	if err := s.validateTuronUserID(req.TuronUserID); err != nil {
-		return err
+// ErrNoCashback and ErrInsufficientCashback reject a decrease that the
+// wallet cannot cover.
+var (
+	ErrNoCashback           = queue.ErrNoCashback
+	ErrInsufficientCashback = queue.ErrInsufficientCashback
+)
+
+// ErrIdempotencyConflict is returned when an Idempotency-Key is reused for a
+// different request.
+var ErrIdempotencyConflict = errors.New("idempotency key was used for a different request")
+
+// maxIdempotencyKeyLength matches the idempotency_key column.
+const maxIdempotencyKeyLength = 255
+
+// IncreaseCashback credits the user and returns the resulting balance and
+// history entry.
+func (s *CashbackService) IncreaseCashback(ctx context.Context, req *models.CashbackRequest) (*models.CashbackMutation, error) {
+	return s.mutateCashback(ctx, constants.Increase, req)
+}
+
+// DecreaseCashback debits the user and returns the resulting balance and
+// history entry.
+func (s *CashbackService) DecreaseCashback(ctx context.Context, req *models.CashbackRequest) (*models.CashbackMutation, error) {
+	return s.mutateCashback(ctx, constants.Decrease, req)
+}
+
+func (s *CashbackService) mutateCashback(ctx context.Context, opType string, req *models.CashbackRequest) (*models.CashbackMutation, error) {
+	if err := s.validateCashbackRequest(req); err != nil {
+		return nil, err
+	}
+
+	if req.IdempotencyKey != "" {
+		if mutation, err := s.replayCashback(ctx, opType, req); mutation != nil || err != nil {
+			return mutation, err
+		}
 	}
 
-	source, err := s.sourceService.FindSourceOrCreate(req.TuronUserID, req.HostIP)
+	source, err := s.sourceService.FindSourceOrCreate(ctx, req.TuronUserID, req.HostIP)
 	if err != nil {
-		return fmt.Errorf("failed to determine source: %w", err)
+		return nil, fmt.Errorf("failed to determine source: %w", err)
 	}
 
-	return s.queue.Enqueue(constants.Increase, req, source.ID)
+	result, err := s.queue.Enqueue(ctx, opType, req, source.ID)
+	if errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+		// A concurrent request with the same key won the race.
+		return s.replayCashback(ctx, opType, req)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.History.SourceSlug = source.Slug
+	return &models.CashbackMutation{
+		Balance: result.Cashback,
+		History: result.History,
+	}, nil
 }
 
-func (s *CashbackService) DecreaseCashback(req *models.CashbackRequest) error {
+// validateCashbackRequest checks an increase or decrease, sync or async, and
+// rounds its amount to cents.
+func (s *CashbackService) validateCashbackRequest(req *models.CashbackRequest) error {
 	if err := s.validateTuronUserID(req.TuronUserID); err != nil {
 		return err
 	}
+	if math.Round(req.CashbackAmount*100) <= 0 {
+		return invalidArgument("cashback_amount must be positive")
+	}
+	req.CashbackAmount = math.Round(req.CashbackAmount*100) / 100
+	if len(req.IdempotencyKey) > maxIdempotencyKeyLength {
+		return invalidArgument("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength)
+	}
+	return nil
+}
 
-	source, err := s.sourceService.FindSourceOrCreate(req.TuronUserID, req.HostIP)
+// replayCashback answers a request whose Idempotency-Key was already
+// posted with the original history entry and the current balance. It
+// returns nil when the key is unused.
+func (s *CashbackService) replayCashback(ctx context.Context, opType string, req *models.CashbackRequest) (*models.CashbackMutation, error) {
+	history, err := s.repo.GetCashbackHistoryByIdempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil || history == nil {
+		return nil, err
+	}
+
+	operation := constants.OperationCredit
+	if opType == constants.Decrease {
+		operation = constants.OperationDebit
+	}
+	if history.TuronUserID != req.TuronUserID || history.Operation != operation || history.CashbackAmount != req.CashbackAmount {
+		return nil, ErrIdempotencyConflict
+	}
+
+	cashback, err := s.GetCashbackByUserID(ctx, req.TuronUserID)
 	if err != nil {
-		return fmt.Errorf("failed to determine source: %w", err)
+		return nil, err
 	}
 
-	return s.queue.Enqueue(constants.Decrease, req, source.ID)
+	slog.InfoContext(ctx, "cashback operation replayed",
+		"operation", opType,
+		"turon_user_id", req.TuronUserID,
+		"amount", req.CashbackAmount,
+		"history_id", history.ID,
+	)
+	return &models.CashbackMutation{
+		Balance:  cashback,
+		History:  history,
+		Replayed: true,
+	}, nil
 }
 
-func (s *CashbackService) GetCashbackByUserID(turonUserID int64) (*models.Cashback, error) {
+// GetCashbackByUserID returns the user's wallet. A user who never had one
+// gets a zero balance with status none, and a closed wallet reports status
+// closed; only a wallet deleted any other way, i.e. erased, is
+// ErrAccountErased.
+func (s *CashbackService) GetCashbackByUserID(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
 	if err := s.validateTuronUserID(turonUserID); err != nil {
 		return nil, err
 	}
-	return s.repo.GetCashbackByUserID(turonUserID)
+
+	cashback, err := s.repo.GetCashbackAccount(ctx, turonUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cashback == nil:
+		return &models.Cashback{
+			TuronUserID: turonUserID,
+			Status:      constants.AccountStatusNone,
+		}, nil
+	case cashback.DeletedAt == nil:
+		return cashback, nil
+	case cashback.Status == constants.AccountStatusClosed:
+		cashback.CashbackAmount = 0
+		return cashback, nil
+	default:
+		return nil, ErrAccountErased
+	}
 }
 
-func (s *CashbackService) GetCashbackHistoryByUserID(turonUserID int64, fromDate, toDate string, pagination *models.Pagination) ([]models.CashbackHistory, error) {
+// getAccountForHistory returns the user's wallet for a look back in time,
+// closed ones included, or nil when the user never had one. An erased wallet
+// is ErrAccountErased.
+func (s *CashbackService) getAccountForHistory(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
+	cashback, err := s.repo.GetCashbackAccount(ctx, turonUserID)
+	if err != nil {
+		return nil, err
+	}
+	if cashback != nil && cashback.DeletedAt != nil && cashback.Status != constants.AccountStatusClosed {
+		return nil, ErrAccountErased
+	}
+	return cashback, nil
+}
+
+func (s *CashbackService) GetCashbackHistoryByUserID(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, pagination *models.Pagination) ([]models.CashbackHistory, error) {
 	if err := s.validateTuronUserID(turonUserID); err != nil {
 		return nil, err
 	}
 
-	if err := s.validateDates(fromDate, toDate); err != nil {
+	if err := s.resolveDates(filter); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateHistoryFilter(filter); err != nil {
 		return nil, err
 	}
 
@@ -86,20 +270,177 @@ func (s *CashbackService) GetCashbackHistoryByUserID(turonUserID int64, fromDate
 	}
 
 	pagination.Calculate()
-	return s.repo.GetCashbackHistoryByUserID(turonUserID, fromDate, toDate, pagination)
+	return s.repo.GetCashbackHistoryByUserID(ctx, turonUserID, filter, pagination)
 }
 
-func (s *CashbackService) validateDates(fromDate, toDate string) error {
-	if fromDate != "" {
-		if _, err := time.Parse("2006-01-02", fromDate); err != nil {
-			return errors.New("invalid from_date format. Use YYYY-MM-DD")
+// StreamCashbackHistory validates the filter like GetCashbackHistoryByUserID
+// and calls fn for every matching history row, without paginating.
+func (s *CashbackService) StreamCashbackHistory(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, fn func(history *models.CashbackHistory) error) error {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return err
+	}
+
+	if err := s.resolveDates(filter); err != nil {
+		return err
+	}
+
+	if err := s.validateHistoryFilter(filter); err != nil {
+		return err
+	}
+
+	return s.repo.StreamCashbackHistory(ctx, turonUserID, filter, fn)
+}
+
+// MaxBalanceLookup caps the number of users in one batch balance lookup.
+const MaxBalanceLookup = 500
+
+// GetCashbacksByUserIDs looks up many balances at once. Duplicate IDs are
+// collapsed; users without a wallet are listed in Missing.
+func (s *CashbackService) GetCashbacksByUserIDs(ctx context.Context, turonUserIDs []int64) (*models.BalanceLookupResult, error) {
+	if len(turonUserIDs) == 0 {
+		return nil, invalidArgument("turon_user_ids must be provided")
+	}
+	if len(turonUserIDs) > MaxBalanceLookup {
+		return nil, invalidArgument("at most %d turon_user_ids are allowed", MaxBalanceLookup)
+	}
+
+	seen := make(map[int64]bool, len(turonUserIDs))
+	ids := make([]int64, 0, len(turonUserIDs))
+	for _, id := range turonUserIDs {
+		if id <= 0 {
+			return nil, invalidArgument("invalid turon_user_id %d", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	cashbacks, err := s.repo.GetCashbacksByUserIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[int64]bool, len(cashbacks))
+	for _, cashback := range cashbacks {
+		found[cashback.TuronUserID] = true
+	}
+	result := &models.BalanceLookupResult{
+		Balances: cashbacks,
+		Missing:  []int64{},
+	}
+	for _, id := range ids {
+		if !found[id] {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	return result, nil
+}
+
+// RebuildBalances recomputes every cached cashback balance from the ledger
+// and returns the number of balances that had drifted.
+func (s *CashbackService) RebuildBalances() (int64, error) {
+	return s.repo.RebuildCashbackBalances()
+}
+
+func (s *CashbackService) GetCashbackHistoryByCursor(ctx context.Context, turonUserID int64, filter *models.HistoryFilter, page *models.CursorPagination) ([]models.CashbackHistory, error) {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return nil, err
+	}
+
+	if err := s.resolveDates(filter); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateHistoryFilter(filter); err != nil {
+		return nil, err
+	}
+
+	if filter.SortBy != "" && filter.SortBy != "created_at" {
+		return nil, invalidArgument("cursor pagination only supports sort_by=created_at")
+	}
+
+	if page.PageSize < 1 {
+		page.PageSize = 10
+	}
+	if page.PageSize > 100 {
+		page.PageSize = 100
+	}
+
+	history, err := s.repo.GetCashbackHistoryByCursor(ctx, turonUserID, filter, page)
+	if errors.Is(err, models.ErrInvalidCursor) {
+		return nil, invalidArgument("%v", err)
+	}
+	return history, err
+}
+
+// resolveDates turns the raw from_date/to_date of the filter into the
+// [From, Until) instants used by the repository.
+func (s *CashbackService) resolveDates(filter *models.HistoryFilter) error {
+	loc, err := s.location(filter.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	filter.From, filter.Until = nil, nil
+	if filter.FromDate != "" {
+		from, _, err := parseTimeBound(filter.FromDate, loc)
+		if err != nil {
+			return invalidArgument("invalid from_date format. Use RFC 3339 or YYYY-MM-DD")
+		}
+		filter.From = &from
+	}
+	if filter.ToDate != "" {
+		to, dateOnly, err := parseTimeBound(filter.ToDate, loc)
+		if err != nil {
+			return invalidArgument("invalid to_date format. Use RFC 3339 or YYYY-MM-DD")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		} else {
+			to = to.Add(time.Microsecond)
 		}
+		filter.Until = &to
 	}
-	if toDate != "" {
-		if _, err := time.Parse("2006-01-02", toDate); err != nil {
-			return errors.New("invalid to_date format. Use YYYY-MM-DD")
+	if filter.From != nil && filter.Until != nil && !filter.From.Before(*filter.Until) {
+		return invalidArgument("from_date must not be after to_date")
+	}
+	return nil
+}
+
+var historyOperations = map[string]bool{
+	constants.OperationCredit:     true,
+	constants.OperationDebit:      true,
+	constants.OperationOpening:    true,
+	constants.OperationAdjustment: true,
+	constants.OperationExpiry:     true,
+	constants.OperationPayout:     true,
+	constants.OperationForfeiture: true,
+	constants.OperationLegacy:     true,
+}
+
+func (s *CashbackService) validateHistoryFilter(filter *models.HistoryFilter) error {
+	for _, operation := range filter.Operations {
+		if !historyOperations[operation] {
+			return invalidArgument("unknown operation %q", operation)
 		}
 	}
+	if filter.MinAmount != nil && *filter.MinAmount < 0 {
+		return invalidArgument("min_amount must not be negative")
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return invalidArgument("min_amount must not be greater than max_amount")
+	}
+	switch filter.SortBy {
+	case "", "created_at", "amount":
+	default:
+		return invalidArgument("sort_by must be created_at or amount")
+	}
+	switch filter.SortOrder {
+	case "", "asc", "desc":
+	default:
+		return invalidArgument("sort_order must be asc or desc")
+	}
 	return nil
 }
 
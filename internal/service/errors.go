@@ -0,0 +1,29 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidArgument matches every error caused by bad client input, so
+// handlers can answer 400 instead of 500.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// ErrAccountErased is returned for a user whose wallet was erased.
+var ErrAccountErased = errors.New("cashback account has been erased")
+
+type invalidArgumentError struct {
+	msg string
+}
+
+func (e *invalidArgumentError) Error() string {
+	return e.msg
+}
+
+func (e *invalidArgumentError) Is(target error) bool {
+	return target == ErrInvalidArgument
+}
+
+func invalidArgument(format string, args ...interface{}) error {
+	return &invalidArgumentError{msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"cashback-serv/internal/export"
+	"cashback-serv/models"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+var historyExportHeader = []interface{}{
+	"id",
+	"turon_user_id",
+	"created_at",
+	"operation",
+	"amount",
+	"cashback_amount",
+	"balance_after",
+	"source",
+	"reason",
+	"host_ip",
+}
+
+// HistoryExport is a validated history export, ready to be streamed.
+type HistoryExport struct {
+	repo        CashbackRepository
+	turonUserID int64
+	filter      *models.HistoryFilter
+	format      string
+	createdAt   time.Time
+}
+
+// NewHistoryExport validates an export of one user's history. Validation
+// happens here so callers can still report errors before the response starts
+// streaming.
+func (s *CashbackService) NewHistoryExport(turonUserID int64, filter *models.HistoryFilter, format string) (*HistoryExport, error) {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return nil, err
+	}
+	return s.newHistoryExport(turonUserID, filter, format)
+}
+
+// NewBulkHistoryExport validates an export of every user's history over a
+// date range. It is for the admin API only.
+func (s *CashbackService) NewBulkHistoryExport(filter *models.HistoryFilter, format string) (*HistoryExport, error) {
+	if filter.FromDate == "" || filter.ToDate == "" {
+		return nil, invalidArgument("from_date and to_date must be provided for a bulk export")
+	}
+	return s.newHistoryExport(0, filter, format)
+}
+
+// newHistoryExport validates the rest of an export. turonUserID zero
+// exports every user.
+func (s *CashbackService) newHistoryExport(turonUserID int64, filter *models.HistoryFilter, format string) (*HistoryExport, error) {
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if format != export.FormatCSV && format != export.FormatXLSX {
+		return nil, invalidArgument("format must be csv or xlsx")
+	}
+
+	if err := s.resolveDates(filter); err != nil {
+		return nil, err
+	}
+	if err := s.validateHistoryFilter(filter); err != nil {
+		return nil, err
+	}
+
+	return &HistoryExport{
+		repo:        s.repo,
+		turonUserID: turonUserID,
+		filter:      filter,
+		format:      format,
+		createdAt:   time.Now(),
+	}, nil
+}
+
+func (e *HistoryExport) ContentType() string {
+	return export.ContentType(e.format)
+}
+
+func (e *HistoryExport) Filename() string {
+	if e.turonUserID == 0 {
+		return fmt.Sprintf("cashback-history-%s.%s", e.createdAt.Format("20060102-150405"), e.format)
+	}
+	return fmt.Sprintf("cashback-history-%d-%s.%s", e.turonUserID, e.createdAt.Format("20060102-150405"), e.format)
+}
+
+// Stream writes the export row by row from the database into w.
+func (e *HistoryExport) Stream(ctx context.Context, w io.Writer) error {
+	writer, err := export.NewRowWriter(e.format, w)
+	if err != nil {
+		return err
+	}
+
+	if err := writer.WriteRow(historyExportHeader); err != nil {
+		return err
+	}
+
+	err = e.repo.StreamCashbackHistory(ctx, e.turonUserID, e.filter, func(h *models.CashbackHistory) error {
+		return writer.WriteRow([]interface{}{
+			h.ID,
+			h.TuronUserID,
+			h.CreatedAt,
+			h.Operation,
+			h.Amount,
+			h.CashbackAmount,
+			h.BalanceAfter,
+			h.SourceSlug,
+			h.Reason,
+			h.HostIP,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/internal/queue"
+	"cashback-serv/models"
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// healthCheckTimeout bounds each readiness check that talks to the
+	// database.
+	healthCheckTimeout = 2 * time.Second
+	// queueSaturation is the share of the queue capacity in use at which
+	// the service stops taking traffic.
+	queueSaturation = 0.9
+
+	// DefaultWorkerStallTimeout is how long the queue worker may spend on
+	// one operation before it is reported as stalled.
+	DefaultWorkerStallTimeout = 30 * time.Second
+)
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	GetMigrationVersion(ctx context.Context) (int64, error)
+}
+
+type QueueMonitor interface {
+	QueueStats() queue.Stats
+}
+
+// HealthService answers readiness probes. The service is ready when the
+// database is reachable and migrated, the cashback queue has room and its
+// worker is making progress, and shutdown has not begun.
+type HealthService struct {
+	repo               HealthRepository
+	queue              QueueMonitor
+	migrationVersion   int64
+	workerStallTimeout time.Duration
+	shuttingDown       atomic.Bool
+}
+
+// NewHealthService returns a HealthService that expects the database to be
+// at migrationVersion or later.
+func NewHealthService(repo HealthRepository, queue QueueMonitor, migrationVersion int64) *HealthService {
+	return &HealthService{
+		repo:               repo,
+		queue:              queue,
+		migrationVersion:   migrationVersion,
+		workerStallTimeout: DefaultWorkerStallTimeout,
+	}
+}
+
+func (s *HealthService) SetWorkerStallTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		s.workerStallTimeout = timeout
+	}
+}
+
+// SetShuttingDown makes every later readiness check fail, so load balancers
+// stop sending traffic while in-flight requests drain.
+func (s *HealthService) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
+
+// Ready runs every readiness check.
+func (s *HealthService) Ready(ctx context.Context) *models.Readiness {
+	readiness := &models.Readiness{
+		Status: constants.ReadinessReady,
+		Checks: map[string]models.HealthCheck{
+			"shutdown":   s.checkShutdown(),
+			"database":   s.checkDatabase(ctx),
+			"migrations": s.checkMigrations(ctx),
+			"queue":      s.checkQueue(),
+			"worker":     s.checkWorker(),
+		},
+	}
+	for _, check := range readiness.Checks {
+		if check.Status != constants.HealthStatusOK {
+			readiness.Status = constants.ReadinessNotReady
+			break
+		}
+	}
+	return readiness
+}
+
+func (s *HealthService) checkShutdown() models.HealthCheck {
+	if s.shuttingDown.Load() {
+		return failedCheck(nil, "server is shutting down")
+	}
+	return models.HealthCheck{Status: constants.HealthStatusOK}
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) models.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	if err := s.repo.Ping(ctx); err != nil {
+		return failedCheck(nil, "database is unreachable: %v", err)
+	}
+	return models.HealthCheck{Status: constants.HealthStatusOK}
+}
+
+func (s *HealthService) checkMigrations(ctx context.Context) models.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	version, err := s.repo.GetMigrationVersion(ctx)
+	if err != nil {
+		return failedCheck(nil, "%v", err)
+	}
+
+	details := map[string]interface{}{
+		"version":  version,
+		"expected": s.migrationVersion,
+	}
+	if version < s.migrationVersion {
+		return failedCheck(details, "database is at migration %d, expected %d", version, s.migrationVersion)
+	}
+	return models.HealthCheck{Status: constants.HealthStatusOK, Details: details}
+}
+
+func (s *HealthService) checkQueue() models.HealthCheck {
+	stats := s.queue.QueueStats()
+	details := map[string]interface{}{
+		"depth":    stats.Depth,
+		"capacity": stats.Capacity,
+	}
+	if float64(stats.Depth) >= float64(stats.Capacity)*queueSaturation {
+		return failedCheck(details, "queue is saturated")
+	}
+	return models.HealthCheck{Status: constants.HealthStatusOK, Details: details}
+}
+
+func (s *HealthService) checkWorker() models.HealthCheck {
+	stats := s.queue.QueueStats()
+	details := map[string]interface{}{
+		"busy_seconds": stats.Busy.Seconds(),
+	}
+	if stats.Busy > s.workerStallTimeout {
+		return failedCheck(details, "queue worker has been on one operation for %s", stats.Busy.Round(time.Second))
+	}
+	return models.HealthCheck{Status: constants.HealthStatusOK, Details: details}
+}
+
+func failedCheck(details map[string]interface{}, format string, args ...interface{}) models.HealthCheck {
+	return models.HealthCheck{
+		Status:  constants.HealthStatusFailed,
+		Error:   fmt.Sprintf(format, args...),
+		Details: details,
+	}
+}
+
+// QueueStats returns the load of the operation queue.
+func (s *CashbackService) QueueStats() queue.Stats {
+	return s.queue.Stats()
+}
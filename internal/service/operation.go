@@ -0,0 +1,215 @@
+package service
+
+import (
+	constants "cashback-serv/const"
+	core "cashback-serv/internal/interfaces"
+	"cashback-serv/internal/queue"
+	"cashback-serv/internal/repository"
+	"cashback-serv/models"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// staleOperationAge is how long an operation may stay pending before it is
+// considered lost with the process that accepted it. Failing it also stops a
+// late posting: the queue only commits while the operation is pending.
+const staleOperationAge = time.Hour
+
+// MaxPendingOperations is how many async operations may wait for the queue
+// at once. It leaves room in the queue for synchronous requests.
+const MaxPendingOperations = 500
+
+var (
+	ErrOperationNotFound = errors.New("operation not found")
+	ErrTooManyOperations = errors.New("too many operations are pending, retry later")
+)
+
+// SubmitCashback accepts an increase or decrease and processes it in the
+// background. The returned operation is pending; poll GetOperation for the
+// outcome. A request repeating an Idempotency-Key gets the operation of the
+// first one, or a succeeded operation when the key was posted
+// synchronously. When MaxPendingOperations are waiting, it fails with
+// ErrTooManyOperations.
+func (s *CashbackService) SubmitCashback(ctx context.Context, opType string, req *models.CashbackRequest) (*models.Operation, error) {
+	if opType != constants.Increase && opType != constants.Decrease {
+		return nil, fmt.Errorf("unsupported async operation %q", opType)
+	}
+	if err := s.validateCashbackRequest(req); err != nil {
+		return nil, err
+	}
+
+	if req.IdempotencyKey != "" {
+		if operation, err := s.replayOperation(ctx, opType, req); operation != nil || err != nil {
+			return operation, err
+		}
+	}
+
+	source, err := s.sourceService.FindSourceOrCreate(ctx, req.TuronUserID, req.HostIP)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine source: %w", err)
+	}
+
+	select {
+	case s.asyncSlots <- struct{}{}:
+	default:
+		return nil, ErrTooManyOperations
+	}
+
+	operation := &models.Operation{
+		ID:             newOperationID(),
+		Type:           opType,
+		TuronUserID:    req.TuronUserID,
+		CashbackAmount: req.CashbackAmount,
+		Status:         constants.OperationStatusPending,
+		IdempotencyKey: req.IdempotencyKey,
+	}
+	if err := s.repo.CreateOperation(ctx, operation); err != nil {
+		<-s.asyncSlots
+		if errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+			// A concurrent submit with the same key won the race.
+			return s.replayOperation(ctx, opType, req)
+		}
+		return nil, err
+	}
+
+	queueReq := &queue.QueueRequest{
+		CashbackRequest: req,
+		SourceID:        source.ID,
+		OperationID:     operation.ID,
+	}
+	slog.InfoContext(ctx, "cashback operation accepted",
+		"operation", opType,
+		"operation_id", operation.ID,
+		"turon_user_id", req.TuronUserID,
+		"source_id", source.ID,
+		"amount", req.CashbackAmount,
+	)
+
+	// The operation outlives the request that submitted it.
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() { <-s.asyncSlots }()
+
+		_, err := s.queue.EnqueueRequest(ctx, opType, queueReq)
+		if errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+			// A synchronous request with the same key posted first.
+			err = s.completeReplayedOperation(ctx, operation, opType, req)
+		}
+		if err != nil {
+			if markErr := s.repo.MarkOperationFailed(ctx, operation.ID, err.Error()); markErr != nil && !errors.Is(markErr, repository.ErrOperationFinished) {
+				slog.ErrorContext(ctx, "failed to record failure of operation", "operation_id", operation.ID, "error", markErr)
+			}
+		}
+	}()
+
+	return operation, nil
+}
+
+// replayOperation answers a submit whose Idempotency-Key was used before:
+// with the operation submitted with it, or with a new succeeded operation
+// when the key was posted by a synchronous request. It returns nil when the
+// key is unused.
+func (s *CashbackService) replayOperation(ctx context.Context, opType string, req *models.CashbackRequest) (*models.Operation, error) {
+	operation, err := s.repo.GetOperationByIdempotencyKey(ctx, req.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+	if operation != nil {
+		if operation.Type != opType || operation.TuronUserID != req.TuronUserID || operation.CashbackAmount != req.CashbackAmount {
+			return nil, ErrIdempotencyConflict
+		}
+		return operation, nil
+	}
+
+	mutation, err := s.replayCashback(ctx, opType, req)
+	if mutation == nil || err != nil {
+		return nil, err
+	}
+
+	operation = &models.Operation{
+		ID:             newOperationID(),
+		Type:           opType,
+		TuronUserID:    req.TuronUserID,
+		CashbackAmount: req.CashbackAmount,
+		Status:         constants.OperationStatusSucceeded,
+		HistoryID:      &mutation.History.ID,
+		IdempotencyKey: req.IdempotencyKey,
+	}
+	err = s.repo.CreateOperation(ctx, operation)
+	if errors.Is(err, repository.ErrDuplicateIdempotencyKey) {
+		return s.repo.GetOperationByIdempotencyKey(ctx, req.IdempotencyKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// completeReplayedOperation marks operation succeeded with the history row
+// already posted under its Idempotency-Key.
+func (s *CashbackService) completeReplayedOperation(ctx context.Context, operation *models.Operation, opType string, req *models.CashbackRequest) error {
+	mutation, err := s.replayCashback(ctx, opType, req)
+	if err != nil {
+		return err
+	}
+	if mutation == nil {
+		return repository.ErrDuplicateIdempotencyKey
+	}
+	return s.repo.WithTxContext(ctx, func(tx core.CashbackTx) error {
+		return tx.MarkOperationSucceeded(operation.ID, mutation.History.ID)
+	})
+}
+
+func (s *CashbackService) GetOperation(ctx context.Context, id string) (*models.Operation, error) {
+	operation, err := s.repo.GetOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if operation == nil {
+		return nil, ErrOperationNotFound
+	}
+	return operation, nil
+}
+
+// CleanupOperations fails operations lost while pending and deletes finished
+// ones older than retention.
+func (s *CashbackService) CleanupOperations(retention time.Duration) error {
+	ctx := context.Background()
+	now := time.Now()
+	if _, err := s.repo.FailStaleOperations(ctx, now.Add(-staleOperationAge), "operation was interrupted before it was processed"); err != nil {
+		return err
+	}
+	_, err := s.repo.DeleteOperationsBefore(ctx, now.Add(-retention))
+	return err
+}
+
+// StartOperationCleanup runs CleanupOperations in the background.
+func (s *CashbackService) StartOperationCleanup(retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(staleOperationAge / 4)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := s.CleanupOperations(retention); err != nil {
+				slog.Error("operation cleanup failed", "error", err)
+			}
+		}
+	}()
+}
+
+// newOperationID returns a random (version 4) UUID.
+func newOperationID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
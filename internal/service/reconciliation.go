@@ -0,0 +1,117 @@
+package service
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Reconcile compares every stored balance in the turon_user_id range with the
+// signed sum of the user's history postings. With repair set, each mismatch
+// is resolved by an adjustment history entry that makes the history agree
+// with the stored balance.
+func (s *CashbackService) Reconcile(fromTuronUserID, toTuronUserID int64, repair bool, hostIP string) (*models.ReconciliationReport, error) {
+	if fromTuronUserID < 0 || toTuronUserID < 0 {
+		return nil, invalidArgument("turon_user_id range bounds must not be negative")
+	}
+	if toTuronUserID > 0 && fromTuronUserID > toTuronUserID {
+		return nil, invalidArgument("from_turon_user_id must not be greater than to_turon_user_id")
+	}
+
+	report := &models.ReconciliationReport{
+		FromTuronUserID: fromTuronUserID,
+		ToTuronUserID:   toTuronUserID,
+		Repair:          repair,
+		StartedAt:       time.Now(),
+	}
+
+	scanned, err := s.repo.CountCashbackAccounts(fromTuronUserID, toTuronUserID)
+	if err != nil {
+		return nil, err
+	}
+	report.Scanned = scanned
+
+	discrepancies, err := s.repo.FindBalanceDiscrepancies(fromTuronUserID, toTuronUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if repair {
+		for i := range discrepancies {
+			s.repairDiscrepancy(&discrepancies[i], hostIP)
+		}
+	}
+
+	report.Discrepancies = discrepancies
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+func (s *CashbackService) repairDiscrepancy(d *models.BalanceDiscrepancy, hostIP string) {
+	req := &models.CashbackRequest{
+		TuronUserID: d.TuronUserID,
+		HostIP:      hostIP,
+		Reason:      constants.ReasonReconciliation,
+	}
+
+	result, err := s.queue.Enqueue(context.Background(), constants.Reconcile, req, 0)
+	if err != nil {
+		d.RepairError = err.Error()
+		return
+	}
+
+	d.Repaired = true
+	if result.History != nil {
+		d.AdjustmentHistoryID = &result.History.ID
+	}
+}
+
+var reconciliationCSVHeader = []string{
+	"cashback_id",
+	"turon_user_id",
+	"stored_balance",
+	"recomputed_balance",
+	"difference",
+	"repaired",
+	"adjustment_history_id",
+	"repair_error",
+}
+
+// WriteReconciliationCSV writes one line per discrepancy of the report.
+func WriteReconciliationCSV(w io.Writer, report *models.ReconciliationReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(reconciliationCSVHeader); err != nil {
+		return err
+	}
+
+	for _, d := range report.Discrepancies {
+		adjustmentHistoryID := ""
+		if d.AdjustmentHistoryID != nil {
+			adjustmentHistoryID = strconv.FormatInt(*d.AdjustmentHistoryID, 10)
+		}
+
+		if err := writer.Write([]string{
+			strconv.FormatInt(d.CashbackID, 10),
+			strconv.FormatInt(d.TuronUserID, 10),
+			formatAmount(d.StoredBalance),
+			formatAmount(d.RecomputedBalance),
+			formatAmount(d.Difference),
+			strconv.FormatBool(d.Repaired),
+			adjustmentHistoryID,
+			d.RepairError,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
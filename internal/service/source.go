@@ -3,13 +3,14 @@ package service
 import (
 	constants "cashback-serv/const"
 	"cashback-serv/models"
+	"context"
 	"errors"
 	"fmt"
 )
 
 type SourceRepository interface {
-	CreateSource(source *models.Source) error
-	GetSourceBySlug(slug string) (*models.Source, error)
+	CreateSource(ctx context.Context, source *models.Source) error
+	GetSourceBySlug(ctx context.Context, slug string) (*models.Source, error)
 }
 
 type SourceService struct {
@@ -20,7 +21,7 @@ func NewSourceService(repo SourceRepository) *SourceService {
 	return &SourceService{repo: repo}
 }
 
-func (s *SourceService) FindSourceOrCreate(turonUserID int64, hostIP string) (*models.Source, error) {
+func (s *SourceService) FindSourceOrCreate(ctx context.Context, turonUserID int64, hostIP string) (*models.Source, error) {
 	var slug string
 	if turonUserID != 0 {
 		slug = constants.SourceTuron
@@ -28,7 +29,7 @@ func (s *SourceService) FindSourceOrCreate(turonUserID int64, hostIP string) (*m
 		return nil, errors.New("cannot determine source slug: turon_user_id must be provided")
 	}
 
-	source, err := s.repo.GetSourceBySlug(slug)
+	source, err := s.repo.GetSourceBySlug(ctx, slug)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get source by slug: %w", err)
 	}
@@ -41,7 +42,7 @@ func (s *SourceService) FindSourceOrCreate(turonUserID int64, hostIP string) (*m
 		Slug:   slug,
 	}
 
-	err = s.repo.CreateSource(newSource)
+	err = s.repo.CreateSource(ctx, newSource)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create source: %w", err)
 	}
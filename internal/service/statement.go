@@ -0,0 +1,199 @@
+package service
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"context"
+	"embed"
+	"html/template"
+	"io"
+	"log/slog"
+	"math"
+	"time"
+)
+
+//go:embed templates/statement.html
+var templateFS embed.FS
+
+var statementTemplate = template.Must(template.New("statement.html").Funcs(template.FuncMap{
+	"amount": formatAmount,
+	"signed": func(value *float64) string {
+		if value == nil {
+			return ""
+		}
+		return formatAmount(*value)
+	},
+}).ParseFS(templateFS, "templates/statement.html"))
+
+// statementPeriod resolves a YYYY-MM period to the [start, end) instants of
+// that month in tz (or the default zone).
+func (s *CashbackService) statementPeriod(period, tz string) (start, end time.Time, err error) {
+	loc, err := s.location(tz)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	start, err = time.ParseInLocation("2006-01", period, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, invalidArgument("invalid period format. Use YYYY-MM")
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// PreviousStatementPeriod returns the last closed month in tz (or the
+// default zone), the one a month-end run generates statements for.
+func (s *CashbackService) PreviousStatementPeriod(tz string) (string, error) {
+	loc, err := s.location(tz)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc).AddDate(0, -1, 0).Format("2006-01"), nil
+}
+
+// GetStatement returns the user's statement for period. Pre-generated
+// statements are served as stored; others, including the month in progress,
+// are built on the fly.
+func (s *CashbackService) GetStatement(ctx context.Context, turonUserID int64, period, tz string) (*models.Statement, error) {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return nil, err
+	}
+
+	start, end, err := s.statementPeriod(period, tz)
+	if err != nil {
+		return nil, err
+	}
+	if start.After(time.Now()) {
+		return nil, invalidArgument("period %s has not started yet", period)
+	}
+
+	cashback, err := s.getAccountForHistory(ctx, turonUserID)
+	if err != nil {
+		return nil, err
+	}
+	if cashback == nil {
+		return emptyStatement(turonUserID, period, start, end), nil
+	}
+
+	statement, err := s.repo.GetStatement(cashback.ID, period, start.Location().String())
+	if err != nil || statement != nil {
+		return statement, err
+	}
+
+	return s.buildStatement(ctx, cashback, period, start, end)
+}
+
+// GenerateStatements pre-generates the statement of every active wallet for
+// a closed period. It returns how many statements were newly stored; those
+// generated by an earlier run are left as they are.
+func (s *CashbackService) GenerateStatements(period, tz string) (int, error) {
+	start, end, err := s.statementPeriod(period, tz)
+	if err != nil {
+		return 0, err
+	}
+	// Postings near the month boundary may still be in flight.
+	if time.Now().Before(end.Add(snapshotLag)) {
+		return 0, invalidArgument("period %s has not closed yet", period)
+	}
+
+	accounts, err := s.repo.ListStatementAccounts(start, end)
+	if err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for i := range accounts {
+		statement, err := s.buildStatement(context.Background(), &accounts[i], period, start, end)
+		if err != nil {
+			return generated, err
+		}
+		saved, err := s.repo.SaveStatement(statement)
+		if err != nil {
+			return generated, err
+		}
+		if saved {
+			generated++
+		}
+	}
+
+	slog.Info("statements generated", "period", period, "accounts", len(accounts), "generated", generated)
+	return generated, nil
+}
+
+// emptyStatement is the statement of a user without a wallet.
+func emptyStatement(turonUserID int64, period string, start, end time.Time) *models.Statement {
+	return &models.Statement{
+		TuronUserID: turonUserID,
+		Period:      period,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		TimeZone:    start.Location().String(),
+		Lines:       []models.StatementLine{},
+		GeneratedAt: time.Now().In(start.Location()),
+	}
+}
+
+func (s *CashbackService) buildStatement(ctx context.Context, cashback *models.Cashback, period string, start, end time.Time) (*models.Statement, error) {
+	statement := emptyStatement(cashback.TuronUserID, period, start, end)
+	statement.CashbackID = cashback.ID
+
+	// Balances are inclusive of their instant; the period is not of its end.
+	var err error
+	statement.OpeningBalance, err = s.repo.GetBalanceAsOf(cashback.ID, start.Add(-time.Microsecond))
+	if err != nil {
+		return nil, err
+	}
+	statement.ClosingBalance, err = s.repo.GetBalanceAsOf(cashback.ID, end.Add(-time.Microsecond))
+	if err != nil {
+		return nil, err
+	}
+
+	filter := &models.HistoryFilter{From: &start, Until: &end, SortOrder: "asc"}
+	err = s.repo.StreamCashbackHistory(ctx, cashback.TuronUserID, filter, func(h *models.CashbackHistory) error {
+		// Legacy rows predate the ledger; the opening entries already carry
+		// their effect.
+		if h.Operation == constants.OperationLegacy || h.Amount == nil {
+			return nil
+		}
+
+		switch h.Operation {
+		case constants.OperationCredit:
+			statement.TotalCredited += *h.Amount
+		case constants.OperationDebit:
+			statement.TotalDebited -= *h.Amount
+		case constants.OperationExpiry:
+			statement.TotalExpired -= *h.Amount
+		default:
+			statement.TotalAdjusted += *h.Amount
+		}
+
+		statement.Lines = append(statement.Lines, models.StatementLine{
+			HistoryID:    h.ID,
+			Date:         h.CreatedAt.In(start.Location()),
+			Operation:    h.Operation,
+			Source:       h.SourceSlug,
+			Reason:       h.Reason,
+			Amount:       h.Amount,
+			BalanceAfter: h.BalanceAfter,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statement.TotalCredited = roundCents(statement.TotalCredited)
+	statement.TotalDebited = roundCents(statement.TotalDebited)
+	statement.TotalExpired = roundCents(statement.TotalExpired)
+	statement.TotalAdjusted = roundCents(statement.TotalAdjusted)
+	return statement, nil
+}
+
+// RenderStatementHTML writes the statement as a printable HTML document.
+func RenderStatementHTML(w io.Writer, statement *models.Statement) error {
+	return statementTemplate.Execute(w, statement)
+}
+
+func roundCents(value float64) float64 {
+	return math.Round(value*100) / 100
+}
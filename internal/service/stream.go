@@ -0,0 +1,115 @@
+package service
+
+import (
+	"cashback-serv/models"
+	"errors"
+	"sync"
+)
+
+// StreamBatchSize caps the history rows GetCashbackUpdates returns; a full
+// batch means more may follow.
+const StreamBatchSize = 500
+
+var ErrStreamUnavailable = errors.New("balance streaming is not enabled")
+
+// CashbackStream fans cashback update notifications out to the balance
+// streams open on this replica. A notification only wakes the stream; the
+// stream then reads what it has not sent yet from the database, so a missed
+// or coalesced notification loses nothing.
+type CashbackStream struct {
+	mu          sync.Mutex
+	subscribers map[int64]map[chan struct{}]struct{}
+}
+
+func NewCashbackStream() *CashbackStream {
+	return &CashbackStream{subscribers: make(map[int64]map[chan struct{}]struct{})}
+}
+
+// Subscribe returns a channel that receives after every update of the user,
+// and a function that ends the subscription.
+func (s *CashbackStream) Subscribe(turonUserID int64) (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	if s.subscribers[turonUserID] == nil {
+		s.subscribers[turonUserID] = make(map[chan struct{}]struct{})
+	}
+	s.subscribers[turonUserID][wake] = struct{}{}
+	s.mu.Unlock()
+
+	return wake, func() {
+		s.mu.Lock()
+		delete(s.subscribers[turonUserID], wake)
+		if len(s.subscribers[turonUserID]) == 0 {
+			delete(s.subscribers, turonUserID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Notify wakes the streams of the user.
+func (s *CashbackStream) Notify(turonUserID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for wake := range s.subscribers[turonUserID] {
+		signal(wake)
+	}
+}
+
+// NotifyAll wakes every stream, e.g. after notifications may have been lost.
+func (s *CashbackStream) NotifyAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subscribers := range s.subscribers {
+		for wake := range subscribers {
+			signal(wake)
+		}
+	}
+}
+
+func signal(wake chan struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// SetStream enables balance streams fed by stream.
+func (s *CashbackService) SetStream(stream *CashbackStream) {
+	s.stream = stream
+}
+
+// SubscribeCashback validates the user and subscribes to their updates.
+// lastEventID is the ID of the last history row the client has seen; zero
+// starts from the current state. The returned ID is where to continue from.
+func (s *CashbackService) SubscribeCashback(turonUserID, lastEventID int64) (<-chan struct{}, func(), int64, error) {
+	if err := s.validateTuronUserID(turonUserID); err != nil {
+		return nil, nil, 0, err
+	}
+	if lastEventID < 0 {
+		return nil, nil, 0, invalidArgument("Last-Event-ID must not be negative")
+	}
+	if s.stream == nil {
+		return nil, nil, 0, ErrStreamUnavailable
+	}
+
+	// Subscribe before reading the starting point, so an update committed in
+	// between still wakes the stream.
+	wake, cancel := s.stream.Subscribe(turonUserID)
+	if lastEventID == 0 {
+		var err error
+		if lastEventID, err = s.repo.GetLastCashbackHistoryID(turonUserID); err != nil {
+			cancel()
+			return nil, nil, 0, err
+		}
+	}
+	return wake, cancel, lastEventID, nil
+}
+
+// GetCashbackUpdates returns the user's history rows after afterID, oldest
+// first.
+func (s *CashbackService) GetCashbackUpdates(turonUserID, afterID int64) ([]models.CashbackHistory, error) {
+	return s.repo.GetCashbackHistoryAfter(turonUserID, afterID, StreamBatchSize)
+}
@@ -0,0 +1,52 @@
+package service
+
+import "time"
+
+// SetTimeZone sets the zone used for date-only bounds when the client does
+// not pass tz.
+func (s *CashbackService) SetTimeZone(loc *time.Location) {
+	if loc != nil {
+		s.timeZone = loc
+	}
+}
+
+func (s *CashbackService) location(name string) (*time.Location, error) {
+	if name == "" {
+		return s.timeZone, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, invalidArgument("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// parseTimeBound accepts an RFC 3339 timestamp, or a YYYY-MM-DD date that
+// resolves to midnight in loc. dateOnly reports which form was given.
+func parseTimeBound(value string, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	if t, err = time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, false, nil
+	}
+	if t, err = time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+	return time.Time{}, false, err
+}
+
+// ParseAsOf accepts an RFC 3339 timestamp, or a YYYY-MM-DD date meaning the
+// end of that day in tz (or the default zone).
+func (s *CashbackService) ParseAsOf(value, tz string) (time.Time, error) {
+	loc, err := s.location(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	asOf, dateOnly, err := parseTimeBound(value, loc)
+	if err != nil {
+		return time.Time{}, invalidArgument("invalid as_of format. Use RFC 3339 or YYYY-MM-DD")
+	}
+	if dateOnly {
+		asOf = asOf.AddDate(0, 0, 1).Add(-time.Microsecond)
+	}
+	return asOf, nil
+}
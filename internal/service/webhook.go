@@ -0,0 +1,334 @@
+package service
+
+import (
+	"bytes"
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// webhookBatchSize is how many due deliveries one dispatch pass claims.
+	webhookBatchSize = 50
+	// webhookTimeout bounds a single delivery attempt; the claim lease is a
+	// little longer so a slow attempt is not picked up twice.
+	webhookTimeout = 10 * time.Second
+	webhookLease   = 2 * webhookTimeout
+
+	webhookBaseBackoff = 30 * time.Second
+	webhookMaxBackoff  = 6 * time.Hour
+
+	// Defaults used when the dispatcher is not configured.
+	DefaultWebhookPollInterval = 5 * time.Second
+	DefaultWebhookMaxAttempts  = 10
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+var webhookEvents = map[string]bool{
+	constants.EventCashbackCredited:      true,
+	constants.EventCashbackDebited:       true,
+	constants.EventCashbackDebitRejected: true,
+	constants.EventSourceCreated:         true,
+	constants.EventAccountClosed:         true,
+}
+
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription, sourceID int64) error
+	ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id int64) (bool, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]models.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	MarkAttemptFailed(ctx context.Context, id int64, statusCode int, message string, nextAttemptAt time.Time, dead bool) error
+	ListDeadDeliveries(ctx context.Context, pagination *models.Pagination) ([]models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, id int64) (bool, error)
+	DeleteDeliveredBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// WebhookService manages webhook subscriptions and delivers the events the
+// cashback and source services write to the outbox.
+type WebhookService struct {
+	repo       WebhookRepository
+	sourceRepo SourceRepository
+	client     *http.Client
+
+	maxAttempts int
+}
+
+func NewWebhookService(repo WebhookRepository, sourceRepo SourceRepository) *WebhookService {
+	return &WebhookService{
+		repo:        repo,
+		sourceRepo:  sourceRepo,
+		client:      &http.Client{Timeout: webhookTimeout},
+		maxAttempts: DefaultWebhookMaxAttempts,
+	}
+}
+
+// SetMaxAttempts sets how many failed attempts move a delivery to the
+// dead-letter list.
+func (s *WebhookService) SetMaxAttempts(maxAttempts int) {
+	if maxAttempts > 0 {
+		s.maxAttempts = maxAttempts
+	}
+}
+
+// CreateSubscription registers a URL and returns the subscription with its
+// signing secret, which is not shown again.
+func (s *WebhookService) CreateSubscription(ctx context.Context, req *models.WebhookSubscriptionRequest) (*models.WebhookSubscription, error) {
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, invalidArgument("url must be an absolute http or https URL")
+	}
+	if len(req.Events) == 0 {
+		return nil, invalidArgument("events must be provided")
+	}
+	for _, event := range req.Events {
+		if !webhookEvents[event] {
+			return nil, invalidArgument("unknown event %q", event)
+		}
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:    req.URL,
+		Secret: newWebhookSecret(),
+		Events: req.Events,
+	}
+
+	var sourceID int64
+	if req.Source != "" {
+		source, err := s.sourceRepo.GetSourceBySlug(ctx, req.Source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get source by slug: %w", err)
+		}
+		if source == nil {
+			return nil, invalidArgument("unknown source %q", req.Source)
+		}
+		sourceID = source.ID
+		subscription.Source = &source.Slug
+	}
+
+	if err := s.repo.CreateSubscription(ctx, subscription, sourceID); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(ctx)
+}
+
+// DeleteSubscription removes a subscription. Its pending deliveries are
+// moved to the dead-letter list.
+func (s *WebhookService) DeleteSubscription(ctx context.Context, id int64) error {
+	deleted, err := s.repo.DeleteSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+func (s *WebhookService) ListDeadLetters(ctx context.Context, pagination *models.Pagination) ([]models.WebhookDelivery, error) {
+	if pagination.Page < 1 {
+		pagination.Page = 1
+	}
+	if pagination.PageSize < 1 {
+		pagination.PageSize = 10
+	}
+	if pagination.PageSize > 100 {
+		pagination.PageSize = 100
+	}
+	pagination.Calculate()
+
+	return s.repo.ListDeadDeliveries(ctx, pagination)
+}
+
+// Redeliver queues a delivery again with a fresh attempt budget.
+func (s *WebhookService) Redeliver(ctx context.Context, id int64) error {
+	queued, err := s.repo.Redeliver(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !queued {
+		return ErrDeliveryNotFound
+	}
+	return nil
+}
+
+// Dispatch delivers the deliveries that are due and returns how many it
+// attempted.
+func (s *WebhookService) Dispatch() (int, error) {
+	deliveries, err := s.repo.ClaimDueDeliveries(context.Background(), webhookBatchSize, time.Now().Add(webhookLease))
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			s.deliver(delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// StartDispatcher runs Dispatch in the background every interval, and again
+// right away while full batches keep coming.
+func (s *WebhookService) StartDispatcher(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			for {
+				attempted, err := s.Dispatch()
+				if err != nil {
+					slog.Error("webhook dispatch failed", "error", err)
+					break
+				}
+				if attempted < webhookBatchSize {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// webhookCleanupInterval is how often StartCleanup prunes the outbox.
+const webhookCleanupInterval = time.Hour
+
+// StartCleanup deletes, every webhookCleanupInterval, the events whose
+// deliveries all succeeded more than retention ago. Dead deliveries are
+// kept for redelivery.
+func (s *WebhookService) StartCleanup(retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(webhookCleanupInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := s.repo.DeleteDeliveredBefore(context.Background(), time.Now().Add(-retention)); err != nil {
+				slog.Error("webhook cleanup failed", "error", err)
+			}
+		}
+	}()
+}
+
+func (s *WebhookService) deliver(delivery *models.WebhookDelivery) {
+	statusCode, err := s.send(delivery)
+	if err == nil {
+		if err := s.repo.MarkDelivered(context.Background(), delivery.ID, statusCode); err != nil {
+			slog.Error("failed to record webhook delivery", "delivery_id", delivery.ID, "error", err)
+		}
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	dead := attempts >= s.maxAttempts
+	next := time.Now().Add(webhookBackoff(attempts))
+	if err := s.repo.MarkAttemptFailed(context.Background(), delivery.ID, statusCode, err.Error(), next, dead); err != nil {
+		slog.Error("failed to record webhook delivery", "delivery_id", delivery.ID, "error", err)
+	}
+}
+
+// send posts the event to the subscriber. Any 2xx answer counts as
+// delivered; the status code is returned whenever there was a response.
+func (s *WebhookService) send(delivery *models.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(struct {
+		ID        int64           `json:"id"`
+		Type      string          `json:"type"`
+		CreatedAt time.Time       `json:"created_at"`
+		Data      json.RawMessage `json:"data"`
+	}{
+		ID:        delivery.EventID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cashback-serv-webhooks")
+	req.Header.Set("X-Cashback-Event", delivery.EventType)
+	req.Header.Set("X-Cashback-Delivery", strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set("X-Cashback-Timestamp", timestamp)
+	req.Header.Set("X-Cashback-Signature", "sha256="+SignWebhook(delivery.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "timestamp.body" under secret,
+// as sent in X-Cashback-Signature. Subscribers recompute it to verify a
+// delivery, and should reject stale timestamps.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff doubles the wait after every failed attempt, up to
+// webhookMaxBackoff.
+func webhookBackoff(attempts int) time.Duration {
+	backoff := float64(webhookBaseBackoff) * math.Pow(2, float64(attempts-1))
+	if backoff > float64(webhookMaxBackoff) {
+		return webhookMaxBackoff
+	}
+	return time.Duration(backoff)
+}
+
+func newWebhookSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
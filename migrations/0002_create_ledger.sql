@@ -0,0 +1,105 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE ledger_accounts (
+    id BIGSERIAL PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL,
+    cashback_id BIGINT,
+    source_id BIGINT,
+    code VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cashback_id) REFERENCES cashback(id),
+    FOREIGN KEY (source_id) REFERENCES sources(id),
+    CHECK (
+        (kind = 'user' AND cashback_id IS NOT NULL AND source_id IS NULL AND code IS NULL) OR
+        (kind = 'source' AND source_id IS NOT NULL AND cashback_id IS NULL AND code IS NULL) OR
+        (kind = 'system' AND code IS NOT NULL AND cashback_id IS NULL AND source_id IS NULL)
+    )
+);
+
+CREATE TABLE ledger_entries (
+    id BIGSERIAL PRIMARY KEY,
+    history_id BIGINT NOT NULL,
+    account_id BIGINT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (history_id) REFERENCES cashback_history(id),
+    FOREIGN KEY (account_id) REFERENCES ledger_accounts(id)
+);
+
+CREATE UNIQUE INDEX ux_ledger_accounts_cashback_id ON ledger_accounts(cashback_id) WHERE cashback_id IS NOT NULL;
+CREATE UNIQUE INDEX ux_ledger_accounts_source_id ON ledger_accounts(source_id) WHERE source_id IS NOT NULL;
+CREATE UNIQUE INDEX ux_ledger_accounts_code ON ledger_accounts(code) WHERE code IS NOT NULL;
+CREATE INDEX idx_ledger_entries_account_id ON ledger_entries(account_id, created_at);
+CREATE INDEX idx_ledger_entries_history_id ON ledger_entries(history_id);
+
+-- Every history row is one ledger transaction; its postings must sum to zero.
+CREATE FUNCTION ledger_entries_check_balanced() RETURNS TRIGGER AS $$
+DECLARE
+    total DECIMAL(12, 2);
+BEGIN
+    SELECT COALESCE(SUM(amount), 0) INTO total
+    FROM ledger_entries
+    WHERE history_id = NEW.history_id;
+
+    IF total <> 0 THEN
+        RAISE EXCEPTION 'ledger transaction for history % is unbalanced by %', NEW.history_id, total;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER trg_ledger_entries_balanced
+    AFTER INSERT OR UPDATE ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION ledger_entries_check_balanced();
+
+INSERT INTO ledger_accounts (kind, code) VALUES
+    ('system', 'opening'),
+    ('system', 'expiry'),
+    ('system', 'reversal'),
+    ('system', 'adjustment');
+
+INSERT INTO ledger_accounts (kind, cashback_id) SELECT 'user', id FROM cashback;
+INSERT INTO ledger_accounts (kind, source_id) SELECT 'source', id FROM sources;
+
+-- Existing balances have no reliable trail, so they enter the ledger as an
+-- opening transaction against the system opening account.
+CREATE TEMPORARY TABLE opening_history ON COMMIT DROP AS
+SELECT id AS cashback_id, cashback_amount
+FROM cashback
+WHERE cashback_amount <> 0 AND deleted_at IS NULL;
+
+ALTER TABLE opening_history ADD COLUMN history_id BIGINT;
+
+UPDATE opening_history o
+SET history_id = nextval(pg_get_serial_sequence('cashback_history', 'id'));
+
+INSERT INTO cashback_history (id, cashback_id, cashback_amount, host_ip, type)
+SELECT history_id, cashback_id, cashback_amount, '', 'opening'
+FROM opening_history;
+
+INSERT INTO ledger_entries (history_id, account_id, amount)
+SELECT o.history_id, a.id, o.cashback_amount
+FROM opening_history o
+JOIN ledger_accounts a ON a.cashback_id = o.cashback_id
+UNION ALL
+SELECT o.history_id, a.id, -o.cashback_amount
+FROM opening_history o
+JOIN ledger_accounts a ON a.kind = 'system' AND a.code = 'opening';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE TEMPORARY TABLE opening_history ON COMMIT DROP AS
+SELECT e.history_id
+FROM ledger_entries e
+JOIN ledger_accounts a ON a.id = e.account_id
+WHERE a.kind = 'system' AND a.code = 'opening';
+
+DROP TABLE IF EXISTS ledger_entries;
+DROP FUNCTION IF EXISTS ledger_entries_check_balanced();
+DROP TABLE IF EXISTS ledger_accounts;
+
+DELETE FROM cashback_history WHERE id IN (SELECT history_id FROM opening_history);
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE cashback_balance_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    cashback_id BIGINT NOT NULL,
+    balance DECIMAL(12, 2) NOT NULL,
+    as_of TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cashback_id) REFERENCES cashback(id)
+);
+
+CREATE UNIQUE INDEX ux_cashback_balance_snapshots_cashback_id_as_of ON cashback_balance_snapshots(cashback_id, as_of DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cashback_balance_snapshots;
+-- +goose StatementEnd
@@ -0,0 +1,60 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE cashback_history RENAME COLUMN type TO reason;
+
+ALTER TABLE cashback_history
+    ADD COLUMN operation VARCHAR(32),
+    ADD COLUMN amount DECIMAL(12, 2),
+    ADD COLUMN balance_after DECIMAL(12, 2);
+
+-- Direction comes from the wallet posting; the system counterparty tells
+-- opening and adjustment transactions apart from ordinary credits/debits.
+WITH postings AS (
+    SELECT
+        e.history_id,
+        SUM(e.amount) FILTER (WHERE a.kind = 'user') AS amount,
+        MAX(a.code) FILTER (WHERE a.kind = 'system') AS system_code
+    FROM ledger_entries e
+    JOIN ledger_accounts a ON a.id = e.account_id
+    GROUP BY e.history_id
+)
+UPDATE cashback_history ch
+SET
+    amount = p.amount,
+    operation = CASE
+        WHEN p.system_code = 'opening' THEN 'opening'
+        WHEN p.system_code = 'adjustment' THEN 'adjustment'
+        WHEN p.amount >= 0 THEN 'credit'
+        ELSE 'debit'
+    END
+FROM postings p
+WHERE p.history_id = ch.id;
+
+-- Rows written before the ledger carry no direction; their net effect is
+-- already part of the opening transaction.
+UPDATE cashback_history SET operation = 'legacy' WHERE operation IS NULL;
+
+WITH running AS (
+    SELECT
+        id,
+        SUM(amount) OVER (PARTITION BY cashback_id ORDER BY created_at, id) AS balance_after
+    FROM cashback_history
+    WHERE amount IS NOT NULL
+)
+UPDATE cashback_history ch
+SET balance_after = r.balance_after
+FROM running r
+WHERE r.id = ch.id;
+
+ALTER TABLE cashback_history ALTER COLUMN operation SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE cashback_history
+    DROP COLUMN balance_after,
+    DROP COLUMN amount,
+    DROP COLUMN operation;
+
+ALTER TABLE cashback_history RENAME COLUMN reason TO type;
+-- +goose StatementEnd
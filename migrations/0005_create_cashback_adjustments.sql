@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE cashback_adjustments (
+    id BIGSERIAL PRIMARY KEY,
+    turon_user_id BIGINT NOT NULL,
+    amount DECIMAL(12, 2) NOT NULL,
+    reason_code VARCHAR(64) NOT NULL,
+    comment TEXT NOT NULL,
+    requested_by VARCHAR(255) NOT NULL,
+    approved_by VARCHAR(255),
+    rejected_by VARCHAR(255),
+    decision_comment TEXT,
+    status VARCHAR(32) NOT NULL,
+    history_id BIGINT,
+    error TEXT,
+    host_ip VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    decided_at TIMESTAMP,
+    FOREIGN KEY (history_id) REFERENCES cashback_history(id),
+    CHECK (amount <> 0)
+);
+
+CREATE INDEX idx_cashback_adjustments_status ON cashback_adjustments(status, created_at);
+CREATE INDEX idx_cashback_adjustments_turon_user_id ON cashback_adjustments(turon_user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cashback_adjustments;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_cashback_history_cashback_id_created_at ON cashback_history(cashback_id, created_at DESC, id DESC);
+CREATE INDEX idx_cashback_history_cashback_id_operation ON cashback_history(cashback_id, operation, created_at DESC);
+CREATE INDEX idx_cashback_history_cashback_id_amount ON cashback_history(cashback_id, cashback_amount);
+CREATE INDEX idx_cashback_history_source_id ON cashback_history(source_id);
+CREATE INDEX idx_cashback_history_reason_trgm ON cashback_history USING gin (reason gin_trgm_ops);
+CREATE INDEX idx_sources_slug ON sources(slug);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_sources_slug;
+DROP INDEX IF EXISTS idx_cashback_history_reason_trgm;
+DROP INDEX IF EXISTS idx_cashback_history_source_id;
+DROP INDEX IF EXISTS idx_cashback_history_cashback_id_amount;
+DROP INDEX IF EXISTS idx_cashback_history_cashback_id_operation;
+DROP INDEX IF EXISTS idx_cashback_history_cashback_id_created_at;
+-- +goose StatementEnd
@@ -0,0 +1,69 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Existing naive values are read in the session time zone. Run the migration
+-- with the zone the service wrote them in (SET TIME ZONE ...) if it differs.
+ALTER TABLE sources
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN deleted_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE cashback
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN deleted_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE cashback_history
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN deleted_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE ledger_accounts
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE ledger_entries
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE cashback_balance_snapshots
+    ALTER COLUMN as_of TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE cashback_adjustments
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN updated_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN decided_at TYPE TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE cashback_adjustments
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN updated_at TYPE TIMESTAMP,
+    ALTER COLUMN decided_at TYPE TIMESTAMP;
+
+ALTER TABLE cashback_balance_snapshots
+    ALTER COLUMN as_of TYPE TIMESTAMP,
+    ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE ledger_entries
+    ALTER COLUMN created_at TYPE TIMESTAMP;
+
+ALTER TABLE ledger_accounts
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN updated_at TYPE TIMESTAMP;
+
+ALTER TABLE cashback_history
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN updated_at TYPE TIMESTAMP,
+    ALTER COLUMN deleted_at TYPE TIMESTAMP;
+
+ALTER TABLE cashback
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN updated_at TYPE TIMESTAMP,
+    ALTER COLUMN deleted_at TYPE TIMESTAMP;
+
+ALTER TABLE sources
+    ALTER COLUMN created_at TYPE TIMESTAMP,
+    ALTER COLUMN updated_at TYPE TIMESTAMP,
+    ALTER COLUMN deleted_at TYPE TIMESTAMP;
+-- +goose StatementEnd
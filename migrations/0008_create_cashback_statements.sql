@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE cashback_statements (
+    id BIGSERIAL PRIMARY KEY,
+    cashback_id BIGINT NOT NULL,
+    turon_user_id BIGINT NOT NULL,
+    period CHAR(7) NOT NULL,
+    time_zone VARCHAR(64) NOT NULL,
+    opening_balance DECIMAL(12, 2) NOT NULL,
+    closing_balance DECIMAL(12, 2) NOT NULL,
+    data JSONB NOT NULL,
+    generated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cashback_id) REFERENCES cashback(id)
+);
+
+CREATE UNIQUE INDEX ux_cashback_statements_cashback_id_period ON cashback_statements(cashback_id, period, time_zone);
+CREATE INDEX idx_cashback_statements_turon_user_id ON cashback_statements(turon_user_id, period);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cashback_statements;
+-- +goose StatementEnd
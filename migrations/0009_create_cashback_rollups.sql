@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Hourly buckets in UTC roll up into days, weeks and months of any zone with
+-- a whole-hour offset, such as Asia/Tashkent.
+CREATE TABLE cashback_rollups (
+    bucket TIMESTAMPTZ NOT NULL,
+    source_id BIGINT NOT NULL DEFAULT 0,
+    operation VARCHAR(32) NOT NULL,
+    credited DECIMAL(14, 2) NOT NULL DEFAULT 0,
+    debited DECIMAL(14, 2) NOT NULL DEFAULT 0,
+    entries BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, source_id, operation)
+);
+
+CREATE TABLE cashback_user_rollups (
+    bucket TIMESTAMPTZ NOT NULL,
+    cashback_id BIGINT NOT NULL,
+    credited DECIMAL(14, 2) NOT NULL DEFAULT 0,
+    debited DECIMAL(14, 2) NOT NULL DEFAULT 0,
+    entries BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (bucket, cashback_id),
+    FOREIGN KEY (cashback_id) REFERENCES cashback(id)
+);
+
+CREATE INDEX idx_cashback_user_rollups_cashback_id ON cashback_user_rollups(cashback_id, bucket);
+
+INSERT INTO cashback_rollups (bucket, source_id, operation, credited, debited, entries)
+SELECT
+    date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+    COALESCE(source_id, 0),
+    operation,
+    COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0),
+    COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0),
+    COUNT(*)
+FROM cashback_history
+WHERE amount IS NOT NULL
+AND deleted_at IS NULL
+GROUP BY 1, 2, 3;
+
+INSERT INTO cashback_user_rollups (bucket, cashback_id, credited, debited, entries)
+SELECT
+    date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+    cashback_id,
+    COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0),
+    COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0),
+    COUNT(*)
+FROM cashback_history
+WHERE amount IS NOT NULL
+AND deleted_at IS NULL
+GROUP BY 1, 2;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cashback_user_rollups;
+DROP TABLE IF EXISTS cashback_rollups;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE cashback_batches (
+    id VARCHAR(64) PRIMARY KEY,
+    checksum CHAR(64) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    total INTEGER NOT NULL,
+    host_ip VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE cashback_batch_items (
+    batch_id VARCHAR(64) NOT NULL,
+    row_number INTEGER NOT NULL,
+    turon_user_id BIGINT NOT NULL,
+    cashback_amount DECIMAL(12, 2) NOT NULL,
+    reason VARCHAR(255) NOT NULL DEFAULT '',
+    status VARCHAR(32) NOT NULL,
+    history_id BIGINT,
+    error TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (batch_id, row_number),
+    FOREIGN KEY (batch_id) REFERENCES cashback_batches(id),
+    FOREIGN KEY (history_id) REFERENCES cashback_history(id)
+);
+
+CREATE INDEX idx_cashback_batch_items_status ON cashback_batch_items(batch_id, status);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cashback_batch_items;
+DROP TABLE IF EXISTS cashback_batches;
+-- +goose StatementEnd
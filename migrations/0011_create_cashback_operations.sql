@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE cashback_operations (
+    id VARCHAR(36) PRIMARY KEY,
+    type VARCHAR(32) NOT NULL,
+    turon_user_id BIGINT NOT NULL,
+    cashback_amount DECIMAL(12, 2) NOT NULL,
+    status VARCHAR(32) NOT NULL,
+    history_id BIGINT,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMPTZ,
+    FOREIGN KEY (history_id) REFERENCES cashback_history(id)
+);
+
+CREATE INDEX idx_cashback_operations_status_created_at ON cashback_operations(status, created_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS cashback_operations;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE cashback
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active',
+    ADD CONSTRAINT chk_cashback_status CHECK (status IN ('active', 'frozen', 'closed'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE cashback
+    DROP CONSTRAINT chk_cashback_status,
+    DROP COLUMN status;
+-- +goose StatementEnd
@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE cashback
+    ADD COLUMN freeze_credits BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN status_reason TEXT,
+    ADD COLUMN status_changed_at TIMESTAMPTZ;
+
+CREATE TABLE cashback_status_events (
+    id BIGSERIAL PRIMARY KEY,
+    cashback_id BIGINT NOT NULL,
+    from_status VARCHAR(16) NOT NULL,
+    to_status VARCHAR(16) NOT NULL,
+    reason TEXT NOT NULL,
+    operator VARCHAR(100) NOT NULL,
+    history_id BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (cashback_id) REFERENCES cashback(id),
+    FOREIGN KEY (history_id) REFERENCES cashback_history(id)
+);
+
+CREATE INDEX idx_cashback_status_events_cashback_id ON cashback_status_events(cashback_id, created_at);
+
+INSERT INTO ledger_accounts (kind, code) VALUES
+    ('system', 'payout'),
+    ('system', 'forfeiture');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM ledger_accounts
+WHERE kind = 'system'
+AND code IN ('payout', 'forfeiture')
+AND NOT EXISTS (SELECT 1 FROM ledger_entries e WHERE e.account_id = ledger_accounts.id);
+
+DROP TABLE IF EXISTS cashback_status_events;
+
+ALTER TABLE cashback
+    DROP COLUMN status_changed_at,
+    DROP COLUMN status_reason,
+    DROP COLUMN freeze_credits;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The Idempotency-Key of the increase or decrease that wrote the row; a
+-- retried request with the same key is answered from the existing row.
+ALTER TABLE cashback_history
+    ADD COLUMN idempotency_key VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_cashback_history_idempotency_key ON cashback_history(idempotency_key) WHERE idempotency_key IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_cashback_history_idempotency_key;
+
+ALTER TABLE cashback_history
+    DROP COLUMN idempotency_key;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The Idempotency-Key of an async increase or decrease; a retried submit
+-- with the same key gets the existing operation back.
+ALTER TABLE cashback_operations
+    ADD COLUMN idempotency_key VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_cashback_operations_idempotency_key ON cashback_operations(idempotency_key) WHERE idempotency_key IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_cashback_operations_idempotency_key;
+
+ALTER TABLE cashback_operations
+    DROP COLUMN idempotency_key;
+-- +goose StatementEnd
@@ -0,0 +1,141 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Opening transactions were stamped with the ledger cut-over, so as-of
+-- balances before it came out as zero. A wallet's balance last changed with
+-- its latest legacy row (or, without one, when it was created); move each
+-- opening transaction back to that instant. Earlier balances cannot be
+-- rebuilt: legacy rows carry no direction.
+CREATE TEMPORARY TABLE opening_moves ON COMMIT DROP AS
+SELECT
+    h.id AS history_id,
+    h.cashback_id,
+    h.amount,
+    h.created_at AS old_at,
+    COALESCE(
+        (
+            SELECT MAX(l.created_at)
+            FROM cashback_history l
+            WHERE l.cashback_id = h.cashback_id
+            AND l.operation = 'legacy'
+            AND l.created_at < h.created_at
+        ),
+        c.created_at
+    ) AS new_at
+FROM cashback_history h
+JOIN cashback c ON c.id = h.cashback_id
+WHERE h.operation = 'opening';
+
+DELETE FROM opening_moves WHERE new_at IS NULL OR new_at >= old_at;
+
+-- Rollups follow the history row to its new hour.
+WITH moved AS (
+    SELECT
+        date_trunc('hour', old_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS bucket,
+        COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0) AS credited,
+        COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0) AS debited,
+        COUNT(*) AS entries
+    FROM opening_moves
+    GROUP BY 1
+)
+UPDATE cashback_rollups r
+SET
+    credited = r.credited - m.credited,
+    debited = r.debited - m.debited,
+    entries = r.entries - m.entries
+FROM moved m
+WHERE r.bucket = m.bucket
+AND r.source_id = 0
+AND r.operation = 'opening';
+
+INSERT INTO cashback_rollups (bucket, source_id, operation, credited, debited, entries)
+SELECT
+    date_trunc('hour', new_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+    0,
+    'opening',
+    COALESCE(SUM(amount) FILTER (WHERE amount > 0), 0),
+    COALESCE(-SUM(amount) FILTER (WHERE amount < 0), 0),
+    COUNT(*)
+FROM opening_moves
+GROUP BY 1
+ON CONFLICT (bucket, source_id, operation) DO UPDATE SET
+    credited = cashback_rollups.credited + EXCLUDED.credited,
+    debited = cashback_rollups.debited + EXCLUDED.debited,
+    entries = cashback_rollups.entries + EXCLUDED.entries;
+
+DELETE FROM cashback_rollups WHERE operation = 'opening' AND entries <= 0;
+
+UPDATE cashback_user_rollups r
+SET
+    credited = r.credited - GREATEST(m.amount, 0),
+    debited = r.debited - GREATEST(-m.amount, 0),
+    entries = r.entries - 1
+FROM opening_moves m
+WHERE r.cashback_id = m.cashback_id
+AND r.bucket = date_trunc('hour', m.old_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+
+INSERT INTO cashback_user_rollups (bucket, cashback_id, credited, debited, entries)
+SELECT
+    date_trunc('hour', new_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+    cashback_id,
+    GREATEST(amount, 0),
+    GREATEST(-amount, 0),
+    1
+FROM opening_moves
+ON CONFLICT (bucket, cashback_id) DO UPDATE SET
+    credited = cashback_user_rollups.credited + EXCLUDED.credited,
+    debited = cashback_user_rollups.debited + EXCLUDED.debited,
+    entries = cashback_user_rollups.entries + EXCLUDED.entries;
+
+DELETE FROM cashback_user_rollups WHERE entries <= 0;
+
+UPDATE ledger_entries e
+SET created_at = m.new_at
+FROM opening_moves m
+WHERE e.history_id = m.history_id;
+
+UPDATE cashback_history h
+SET
+    created_at = m.new_at,
+    updated_at = m.new_at
+FROM opening_moves m
+WHERE h.id = m.history_id;
+
+-- Snapshots and stored statements of the moved window were built without
+-- the opening balance; they are taken or rebuilt again on demand. A day of
+-- slack covers every statement time zone.
+DELETE FROM cashback_balance_snapshots s
+USING opening_moves m
+WHERE s.cashback_id = m.cashback_id
+AND s.as_of >= m.new_at
+AND s.as_of < m.old_at;
+
+DELETE FROM cashback_statements s
+USING opening_moves m
+WHERE s.cashback_id = m.cashback_id
+AND to_date(s.period, 'YYYY-MM') < m.old_at + INTERVAL '1 day';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Only the ledger and history stamps are restored, to the cut-over instant
+-- recorded on the system opening account.
+CREATE TEMPORARY TABLE opening_moves ON COMMIT DROP AS
+SELECT h.id AS history_id, a.created_at AS old_at
+FROM cashback_history h
+CROSS JOIN ledger_accounts a
+WHERE h.operation = 'opening'
+AND a.kind = 'system'
+AND a.code = 'opening';
+
+UPDATE ledger_entries e
+SET created_at = m.old_at
+FROM opening_moves m
+WHERE e.history_id = m.history_id;
+
+UPDATE cashback_history h
+SET
+    created_at = m.old_at,
+    updated_at = m.old_at
+FROM opening_moves m
+WHERE h.id = m.history_id;
+-- +goose StatementEnd
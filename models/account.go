@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// AccountStatusRequest freezes or unfreezes an account. Operator is the
+// authenticated operator, never read from the body.
+type AccountStatusRequest struct {
+	Reason   string `json:"reason" example:"fraud investigation #881"`
+	Operator string `json:"-"`
+	// BlockCredits makes a freeze reject credits too; debits are always
+	// rejected.
+	BlockCredits bool `json:"block_credits" example:"false"`
+}
+
+// CloseAccountRequest closes an account. Disposition decides whether the
+// remaining balance is paid out or forfeited. Operator is the authenticated
+// operator, never read from the body.
+type CloseAccountRequest struct {
+	Reason      string `json:"reason" example:"customer request"`
+	Operator    string `json:"-"`
+	Disposition string `json:"disposition" example:"payout" enums:"payout,forfeit"`
+}
+
+// AccountStatusChange is one transition of an account's lifecycle.
+type AccountStatusChange struct {
+	CashbackID    int64     `json:"cashback_id" example:"1"`
+	FromStatus    string    `json:"from_status" example:"active"`
+	ToStatus      string    `json:"to_status" example:"frozen"`
+	Reason        string    `json:"reason" example:"fraud investigation #881"`
+	Operator      string    `json:"operator" example:"fraud.carol"`
+	FreezeCredits bool      `json:"freeze_credits" example:"false"`
+	HistoryID     *int64    `json:"history_id,omitempty" example:"42"`
+	CreatedAt     time.Time `json:"created_at" example:"2024-03-20T10:00:00Z"`
+}
+
+// AccountClosure is a closed account and the history entry that settled its
+// remaining balance, if there was one.
+type AccountClosure struct {
+	Account    *Cashback        `json:"account"`
+	Settlement *CashbackHistory `json:"settlement,omitempty"`
+}
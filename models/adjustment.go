@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// AdjustmentRequest is a manual balance correction entered by support staff.
+// Amount is signed: positive credits the user, negative debits. Operator is
+// the authenticated operator, never read from the body.
+type AdjustmentRequest struct {
+	Amount     float64 `json:"amount" example:"-25.00"`
+	ReasonCode string  `json:"reason_code" example:"correction"`
+	Comment    string  `json:"comment" example:"Duplicate credit for order 1042"`
+	Operator   string  `json:"-"`
+}
+
+// AdjustmentDecision is the second operator's approval or rejection.
+// Operator is the authenticated operator, never read from the body.
+type AdjustmentDecision struct {
+	Operator string `json:"-"`
+	Comment  string `json:"comment" example:"Checked against order log"`
+}
+
+type CashbackAdjustment struct {
+	ID              int64      `json:"id" example:"1"`
+	TuronUserID     int64      `json:"turon_user_id" example:"123"`
+	Amount          float64    `json:"amount" example:"-25.00"`
+	ReasonCode      string     `json:"reason_code" example:"correction"`
+	Comment         string     `json:"comment" example:"Duplicate credit for order 1042"`
+	RequestedBy     string     `json:"requested_by" example:"support.alice"`
+	ApprovedBy      *string    `json:"approved_by,omitempty" example:"support.bob"`
+	RejectedBy      *string    `json:"rejected_by,omitempty" example:"support.bob"`
+	DecisionComment *string    `json:"decision_comment,omitempty" example:"Checked against order log"`
+	Status          string     `json:"status" example:"posted"`
+	HistoryID       *int64     `json:"history_id,omitempty" example:"42"`
+	Error           *string    `json:"error,omitempty" example:"insufficient cashback amount"`
+	HostIP          string     `json:"host_ip" example:"192.168.1.1"`
+	CreatedAt       time.Time  `json:"created_at" example:"2024-03-20T10:00:00Z"`
+	UpdatedAt       time.Time  `json:"updated_at" example:"2024-03-20T10:00:00Z"`
+	DecidedAt       *time.Time `json:"decided_at,omitempty" example:"2024-03-20T10:05:00Z"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// AnalyticsFilter selects the range and grouping of an analytics query.
+type AnalyticsFilter struct {
+	// FromDate and ToDate are the raw client bounds, as in HistoryFilter;
+	// From and Until are resolved by the service, Until exclusive.
+	FromDate string
+	ToDate   string
+	TimeZone string
+	From     *time.Time
+	Until    *time.Time
+	// Interval is day, week or month; GroupBy is empty, source or operation.
+	Interval string
+	GroupBy  string
+	Limit    int
+}
+
+type AnalyticsTotal struct {
+	Period    time.Time `json:"period" example:"2024-03-01T00:00:00+05:00"`
+	Source    string    `json:"source,omitempty" example:"turon"`
+	Operation string    `json:"operation,omitempty" example:"credit"`
+	Credited  float64   `json:"credited" example:"1500.00"`
+	Debited   float64   `json:"debited" example:"300.00"`
+	Net       float64   `json:"net" example:"1200.00"`
+	Entries   int64     `json:"entries" example:"42"`
+}
+
+// LiabilityPoint is the cashback outstanding across all wallets at the end
+// of a period.
+type LiabilityPoint struct {
+	Period    time.Time `json:"period" example:"2024-03-01T00:00:00+05:00"`
+	Liability float64   `json:"liability" example:"125000.00"`
+}
+
+type TopEarner struct {
+	TuronUserID int64   `json:"turon_user_id" example:"123"`
+	CashbackID  int64   `json:"cashback_id" example:"1"`
+	Credited    float64 `json:"credited" example:"500.00"`
+	Debited     float64 `json:"debited" example:"100.00"`
+	Net         float64 `json:"net" example:"400.00"`
+	Entries     int64   `json:"entries" example:"12"`
+}
+
+// ActiveUsersPoint counts the wallets with at least one entry in a period.
+type ActiveUsersPoint struct {
+	Period      time.Time `json:"period" example:"2024-03-01T00:00:00+05:00"`
+	ActiveUsers int64     `json:"active_users" example:"830"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// BulkCreditRow is one credit of a bulk payout.
+type BulkCreditRow struct {
+	TuronUserID    int64   `json:"turon_user_id" example:"123"`
+	CashbackAmount float64 `json:"cashback_amount" example:"25.00"`
+	Reason         string  `json:"reason" example:"partner_payout"`
+}
+
+// BulkRowError reports why a row of a bulk payout was rejected.
+type BulkRowError struct {
+	Row   int    `json:"row" example:"3"`
+	Error string `json:"error" example:"cashback_amount must be positive"`
+}
+
+// CashbackBatch is a bulk payout and its per-row outcome.
+type CashbackBatch struct {
+	ID        string      `json:"batch_id" example:"partner-2024-03"`
+	Checksum  string      `json:"-"`
+	Status    string      `json:"status" example:"completed"`
+	Total     int         `json:"total" example:"3"`
+	Succeeded int         `json:"succeeded" example:"2"`
+	Failed    int         `json:"failed" example:"1"`
+	Pending   int         `json:"pending" example:"0"`
+	HostIP    string      `json:"-"`
+	CreatedAt time.Time   `json:"created_at" example:"2024-03-20T10:00:00Z"`
+	UpdatedAt time.Time   `json:"updated_at" example:"2024-03-20T10:02:00Z"`
+	Items     []BatchItem `json:"items"`
+}
+
+type BatchItem struct {
+	Row            int     `json:"row" example:"1"`
+	TuronUserID    int64   `json:"turon_user_id" example:"123"`
+	CashbackAmount float64 `json:"cashback_amount" example:"25.00"`
+	Reason         string  `json:"reason" example:"partner_payout"`
+	Status         string  `json:"status" example:"succeeded"`
+	HistoryID      *int64  `json:"history_id,omitempty" example:"42"`
+	Error          *string `json:"error,omitempty" example:"source not found"`
+}
@@ -5,30 +5,100 @@ import (
 )
 
 type Cashback struct {
-	ID             int64      `json:"id" db:"id" example:"1"`
-	CashbackAmount float64    `json:"cashback_amount" db:"cashback_amount" example:"100.50"`
-	TuronUserID    int64      `json:"turon_user_id" db:"turon_user_id" example:"123"`
-	CreatedAt      time.Time  `json:"created_at" db:"created_at" example:"2024-03-20T10:00:00Z"`
-	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at" example:"2024-03-20T10:00:00Z"`
-	DeletedAt      *time.Time `json:"deleted_at" db:"deleted_at" example:"null"`
+	ID              int64      `json:"id" db:"id" example:"1"`
+	CashbackAmount  float64    `json:"cashback_amount" db:"cashback_amount" example:"100.50"`
+	TuronUserID     int64      `json:"turon_user_id" db:"turon_user_id" example:"123"`
+	Status          string     `json:"status" db:"status" example:"active" enums:"none,active,frozen,closed"`
+	FreezeCredits   bool       `json:"freeze_credits,omitempty" db:"freeze_credits" example:"false"`
+	StatusReason    *string    `json:"status_reason,omitempty" db:"status_reason" example:"fraud investigation"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty" db:"status_changed_at" example:"2024-03-20T10:00:00Z"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at" example:"2024-03-20T10:00:00Z"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at" example:"2024-03-20T10:00:00Z"`
+	DeletedAt       *time.Time `json:"deleted_at" db:"deleted_at" example:"null"`
 }
 
 type CashbackHistory struct {
 	ID             int64      `json:"id" db:"id" example:"1"`
 	CashbackID     int64      `json:"cashback_id" db:"cashback_id" example:"1"`
+	TuronUserID    int64      `json:"turon_user_id" db:"turon_user_id" example:"123"`
 	SourceID       int64      `json:"-" db:"source_id" example:"1"`
 	SourceSlug     string     `json:"source_slug" db:"source_slug" example:"turon"`
-	Type           string     `json:"type" db:"type" example:"turon"`
+	Operation      string     `json:"operation" db:"operation" example:"credit"`
+	Reason         string     `json:"reason" db:"reason" example:"purchase"`
 	CashbackAmount float64    `json:"cashback_amount" db:"cashback_amount" example:"50.25"`
+	Amount         *float64   `json:"amount" db:"amount" example:"-50.25"`
+	BalanceAfter   *float64   `json:"balance_after" db:"balance_after" example:"100.50"`
 	HostIP         string     `json:"host_ip" db:"host_ip" example:"192.168.1.1"`
+	IdempotencyKey string     `json:"-" db:"idempotency_key"`
 	CreatedAt      time.Time  `json:"created_at" db:"created_at" example:"2024-03-20T10:00:00Z"`
 	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at" example:"2024-03-20T10:00:00Z"`
 	DeletedAt      *time.Time `json:"deleted_at" db:"deleted_at" example:"null"`
 }
 
+// CashbackHistoryV1 is a history entry as the v1 routes serve it. Type
+// repeats Reason under the name it had before the rename.
+type CashbackHistoryV1 struct {
+	CashbackHistory
+	// Deprecated: use Reason.
+	Type string `json:"type" example:"purchase"`
+}
+
+// NewCashbackHistoryV1 wraps history entries for the v1 routes.
+func NewCashbackHistoryV1(history []CashbackHistory) []CashbackHistoryV1 {
+	entries := make([]CashbackHistoryV1, len(history))
+	for i, h := range history {
+		entries[i] = CashbackHistoryV1{CashbackHistory: h, Type: h.Reason}
+	}
+	return entries
+}
+
 type CashbackRequest struct {
 	TuronUserID    int64   `json:"turon_user_id"`
 	CashbackAmount float64 `json:"cashback_amount"`
 	HostIP         string  `json:"host_ip"`
-	Type           string  `json:"type"`
+	Reason         string  `json:"reason"`
+	// Deprecated: use Reason. Still accepted when Reason is empty.
+	Type string `json:"type"`
+	// IdempotencyKey comes from the Idempotency-Key header. A request that
+	// repeats a key gets the outcome of the first one instead of posting
+	// again.
+	IdempotencyKey string `json:"-"`
+}
+
+// GetReason returns the client-supplied reason, falling back to the legacy
+// type field.
+func (r *CashbackRequest) GetReason() string {
+	if r.Reason != "" {
+		return r.Reason
+	}
+	return r.Type
+}
+
+// CashbackMutation is the outcome of an increase or decrease: the balance
+// after it and the history entry it wrote.
+type CashbackMutation struct {
+	Balance *Cashback        `json:"balance"`
+	History *CashbackHistory `json:"history"`
+	// Replayed is set when the request repeated an Idempotency-Key and
+	// nothing new was posted.
+	Replayed bool `json:"replayed"`
+}
+
+// CashbackBalance is a user's balance as it stood at AsOf.
+type CashbackBalance struct {
+	CashbackID     int64     `json:"cashback_id" example:"1"`
+	TuronUserID    int64     `json:"turon_user_id" example:"123"`
+	CashbackAmount float64   `json:"cashback_amount" example:"100.50"`
+	AsOf           time.Time `json:"as_of" example:"2024-03-31T23:59:59Z"`
+}
+
+type BalanceLookupRequest struct {
+	TuronUserIDs []int64 `json:"turon_user_ids" example:"123,456"`
+}
+
+// BalanceLookupResult holds the balances found by a batch lookup; Missing
+// lists the requested users that have no cashback wallet.
+type BalanceLookupResult struct {
+	Balances []Cashback `json:"balances"`
+	Missing  []int64    `json:"missing" example:"456"`
 }
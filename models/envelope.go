@@ -0,0 +1,41 @@
+package models
+
+// Error codes of /v2 responses.
+const (
+	ErrorCodeInvalidArgument      = "invalid_argument"
+	ErrorCodeValidationFailed     = "validation_failed"
+	ErrorCodeUnauthenticated      = "unauthenticated"
+	ErrorCodeNotFound             = "not_found"
+	ErrorCodeAccountErased        = "account_erased"
+	ErrorCodeAccountFrozen        = "account_frozen"
+	ErrorCodeAccountClosed        = "account_closed"
+	ErrorCodeInsufficientCashback = "insufficient_cashback"
+	ErrorCodeBatchConflict        = "batch_conflict"
+	ErrorCodeIdempotencyConflict  = "idempotency_conflict"
+	ErrorCodeUnavailable          = "unavailable"
+	ErrorCodeInternal             = "internal"
+)
+
+// Envelope is the body of every /v2 response. Exactly one of Data and Error
+// is set.
+type Envelope struct {
+	Data      interface{} `json:"data,omitempty" swaggertype:"object"`
+	Error     *APIError   `json:"error,omitempty"`
+	RequestID string      `json:"request_id" example:"4f1c2a9e0b7d4e3f8a6b5c4d3e2f1a0b"`
+}
+
+// APIError is a machine-readable code with a human-readable message. Details
+// carries structured context for some codes, e.g. the failing rows of a bulk
+// upload.
+type APIError struct {
+	Code    string      `json:"code" example:"invalid_argument"`
+	Message string      `json:"message" example:"turon_user_id must be positive"`
+	Details interface{} `json:"details,omitempty" swaggertype:"object"`
+}
+
+// HistoryPage is the data of a /v2 history response. Pagination is a
+// Pagination or, in cursor mode, a CursorPagination.
+type HistoryPage struct {
+	Items      []CashbackHistory `json:"items"`
+	Pagination interface{}       `json:"pagination" swaggertype:"object"`
+}
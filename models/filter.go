@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// HistoryFilter narrows and orders a user's cashback history. Empty fields
+// do not filter.
+type HistoryFilter struct {
+	// FromDate and ToDate are the raw client bounds: RFC 3339 timestamps, or
+	// YYYY-MM-DD dates interpreted in TimeZone with an inclusive end date.
+	FromDate string
+	ToDate   string
+	TimeZone string
+	// From and Until are resolved from the raw bounds by the service; Until
+	// is exclusive.
+	From       *time.Time
+	Until      *time.Time
+	Operations []string
+	Sources    []string
+	MinAmount  *float64
+	MaxAmount  *float64
+	Reason     string
+	SortBy     string
+	SortOrder  string
+}
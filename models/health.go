@@ -0,0 +1,15 @@
+package models
+
+// Readiness is the answer of /readyz: ready only when every check is ok.
+type Readiness struct {
+	Status string                 `json:"status" example:"ready" enums:"ready,not_ready"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// HealthCheck is the outcome of one readiness check. Details carries what the
+// check measured, e.g. the queue depth.
+type HealthCheck struct {
+	Status  string                 `json:"status" example:"ok" enums:"ok,failed"`
+	Error   string                 `json:"error,omitempty" example:"database is unreachable"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// LedgerAccount is one side of a posting: a user wallet, a source (issuer)
+// account or a system account such as expiry or reversal.
+type LedgerAccount struct {
+	ID         int64     `json:"id" db:"id" example:"1"`
+	Kind       string    `json:"kind" db:"kind" example:"user"`
+	CashbackID *int64    `json:"cashback_id,omitempty" db:"cashback_id" example:"1"`
+	SourceID   *int64    `json:"source_id,omitempty" db:"source_id" example:"1"`
+	Code       *string   `json:"code,omitempty" db:"code" example:"expiry"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at" example:"2024-03-20T10:00:00Z"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at" example:"2024-03-20T10:00:00Z"`
+}
+
+// LedgerEntry is a signed posting against an account. All entries sharing a
+// HistoryID form one balanced transaction.
+type LedgerEntry struct {
+	ID        int64     `json:"id" db:"id" example:"1"`
+	HistoryID int64     `json:"history_id" db:"history_id" example:"1"`
+	AccountID int64     `json:"account_id" db:"account_id" example:"1"`
+	Amount    float64   `json:"amount" db:"amount" example:"-50.25"`
+	CreatedAt time.Time `json:"created_at" db:"created_at" example:"2024-03-20T10:00:00Z"`
+}
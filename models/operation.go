@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Operation tracks an increase or decrease submitted with async=true.
+type Operation struct {
+	ID             string     `json:"id" example:"0f8fad5b-d9cb-469f-a165-70867728950e"`
+	Type           string     `json:"type" example:"increase"`
+	TuronUserID    int64      `json:"turon_user_id" example:"123"`
+	CashbackAmount float64    `json:"cashback_amount" example:"25.00"`
+	Status         string     `json:"status" example:"succeeded"`
+	HistoryID      *int64     `json:"history_id,omitempty" example:"42"`
+	Error          *string    `json:"error,omitempty" example:"insufficient cashback amount"`
+	CreatedAt      time.Time  `json:"created_at" example:"2024-03-20T10:00:00Z"`
+	UpdatedAt      time.Time  `json:"updated_at" example:"2024-03-20T10:00:01Z"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" example:"2024-03-20T10:00:01Z"`
+	// IdempotencyKey is the Idempotency-Key the operation was submitted
+	// with; a retry with the same key gets this operation back.
+	IdempotencyKey string `json:"-"`
+}
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
 type Pagination struct {
 	Limit     int64 `json:"-" default:"10"`
 	Offset    int64 `json:"-" default:"0"`
@@ -7,6 +14,8 @@ type Pagination struct {
 	PageSize  int64 `json:"pageSize" default:"10"`
 	PageTotal int64 `json:"pageTotal"`
 	ItemTotal int64 `json:"itemTotal"`
+	// SkipTotal leaves PageTotal and ItemTotal unset and saves the COUNT(*).
+	SkipTotal bool `json:"-"`
 }
 
 func (p *Pagination) Calculate() {
@@ -19,3 +28,42 @@ func (p *Pagination) Calculate() {
 	p.Limit = p.PageSize
 	p.Offset = (p.Page - 1) * p.PageSize
 }
+
+// CursorPagination pages by keyset on (created_at, id), so deep pages cost
+// the same as the first and rows inserted meanwhile do not shift results.
+type CursorPagination struct {
+	Cursor       string  `json:"-"`
+	PageSize     int64   `json:"pageSize" default:"10"`
+	NextCursor   *string `json:"next_cursor"`
+	PrevCursor   *string `json:"prev_cursor"`
+	IncludeTotal bool    `json:"-"`
+	ItemTotal    *int64  `json:"itemTotal,omitempty"`
+}
+
+// HistoryCursor is the decoded form of an opaque cursor. Backward cursors
+// page towards the start of the list.
+type HistoryCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (c HistoryCursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeHistoryCursor(value string) (*HistoryCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor HistoryCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 || cursor.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// BalanceDiscrepancy is a wallet whose stored cashback_amount differs from the
+// signed sum of its history postings.
+type BalanceDiscrepancy struct {
+	CashbackID          int64   `json:"cashback_id" example:"1"`
+	TuronUserID         int64   `json:"turon_user_id" example:"123"`
+	StoredBalance       float64 `json:"stored_balance" example:"100.50"`
+	RecomputedBalance   float64 `json:"recomputed_balance" example:"90.50"`
+	Difference          float64 `json:"difference" example:"10.00"`
+	Repaired            bool    `json:"repaired" example:"false"`
+	AdjustmentHistoryID *int64  `json:"adjustment_history_id,omitempty" example:"42"`
+	RepairError         string  `json:"repair_error,omitempty" example:""`
+}
+
+type ReconciliationReport struct {
+	FromTuronUserID int64                `json:"from_turon_user_id,omitempty" example:"1"`
+	ToTuronUserID   int64                `json:"to_turon_user_id,omitempty" example:"1000"`
+	Repair          bool                 `json:"repair" example:"false"`
+	Scanned         int64                `json:"scanned" example:"1000"`
+	Discrepancies   []BalanceDiscrepancy `json:"discrepancies"`
+	StartedAt       time.Time            `json:"started_at" example:"2024-03-20T10:00:00Z"`
+	FinishedAt      time.Time            `json:"finished_at" example:"2024-03-20T10:00:05Z"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Statement is a user's official monthly cashback statement.
+type Statement struct {
+	TuronUserID    int64           `json:"turon_user_id" example:"123"`
+	CashbackID     int64           `json:"cashback_id" example:"1"`
+	Period         string          `json:"period" example:"2024-03"`
+	PeriodStart    time.Time       `json:"period_start" example:"2024-03-01T00:00:00+05:00"`
+	PeriodEnd      time.Time       `json:"period_end" example:"2024-04-01T00:00:00+05:00"`
+	TimeZone       string          `json:"time_zone" example:"Asia/Tashkent"`
+	OpeningBalance float64         `json:"opening_balance" example:"100.00"`
+	TotalCredited  float64         `json:"total_credited" example:"50.00"`
+	TotalDebited   float64         `json:"total_debited" example:"20.00"`
+	TotalExpired   float64         `json:"total_expired" example:"0.00"`
+	TotalAdjusted  float64         `json:"total_adjusted" example:"0.00"`
+	ClosingBalance float64         `json:"closing_balance" example:"130.00"`
+	Lines          []StatementLine `json:"lines"`
+	GeneratedAt    time.Time       `json:"generated_at" example:"2024-04-01T00:05:00+05:00"`
+}
+
+type StatementLine struct {
+	HistoryID    int64     `json:"history_id" example:"42"`
+	Date         time.Time `json:"date" example:"2024-03-05T14:20:00+05:00"`
+	Operation    string    `json:"operation" example:"credit"`
+	Source       string    `json:"source" example:"turon"`
+	Reason       string    `json:"reason" example:"purchase"`
+	Amount       *float64  `json:"amount" example:"50.00"`
+	BalanceAfter *float64  `json:"balance_after" example:"150.00"`
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookSubscriptionRequest registers a URL for events of one source, or of
+// every source when Source is empty.
+type WebhookSubscriptionRequest struct {
+	Source string   `json:"source" example:"turon"`
+	URL    string   `json:"url" example:"https://partner.example.com/hooks/cashback"`
+	Events []string `json:"events" example:"cashback.credited,cashback.debited"`
+}
+
+type WebhookSubscription struct {
+	ID     int64   `json:"id" example:"1"`
+	Source *string `json:"source,omitempty" example:"turon"`
+	URL    string  `json:"url" example:"https://partner.example.com/hooks/cashback"`
+	// Secret signs the deliveries. It is only returned when the subscription
+	// is created.
+	Secret    string    `json:"secret,omitempty" example:"whsec_6f1c..."`
+	Events    []string  `json:"events" example:"cashback.credited,cashback.debited"`
+	CreatedAt time.Time `json:"created_at" example:"2024-03-20T10:00:00Z"`
+}
+
+// WebhookEvent is an outbox entry. Payload becomes the data field of the
+// body delivered to subscribers.
+type WebhookEvent struct {
+	ID        int64           `json:"id" example:"1"`
+	Type      string          `json:"type" example:"cashback.credited"`
+	SourceID  int64           `json:"-"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at" example:"2024-03-20T10:00:00Z"`
+}
+
+// CashbackEventData is the data of cashback.* and account.closed events.
+// For account.closed, Amount and HistoryID describe the settlement of the
+// remaining balance, if there was one.
+type CashbackEventData struct {
+	TuronUserID  int64    `json:"turon_user_id"`
+	HistoryID    *int64   `json:"history_id,omitempty"`
+	Amount       float64  `json:"amount"`
+	BalanceAfter *float64 `json:"balance_after,omitempty"`
+	Reason       string   `json:"reason,omitempty"`
+	Disposition  string   `json:"disposition,omitempty"`
+	Error        string   `json:"error,omitempty"`
+}
+
+// SourceEventData is the data of source.* events.
+type SourceEventData struct {
+	ID     int64  `json:"id"`
+	Slug   string `json:"slug"`
+	HostIP string `json:"host_ip"`
+}
+
+type WebhookDelivery struct {
+	ID             int64           `json:"id" example:"1"`
+	EventID        int64           `json:"event_id" example:"1"`
+	EventType      string          `json:"event_type" example:"cashback.credited"`
+	SubscriptionID int64           `json:"subscription_id" example:"1"`
+	URL            string          `json:"url" example:"https://partner.example.com/hooks/cashback"`
+	Secret         string          `json:"-"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status" example:"dead"`
+	Attempts       int             `json:"attempts" example:"10"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" example:"2024-03-20T10:00:00Z"`
+	LastStatusCode *int            `json:"last_status_code,omitempty" example:"503"`
+	LastError      *string         `json:"last_error,omitempty" example:"unexpected status 503"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty" example:"2024-03-20T10:00:01Z"`
+	CreatedAt      time.Time       `json:"created_at" example:"2024-03-20T10:00:00Z"`
+	UpdatedAt      time.Time       `json:"updated_at" example:"2024-03-20T10:05:00Z"`
+}
@@ -0,0 +1,433 @@
+package client
+
+import (
+	"bufio"
+	"cashback-serv/models"
+	"cashback-serv/pkg/signature"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IncreaseCashback credits the user and returns the resulting balance and
+// history entry. req.IdempotencyKey is generated when empty; set it to keep
+// retries of the same operation across calls from posting twice.
+func (c *Client) IncreaseCashback(ctx context.Context, req *models.CashbackRequest) (*models.CashbackMutation, error) {
+	return c.mutate(ctx, "/v2/cashback/increase", req)
+}
+
+// DecreaseCashback debits the user, as IncreaseCashback credits.
+func (c *Client) DecreaseCashback(ctx context.Context, req *models.CashbackRequest) (*models.CashbackMutation, error) {
+	return c.mutate(ctx, "/v2/cashback/decrease", req)
+}
+
+func (c *Client) mutate(ctx context.Context, path string, req *models.CashbackRequest) (*models.CashbackMutation, error) {
+	r, err := c.mutationRequest(path, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var mutation models.CashbackMutation
+	if err := c.call(ctx, r, &mutation); err != nil {
+		return nil, err
+	}
+	return &mutation, nil
+}
+
+// SubmitIncrease queues an increase for background processing; poll
+// GetOperation for the outcome.
+func (c *Client) SubmitIncrease(ctx context.Context, req *models.CashbackRequest) (*models.Operation, error) {
+	return c.submit(ctx, "/v2/cashback/increase", req)
+}
+
+// SubmitDecrease queues a decrease for background processing.
+func (c *Client) SubmitDecrease(ctx context.Context, req *models.CashbackRequest) (*models.Operation, error) {
+	return c.submit(ctx, "/v2/cashback/decrease", req)
+}
+
+func (c *Client) submit(ctx context.Context, path string, req *models.CashbackRequest) (*models.Operation, error) {
+	r, err := c.mutationRequest(path, req)
+	if err != nil {
+		return nil, err
+	}
+	r.query = url.Values{"async": {"true"}}
+
+	var operation models.Operation
+	if err := c.call(ctx, r, &operation); err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+func (c *Client) mutationRequest(path string, req *models.CashbackRequest) (*request, error) {
+	if req.IdempotencyKey == "" {
+		req.IdempotencyKey = NewIdempotencyKey()
+	}
+
+	r, err := jsonRequest(http.MethodPost, path, req)
+	if err != nil {
+		return nil, err
+	}
+	r.header = http.Header{IdempotencyKeyHeader: {req.IdempotencyKey}}
+	return r, nil
+}
+
+func (c *Client) GetOperation(ctx context.Context, id string) (*models.Operation, error) {
+	var operation models.Operation
+	r := &request{method: http.MethodGet, path: "/v2/operations/" + url.PathEscape(id)}
+	if err := c.call(ctx, r, &operation); err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+// GetCashback returns the user's balance and account status. A user without
+// a wallet has status none and a zero balance.
+func (c *Client) GetCashback(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
+	var cashback models.Cashback
+	if err := c.call(ctx, c.userRequest(turonUserID, ""), &cashback); err != nil {
+		return nil, err
+	}
+	return &cashback, nil
+}
+
+// GetCashbackAsOf returns the user's balance as it stood at asOf.
+func (c *Client) GetCashbackAsOf(ctx context.Context, turonUserID int64, asOf time.Time) (*models.CashbackBalance, error) {
+	r := c.userRequest(turonUserID, "")
+	r.query = url.Values{"as_of": {asOf.Format(time.RFC3339Nano)}}
+
+	var balance models.CashbackBalance
+	if err := c.call(ctx, r, &balance); err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+// HistoryQuery filters a history listing or export. Zero fields are not
+// sent.
+type HistoryQuery struct {
+	// FromDate and ToDate are RFC 3339 timestamps or YYYY-MM-DD dates; ToDate
+	// is inclusive.
+	FromDate   string
+	ToDate     string
+	TimeZone   string
+	Operations []string
+	Sources    []string
+	Reason     string
+	MinAmount  *float64
+	MaxAmount  *float64
+	SortBy     string
+	SortOrder  string
+	PageSize   int64
+}
+
+func (q *HistoryQuery) values() url.Values {
+	values := url.Values{}
+	if q == nil {
+		return values
+	}
+	set := func(key, value string) {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	set("from_date", q.FromDate)
+	set("to_date", q.ToDate)
+	set("tz", q.TimeZone)
+	set("reason", q.Reason)
+	set("sort_by", q.SortBy)
+	set("sort_order", q.SortOrder)
+	for _, operation := range q.Operations {
+		values.Add("operation", operation)
+	}
+	for _, source := range q.Sources {
+		values.Add("source", source)
+	}
+	if q.MinAmount != nil {
+		values.Set("min_amount", strconv.FormatFloat(*q.MinAmount, 'f', -1, 64))
+	}
+	if q.MaxAmount != nil {
+		values.Set("max_amount", strconv.FormatFloat(*q.MaxAmount, 'f', -1, 64))
+	}
+	if q.PageSize > 0 {
+		values.Set("page_size", strconv.FormatInt(q.PageSize, 10))
+	}
+	return values
+}
+
+// HistoryPage is one offset page of history.
+type HistoryPage struct {
+	Items      []models.CashbackHistory `json:"items"`
+	Pagination models.Pagination        `json:"pagination"`
+}
+
+// HistoryCursorPage is one keyset page of history. Pass
+// Pagination.NextCursor to GetHistoryByCursor for the next one.
+type HistoryCursorPage struct {
+	Items      []models.CashbackHistory `json:"items"`
+	Pagination models.CursorPagination  `json:"pagination"`
+}
+
+// GetHistory returns page of the user's history, counting from 1.
+func (c *Client) GetHistory(ctx context.Context, turonUserID int64, query *HistoryQuery, page int64) (*HistoryPage, error) {
+	r := c.userRequest(turonUserID, "/history")
+	r.query = query.values()
+	if page > 0 {
+		r.query.Set("page", strconv.FormatInt(page, 10))
+	}
+
+	var result HistoryPage
+	if err := c.call(ctx, r, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetHistoryByCursor returns the page of the user's history at cursor; an
+// empty cursor starts at the beginning.
+func (c *Client) GetHistoryByCursor(ctx context.Context, turonUserID int64, query *HistoryQuery, cursor string) (*HistoryCursorPage, error) {
+	r := c.userRequest(turonUserID, "/history")
+	r.query = query.values()
+	r.query.Set("pagination", "cursor")
+	if cursor != "" {
+		r.query.Set("cursor", cursor)
+	}
+
+	var result HistoryCursorPage
+	if err := c.call(ctx, r, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportHistory writes the user's history as CSV or XLSX (format "csv" or
+// "xlsx") to w.
+func (c *Client) ExportHistory(ctx context.Context, turonUserID int64, query *HistoryQuery, format string, w io.Writer) error {
+	r := &request{
+		method:    http.MethodGet,
+		path:      fmt.Sprintf("/v1/cashback/%d/history/export", turonUserID),
+		query:     query.values(),
+		longLived: true,
+	}
+	if format != "" {
+		r.query.Set("format", format)
+	}
+
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// GetStatement returns the user's statement for period (YYYY-MM) in the
+// IANA time zone tz, or the server's when empty.
+func (c *Client) GetStatement(ctx context.Context, turonUserID int64, period, tz string) (*models.Statement, error) {
+	r := &request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/v1/cashback/%d/statements/%s", turonUserID, url.PathEscape(period)),
+		query:  url.Values{"format": {"json"}},
+	}
+	if tz != "" {
+		r.query.Set("tz", tz)
+	}
+
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	defer drain(resp.Body)
+
+	var statement models.Statement
+	if err := json.NewDecoder(resp.Body).Decode(&statement); err != nil {
+		return nil, fmt.Errorf("failed to decode statement: %w", err)
+	}
+	return &statement, nil
+}
+
+// GetBalances looks up the balances of up to 500 users at once.
+func (c *Client) GetBalances(ctx context.Context, turonUserIDs []int64) (*models.BalanceLookupResult, error) {
+	r, err := jsonRequest(http.MethodPost, "/v2/cashback/balances", models.BalanceLookupRequest{TuronUserIDs: turonUserIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	var result models.BalanceLookupResult
+	if err := c.call(ctx, r, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// BulkCredit submits many credits in one batch. batchID is generated when
+// empty; calling again with the same ID and rows resumes the batch. The rows
+// are posted in the background: the returned batch is processing, poll
+// GetBatch for the outcome. Row problems fail with ErrValidationFailed; see
+// Error.RowErrors.
+func (c *Client) BulkCredit(ctx context.Context, batchID string, rows []models.BulkCreditRow) (*models.CashbackBatch, error) {
+	if batchID == "" {
+		batchID = NewIdempotencyKey()
+	}
+
+	r, err := jsonRequest(http.MethodPost, "/v2/cashback/bulk", rows)
+	if err != nil {
+		return nil, err
+	}
+	r.query = url.Values{"batch_id": {batchID}}
+
+	var batch models.CashbackBatch
+	if err := c.call(ctx, r, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+func (c *Client) GetBatch(ctx context.Context, batchID string) (*models.CashbackBatch, error) {
+	var batch models.CashbackBatch
+	r := &request{method: http.MethodGet, path: "/v2/cashback/bulk/" + url.PathEscape(batchID)}
+	if err := c.call(ctx, r, &batch); err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// StreamEvent is one event of a balance stream. Exactly one of History and
+// Balance is set.
+type StreamEvent struct {
+	// ID resumes the stream when passed as lastEventID.
+	ID      int64
+	History *models.CashbackHistory
+	Balance *models.Cashback
+}
+
+// StreamURL returns a URL that opens the user's balance stream until ttl,
+// at most signature.MaxStreamTokenTTL, has passed. It is meant for browsers,
+// whose EventSource cannot sign requests: hand them the URL instead of the
+// secret.
+func (c *Client) StreamURL(turonUserID int64, ttl time.Duration) (string, error) {
+	if c.secret == "" {
+		return "", errors.New("a stream URL needs a signing secret")
+	}
+	if ttl <= 0 || ttl > signature.MaxStreamTokenTTL {
+		return "", fmt.Errorf("ttl must be positive and at most %s", signature.MaxStreamTokenTTL)
+	}
+
+	u := *c.baseURL
+	u.Path += streamPath(turonUserID)
+	u.RawQuery = c.streamQuery(turonUserID, time.Now().Add(ttl)).Encode()
+	return u.String(), nil
+}
+
+func streamPath(turonUserID int64) string {
+	return fmt.Sprintf("/v1/cashback/%d/stream", turonUserID)
+}
+
+// streamQuery carries a stream token valid until expires, or nothing when
+// the client does not sign.
+func (c *Client) streamQuery(turonUserID int64, expires time.Time) url.Values {
+	if c.secret == "" {
+		return nil
+	}
+	return url.Values{
+		signature.StreamExpiresParam: {strconv.FormatInt(expires.Unix(), 10)},
+		signature.StreamTokenParam:   {signature.StreamToken(c.secret, turonUserID, expires)},
+	}
+}
+
+// StreamCashback follows the user's balance live, calling fn for every
+// event until ctx is done, fn fails or the server ends the stream. Pass the
+// ID of the last event seen as lastEventID to resume without gaps, or 0 to
+// start from the current balance. It does not reconnect by itself.
+func (c *Client) StreamCashback(ctx context.Context, turonUserID, lastEventID int64, fn func(event *StreamEvent) error) error {
+	r := &request{
+		method:    http.MethodGet,
+		path:      streamPath(turonUserID),
+		query:     c.streamQuery(turonUserID, time.Now().Add(signature.MaxSkew)),
+		header:    http.Header{"Accept": {"text/event-stream"}},
+		longLived: true,
+	}
+	if lastEventID > 0 {
+		r.header.Set("Last-Event-ID", strconv.FormatInt(lastEventID, 10))
+	}
+
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var (
+		id        int64
+		eventType string
+		data      strings.Builder
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() > 0 {
+				event, err := decodeStreamEvent(id, eventType, data.String())
+				if err != nil {
+					return err
+				}
+				if event != nil {
+					if err := fn(event); err != nil {
+						return err
+					}
+				}
+			}
+			eventType = ""
+			data.Reset()
+		case strings.HasPrefix(line, "id: "):
+			id, _ = strconv.ParseInt(strings.TrimPrefix(line, "id: "), 10, 64)
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data.WriteString(strings.TrimPrefix(line, "data: "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func decodeStreamEvent(id int64, eventType, data string) (*StreamEvent, error) {
+	event := &StreamEvent{ID: id}
+	switch eventType {
+	case "history":
+		event.History = &models.CashbackHistory{}
+		if err := json.Unmarshal([]byte(data), event.History); err != nil {
+			return nil, fmt.Errorf("failed to decode history event: %w", err)
+		}
+	case "balance":
+		event.Balance = &models.Cashback{}
+		if err := json.Unmarshal([]byte(data), event.Balance); err != nil {
+			return nil, fmt.Errorf("failed to decode balance event: %w", err)
+		}
+	default:
+		// Unknown events are skipped, so the server can add new ones.
+		return nil, nil
+	}
+	return event, nil
+}
+
+func (c *Client) userRequest(turonUserID int64, suffix string) *request {
+	return &request{
+		method: http.MethodGet,
+		path:   fmt.Sprintf("/v2/cashback/%d%s", turonUserID, suffix),
+	}
+}
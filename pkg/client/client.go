@@ -0,0 +1,277 @@
+// Package client is a Go client for the cashback-serv HTTP API.
+//
+// It talks to the /v2 endpoints, decodes their envelopes into the models
+// types and returns *Error values that match the server error codes. Every
+// call is safe to retry: increases and decreases carry an Idempotency-Key
+// and bulk credits a batch ID, so transient failures are retried with
+// backoff. With WithSigningSecret, requests are signed as package signature
+// describes.
+package client
+
+import (
+	"bytes"
+	"cashback-serv/pkg/signature"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header the server deduplicates
+	// increases and decreases by.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// RequestIDHeader is echoed by the server and reported in *Error.
+	RequestIDHeader = "X-Request-ID"
+
+	defaultTimeout     = 30 * time.Second
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 200 * time.Millisecond
+	defaultMaxDelay    = 5 * time.Second
+)
+
+// Client calls one cashback-serv deployment. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	secret     string
+	userAgent  string
+
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces the default client, which has a 30s timeout.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithSigningSecret signs every request with secret.
+func WithSigningSecret(secret string) Option {
+	return func(c *Client) {
+		c.secret = secret
+	}
+}
+
+// WithRetry sets how many attempts a call makes in total and the bounds of
+// the exponential backoff between them. maxAttempts 1 turns retries off.
+func WithRetry(maxAttempts int, baseDelay, maxDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.baseDelay = baseDelay
+		c.maxDelay = maxDelay
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "http://cashback.internal:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", baseURL)
+	}
+
+	c := &Client{
+		baseURL:     u,
+		httpClient:  &http.Client{Timeout: defaultTimeout},
+		userAgent:   "cashback-serv-go-client",
+		maxAttempts: defaultMaxAttempts,
+		baseDelay:   defaultBaseDelay,
+		maxDelay:    defaultMaxDelay,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.maxAttempts < 1 {
+		c.maxAttempts = 1
+	}
+	return c, nil
+}
+
+// NewIdempotencyKey returns a random (version 4) UUID. Keep the key of a
+// logical operation to retry it later without posting twice.
+func NewIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// request describes one API call.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	contentType string
+	body        []byte
+	// longLived responses, like streams and exports, are not bound by the
+	// client timeout; only ctx ends them.
+	longLived bool
+}
+
+func jsonRequest(method, path string, payload interface{}) (*request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	return &request{method: method, path: path, contentType: "application/json", body: body}, nil
+}
+
+// send performs req, retrying transient failures, and returns the response
+// of the last attempt. A non-2xx response is returned as *Error, with the
+// body closed.
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.attempt(ctx, req)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+			return resp, nil
+		}
+
+		var retryAfter time.Duration
+		if err == nil {
+			retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+			err = decodeError(resp)
+			resp.Body.Close()
+		}
+
+		if attempt >= c.maxAttempts || !retryable(ctx, err) {
+			return nil, err
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, req *request) (*http.Response, error) {
+	u := *c.baseURL
+	u.Path += req.path
+	if len(req.query) > 0 {
+		u.RawQuery = req.query.Encode()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u.String(), bytes.NewReader(req.body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	httpReq.Header.Set("User-Agent", c.userAgent)
+
+	if c.secret != "" {
+		// Signed per attempt, so a retry after a long backoff is not stale.
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		httpReq.Header.Set(signature.TimestampHeader, timestamp)
+		httpReq.Header.Set(signature.SignatureHeader, signature.Sign(c.secret, timestamp, req.method, httpReq.URL.RequestURI(), req.body))
+	}
+
+	httpClient := c.httpClient
+	if req.longLived && httpClient.Timeout != 0 {
+		untimed := *httpClient
+		untimed.Timeout = 0
+		httpClient = &untimed
+	}
+	return httpClient.Do(httpReq)
+}
+
+// call sends req and decodes the data of the /v2 envelope into out.
+func (c *Client) call(ctx context.Context, req *request, out interface{}) error {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	envelope := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response data: %w", err)
+	}
+	return nil
+}
+
+// backoff is an exponential delay with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := float64(c.baseDelay) * math.Pow(2, float64(attempt-1))
+	if delay > float64(c.maxDelay) {
+		delay = float64(c.maxDelay)
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(mathrand.Int63n(int64(delay)) + 1)
+}
+
+// retryable reports whether err is worth another attempt: the connection
+// failed or the server was temporarily unable to answer.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if apiErr, ok := err.(*Error); ok {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
+
+// drain discards what is left of a response body, so the connection can be
+// reused.
+func drain(body io.ReadCloser) {
+	io.Copy(io.Discard, io.LimitReader(body, 64<<10))
+	body.Close()
+}
@@ -0,0 +1,228 @@
+package client_test
+
+import (
+	"cashback-serv/models"
+	"cashback-serv/pkg/client"
+	"cashback-serv/pkg/signature"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSigning(t *testing.T) {
+	server := newServer(t, newRouter(newFakeRepository(), "old-secret", "new-secret"))
+
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr error
+	}{
+		{name: "current secret", secret: "new-secret"},
+		{name: "rotated secret", secret: "old-secret"},
+		{name: "wrong secret", secret: "other-secret", wantErr: client.ErrUnauthenticated},
+		{name: "unsigned", wantErr: client.ErrUnauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := []client.Option{client.WithHTTPClient(server.Client()), client.WithRetry(1, 0, 0)}
+			if tt.secret != "" {
+				opts = append(opts, client.WithSigningSecret(tt.secret))
+			}
+			c, err := client.New(server.URL, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = c.IncreaseCashback(context.Background(), &models.CashbackRequest{TuronUserID: 1, CashbackAmount: 5})
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("got %v, want success", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if err.(*client.Error).StatusCode != http.StatusUnauthorized {
+				t.Errorf("got status %d, want 401", err.(*client.Error).StatusCode)
+			}
+		})
+	}
+}
+
+// tokenQuery is the query of a stream URL for turonUserID signed with secret.
+func tokenQuery(secret string, turonUserID int64, expires time.Time) url.Values {
+	return url.Values{
+		signature.StreamExpiresParam: {strconv.FormatInt(expires.Unix(), 10)},
+		signature.StreamTokenParam:   {signature.StreamToken(secret, turonUserID, expires)},
+	}
+}
+
+func TestStreamToken(t *testing.T) {
+	server := newServer(t, newRouter(newFakeRepository(), "secret"))
+	c, err := client.New(server.URL, client.WithHTTPClient(server.Client()), client.WithSigningSecret("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	streamURL, err := c.StreamURL(1, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := url.Parse(streamURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	// The test service has no stream notifier, so an accepted stream
+	// answers 503.
+	tests := []struct {
+		name   string
+		path   string
+		query  url.Values
+		status int
+	}{
+		{name: "client URL", path: signed.Path, query: signed.Query(), status: http.StatusServiceUnavailable},
+		{name: "unprefixed route", path: "/cashback/1/stream", query: signed.Query(), status: http.StatusServiceUnavailable},
+		{name: "no token", path: "/v1/cashback/1/stream", status: http.StatusUnauthorized},
+		{name: "other user", path: "/v1/cashback/2/stream", query: signed.Query(), status: http.StatusUnauthorized},
+		{name: "expired", path: "/v1/cashback/1/stream", query: tokenQuery("secret", 1, now.Add(-time.Minute)), status: http.StatusUnauthorized},
+		{name: "too far ahead", path: "/v1/cashback/1/stream", query: tokenQuery("secret", 1, now.Add(2*signature.MaxStreamTokenTTL)), status: http.StatusUnauthorized},
+		{name: "wrong secret", path: "/v1/cashback/1/stream", query: tokenQuery("other-secret", 1, now.Add(time.Minute)), status: http.StatusUnauthorized},
+		{name: "not a stream", path: "/v1/cashback/1", query: signed.Query(), status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := server.Client().Get(server.URL + tt.path + "?" + tt.query.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	server := newServer(t, newRouter(newFakeRepository()))
+	c, err := client.New(server.URL, client.WithHTTPClient(server.Client()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	req := &models.CashbackRequest{TuronUserID: 1, CashbackAmount: 10}
+	first, err := c.IncreaseCashback(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Replayed {
+		t.Error("first call is marked replayed")
+	}
+	if req.IdempotencyKey == "" {
+		t.Fatal("idempotency key was not generated")
+	}
+
+	second, err := c.IncreaseCashback(ctx, &models.CashbackRequest{
+		TuronUserID:    1,
+		CashbackAmount: 10,
+		IdempotencyKey: req.IdempotencyKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.Replayed {
+		t.Error("repeated key is not marked replayed")
+	}
+	if second.History.ID != first.History.ID {
+		t.Errorf("got history %d, want the original %d", second.History.ID, first.History.ID)
+	}
+	if second.Balance.CashbackAmount != 10 {
+		t.Errorf("got balance %v, want 10: the replay posted again", second.Balance.CashbackAmount)
+	}
+
+	_, err = c.IncreaseCashback(ctx, &models.CashbackRequest{
+		TuronUserID:    1,
+		CashbackAmount: 20,
+		IdempotencyKey: req.IdempotencyKey,
+	})
+	if !errors.Is(err, client.ErrIdempotencyConflict) {
+		t.Errorf("got %v, want %v", err, client.ErrIdempotencyConflict)
+	}
+}
+
+// failFirst fails the first attempt with status, or by dropping the
+// connection when status is 0, and hands later ones to next.
+func failFirst(status int, next http.Handler, attempts *atomic.Int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) > 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if status == 0 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(status)
+	})
+}
+
+func TestRetries(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		retried bool
+	}{
+		{name: "429", status: http.StatusTooManyRequests, retried: true},
+		{name: "502", status: http.StatusBadGateway, retried: true},
+		{name: "503", status: http.StatusServiceUnavailable, retried: true},
+		{name: "504", status: http.StatusGatewayTimeout, retried: true},
+		{name: "transport error", status: 0, retried: true},
+		{name: "400", status: http.StatusBadRequest},
+		{name: "409", status: http.StatusConflict},
+		{name: "500", status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			server := newServer(t, failFirst(tt.status, newRouter(newFakeRepository()), &attempts))
+			c, err := client.New(server.URL,
+				client.WithHTTPClient(server.Client()),
+				client.WithRetry(3, time.Millisecond, time.Millisecond),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = c.IncreaseCashback(context.Background(), &models.CashbackRequest{TuronUserID: 1, CashbackAmount: 5})
+			if tt.retried {
+				if err != nil {
+					t.Fatalf("got %v, want success after a retry", err)
+				}
+				if got := attempts.Load(); got != 2 {
+					t.Errorf("got %d attempts, want 2", got)
+				}
+				return
+			}
+
+			var apiErr *client.Error
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Fatalf("got %v, want a %d *client.Error", err, tt.status)
+			}
+			if got := attempts.Load(); got != 1 {
+				t.Errorf("got %d attempts, want 1", got)
+			}
+		})
+	}
+}
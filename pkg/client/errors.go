@@ -0,0 +1,111 @@
+package client
+
+import (
+	"cashback-serv/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Error is a non-2xx answer of the server. Code is one of the
+// models.ErrorCode* values; compare with errors.Is against the Err*
+// variables below.
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	// Details is the raw error.details, e.g. []models.BulkRowError for
+	// validation_failed.
+	Details json.RawMessage
+}
+
+func (e *Error) Error() string {
+	if e.RequestID != "" {
+		return fmt.Sprintf("cashback-serv: %s (%d %s, request %s)", e.Message, e.StatusCode, e.Code, e.RequestID)
+	}
+	return fmt.Sprintf("cashback-serv: %s (%d %s)", e.Message, e.StatusCode, e.Code)
+}
+
+// Is matches errors with the same code, so errors.Is(err, ErrAccountFrozen)
+// works on any *Error.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// RowErrors returns the failing rows of a validation_failed bulk credit.
+func (e *Error) RowErrors() []models.BulkRowError {
+	var rows []models.BulkRowError
+	json.Unmarshal(e.Details, &rows)
+	return rows
+}
+
+var (
+	ErrInvalidArgument      = &Error{Code: models.ErrorCodeInvalidArgument}
+	ErrValidationFailed     = &Error{Code: models.ErrorCodeValidationFailed}
+	ErrUnauthenticated      = &Error{Code: models.ErrorCodeUnauthenticated}
+	ErrNotFound             = &Error{Code: models.ErrorCodeNotFound}
+	ErrAccountErased        = &Error{Code: models.ErrorCodeAccountErased}
+	ErrAccountFrozen        = &Error{Code: models.ErrorCodeAccountFrozen}
+	ErrAccountClosed        = &Error{Code: models.ErrorCodeAccountClosed}
+	ErrInsufficientCashback = &Error{Code: models.ErrorCodeInsufficientCashback}
+	ErrBatchConflict        = &Error{Code: models.ErrorCodeBatchConflict}
+	ErrIdempotencyConflict  = &Error{Code: models.ErrorCodeIdempotencyConflict}
+	ErrUnavailable          = &Error{Code: models.ErrorCodeUnavailable}
+	ErrInternal             = &Error{Code: models.ErrorCodeInternal}
+)
+
+// decodeError reads an error response: a /v2 envelope, or the {"error": ...}
+// body of the unversioned endpoints, whose code follows from the status.
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RequestID:  resp.Header.Get(RequestIDHeader),
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var envelope struct {
+		Error     json.RawMessage `json:"error"`
+		RequestID string          `json:"request_id"`
+	}
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope.Error) > 0 {
+		var detail struct {
+			Code    string          `json:"code"`
+			Message string          `json:"message"`
+			Details json.RawMessage `json:"details"`
+		}
+		if json.Unmarshal(envelope.Error, &detail) == nil {
+			apiErr.Code, apiErr.Message, apiErr.Details = detail.Code, detail.Message, detail.Details
+		} else {
+			json.Unmarshal(envelope.Error, &apiErr.Message)
+		}
+		if envelope.RequestID != "" {
+			apiErr.RequestID = envelope.RequestID
+		}
+	}
+
+	if apiErr.Code == "" {
+		apiErr.Code = codeForStatus(resp.StatusCode)
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
+
+func codeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return models.ErrorCodeInvalidArgument
+	case http.StatusUnauthorized:
+		return models.ErrorCodeUnauthenticated
+	case http.StatusNotFound:
+		return models.ErrorCodeNotFound
+	case http.StatusServiceUnavailable:
+		return models.ErrorCodeUnavailable
+	default:
+		return models.ErrorCodeInternal
+	}
+}
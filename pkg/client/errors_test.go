@@ -0,0 +1,74 @@
+package client_test
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/models"
+	"cashback-serv/pkg/client"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestErrorCodes(t *testing.T) {
+	repo := newFakeRepository()
+	repo.addCashback(models.Cashback{TuronUserID: 2, CashbackAmount: 10, Status: constants.AccountStatusActive})
+	repo.addCashback(models.Cashback{TuronUserID: 3, CashbackAmount: 10, Status: constants.AccountStatusFrozen})
+	server := newServer(t, newRouter(repo))
+
+	c, err := client.New(server.URL, client.WithHTTPClient(server.Client()), client.WithRetry(1, 0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		req    *models.CashbackRequest
+		target error
+		status int
+	}{
+		{
+			name:   "missing user",
+			req:    &models.CashbackRequest{CashbackAmount: 5},
+			target: client.ErrInvalidArgument,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "no wallet",
+			req:    &models.CashbackRequest{TuronUserID: 1, CashbackAmount: 5},
+			target: client.ErrInsufficientCashback,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "over balance",
+			req:    &models.CashbackRequest{TuronUserID: 2, CashbackAmount: 50},
+			target: client.ErrInsufficientCashback,
+			status: http.StatusConflict,
+		},
+		{
+			name:   "frozen",
+			req:    &models.CashbackRequest{TuronUserID: 3, CashbackAmount: 5},
+			target: client.ErrAccountFrozen,
+			status: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.DecreaseCashback(context.Background(), tt.req)
+
+			var apiErr *client.Error
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("got %v, want *client.Error", err)
+			}
+			if !errors.Is(err, tt.target) {
+				t.Errorf("got code %q, want %q", apiErr.Code, tt.target.(*client.Error).Code)
+			}
+			if apiErr.StatusCode != tt.status {
+				t.Errorf("got status %d, want %d", apiErr.StatusCode, tt.status)
+			}
+			if apiErr.RequestID == "" {
+				t.Error("request ID is empty")
+			}
+		})
+	}
+}
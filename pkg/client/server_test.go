@@ -0,0 +1,200 @@
+package client_test
+
+import (
+	constants "cashback-serv/const"
+	"cashback-serv/internal/handler"
+	core "cashback-serv/internal/interfaces"
+	"cashback-serv/internal/repository"
+	"cashback-serv/internal/service"
+	"cashback-serv/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeRepository keeps wallets and history in memory. Only the methods
+// increases, decreases and balance reads reach are implemented; any other
+// call panics on the nil embedded interface.
+type fakeRepository struct {
+	service.CashbackRepository
+
+	mu        sync.Mutex
+	cashbacks map[int64]*models.Cashback
+	balances  map[int64]float64
+	history   []models.CashbackHistory
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		cashbacks: map[int64]*models.Cashback{},
+		balances:  map[int64]float64{},
+	}
+}
+
+// addCashback stores a wallet as if earlier operations had opened it.
+func (r *fakeRepository) addCashback(cashback models.Cashback) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cashback.ID = int64(len(r.cashbacks) + 1)
+	r.cashbacks[cashback.TuronUserID] = &cashback
+	r.balances[cashback.ID] = cashback.CashbackAmount
+}
+
+func (r *fakeRepository) WithTx(fn func(tx core.CashbackTx) error) error {
+	return r.WithTxContext(context.Background(), fn)
+}
+
+// WithTxContext runs fn under the repository lock. Nothing is rolled back
+// on error; the tests only fail operations before they write.
+func (r *fakeRepository) WithTxContext(ctx context.Context, fn func(tx core.CashbackTx) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return fn(&fakeTx{repo: r})
+}
+
+func (r *fakeRepository) GetCashbackByUserID(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
+	return r.GetCashbackAccount(ctx, turonUserID)
+}
+
+func (r *fakeRepository) GetCashbackAccount(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cashback(turonUserID), nil
+}
+
+func (r *fakeRepository) GetCashbackHistoryByIdempotencyKey(ctx context.Context, key string) (*models.CashbackHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.history {
+		if r.history[i].IdempotencyKey == key {
+			history := r.history[i]
+			return &history, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) cashback(turonUserID int64) *models.Cashback {
+	cashback, ok := r.cashbacks[turonUserID]
+	if !ok {
+		return nil
+	}
+	copied := *cashback
+	return &copied
+}
+
+// fakeTx is the transaction of fakeRepository; its caller holds the lock.
+type fakeTx struct {
+	core.CashbackTx
+	repo *fakeRepository
+}
+
+func (t *fakeTx) GetCashbackByUserIDForUpdate(turonUserID int64) (*models.Cashback, error) {
+	return t.repo.cashback(turonUserID), nil
+}
+
+func (t *fakeTx) GetCashbackAccount(ctx context.Context, turonUserID int64) (*models.Cashback, error) {
+	return t.repo.cashback(turonUserID), nil
+}
+
+func (t *fakeTx) CreateCashback(cashback *models.Cashback) error {
+	cashback.ID = int64(len(t.repo.cashbacks) + 1)
+	cashback.Status = constants.AccountStatusActive
+	cashback.CreatedAt = time.Now()
+	cashback.UpdatedAt = cashback.CreatedAt
+	stored := *cashback
+	t.repo.cashbacks[cashback.TuronUserID] = &stored
+	return nil
+}
+
+func (t *fakeTx) CreateCashbackHistory(history *models.CashbackHistory) error {
+	for _, h := range t.repo.history {
+		if history.IdempotencyKey != "" && h.IdempotencyKey == history.IdempotencyKey {
+			return repository.ErrDuplicateIdempotencyKey
+		}
+	}
+	history.ID = int64(len(t.repo.history) + 1)
+	history.CreatedAt = time.Now()
+	history.UpdatedAt = history.CreatedAt
+	t.repo.history = append(t.repo.history, *history)
+	return nil
+}
+
+func (t *fakeTx) EnsureUserAccount(cashbackID int64) (int64, error) {
+	return cashbackID, nil
+}
+
+func (t *fakeTx) EnsureSourceAccount(sourceID int64) (int64, error) {
+	return -sourceID, nil
+}
+
+// CreateLedgerEntries keeps only the user side of each posting, which is
+// the first entry.
+func (t *fakeTx) CreateLedgerEntries(historyID int64, entries []models.LedgerEntry) error {
+	t.repo.balances[entries[0].AccountID] += entries[0].Amount
+	return nil
+}
+
+func (t *fakeTx) GetLedgerBalance(cashbackID int64) (float64, error) {
+	return t.repo.balances[cashbackID], nil
+}
+
+func (t *fakeTx) RefreshCashbackBalance(cashbackID int64) (float64, error) {
+	balance := t.repo.balances[cashbackID]
+	for _, cashback := range t.repo.cashbacks {
+		if cashback.ID == cashbackID {
+			cashback.CashbackAmount = balance
+		}
+	}
+	return balance, nil
+}
+
+func (t *fakeTx) UpdateRollups(history *models.CashbackHistory) error {
+	return nil
+}
+
+func (t *fakeTx) CreateWebhookEvent(event *models.WebhookEvent) error {
+	return nil
+}
+
+func (t *fakeTx) NotifyCashbackUpdate(turonUserID int64) error {
+	return nil
+}
+
+type fakeSources struct{}
+
+func (fakeSources) FindSourceOrCreate(ctx context.Context, turonUserID int64, hostIP string) (*models.Source, error) {
+	return &models.Source{ID: 1, HostIP: hostIP, Slug: "test"}, nil
+}
+
+// newRouter serves the cashback API of a fresh fake repository, requiring
+// requests signed with one of secrets, and streams opened with a stream
+// token, when any are given.
+func newRouter(repo *fakeRepository, secrets ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(handler.RequestID())
+
+	cashbackService := service.NewCashbackService(repo, fakeSources{})
+	cashbackHandler := handler.NewCashbackHandler(cashbackService)
+	if len(secrets) > 0 {
+		router.Use(handler.VerifySignature(secrets, "/cashback/:turon_user_id/stream", "/v1/cashback/:turon_user_id/stream"))
+		cashbackHandler.SetStreamSecrets(secrets)
+	}
+	cashbackHandler.RegisterRoutes(router)
+	return router
+}
+
+// newServer starts h and closes it when the test ends.
+func newServer(t *testing.T, h http.Handler) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+	return server
+}
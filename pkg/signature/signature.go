@@ -0,0 +1,108 @@
+// Package signature signs cashback-serv API requests and verifies them on
+// the server. A request carries the Unix time it was signed at and an
+// HMAC-SHA256 over that time, the method, the request URI and the body.
+//
+// Balance streams are opened by browsers, whose EventSource cannot set
+// headers. A backend holding the secret hands them a stream token instead:
+// an HMAC-SHA256 over the user and an expiry, sent in the query string.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	TimestampHeader = "X-Cashback-Timestamp"
+	SignatureHeader = "X-Cashback-Signature"
+
+	// MaxSkew is how far a request timestamp may be from the server clock.
+	MaxSkew = 5 * time.Minute
+
+	// StreamTokenParam and StreamExpiresParam are the query parameters of a
+	// stream token and of the Unix time it expires at.
+	StreamTokenParam   = "token"
+	StreamExpiresParam = "expires"
+
+	// MaxStreamTokenTTL is how far ahead a stream token may expire.
+	MaxStreamTokenTTL = time.Hour
+)
+
+var (
+	ErrMissing   = errors.New("request is not signed")
+	ErrExpired   = errors.New("request timestamp is too far from the server time")
+	ErrMalformed = errors.New("malformed request signature")
+	ErrMismatch  = errors.New("request signature does not match")
+
+	ErrTokenExpired = errors.New("stream token has expired or expires too far ahead")
+)
+
+// Sign returns the X-Cashback-Signature value of a request. uri is the path
+// with the raw query, as sent.
+func Sign(secret, timestamp, method, uri string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + strings.ToUpper(method) + "." + uri + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a request against every secret, so secrets can be rotated
+// by accepting the old and the new one for a while.
+func Verify(secrets []string, timestamp, signature, method, uri string, body []byte, now time.Time) error {
+	if timestamp == "" || signature == "" {
+		return ErrMissing
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrMalformed
+	}
+	skew := now.Sub(time.Unix(unix, 0))
+	if skew > MaxSkew || skew < -MaxSkew {
+		return ErrExpired
+	}
+
+	for _, secret := range secrets {
+		if hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, method, uri, body))) {
+			return nil
+		}
+	}
+	return ErrMismatch
+}
+
+// StreamToken returns the token that opens the balance stream of
+// turonUserID until expires.
+func StreamToken(secret string, turonUserID int64, expires time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("stream." + strconv.FormatInt(turonUserID, 10) + "." + strconv.FormatInt(expires.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyStreamToken checks a stream token of turonUserID against every
+// secret. expires is the raw StreamExpiresParam value.
+func VerifyStreamToken(secrets []string, turonUserID int64, expires, token string, now time.Time) error {
+	if expires == "" || token == "" {
+		return ErrMissing
+	}
+
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrMalformed
+	}
+	expiresAt := time.Unix(unix, 0)
+	if !now.Before(expiresAt) || expiresAt.Sub(now) > MaxStreamTokenTTL {
+		return ErrTokenExpired
+	}
+
+	for _, secret := range secrets {
+		if hmac.Equal([]byte(token), []byte(StreamToken(secret, turonUserID, expiresAt))) {
+			return nil
+		}
+	}
+	return ErrMismatch
+}